@@ -0,0 +1,48 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+//isAdmin checks the author of the message has Administrator or
+//Manage Server permission in the channel that message is sent.
+func isAdmin(s *discordgo.Session, m *discordgo.MessageCreate) bool {
+	permissions, err := s.State.UserChannelPermissions(m.Author.ID, m.ChannelID)
+	if err != nil {
+		//member may not be cached yet.
+		permissions, err = s.UserChannelPermissions(m.Author.ID, m.ChannelID)
+		if err != nil {
+			log.Printf("Error while getting permissions of %s-%s: %v\n", m.Author.Username, m.Author.ID, err)
+			return false
+		}
+	}
+
+	return permissions&discordgo.PermissionAdministrator != 0 ||
+		permissions&discordgo.PermissionManageServer != 0
+}
+
+//showQuota sends the Youtube Data API quota usage of the day to admins.
+func (vi *VoiceInstance) showQuota(s *discordgo.Session, m *discordgo.MessageCreate) {
+	if !isAdmin(s, m) {
+		vi.sendMessageToChannel(m.ChannelID, "Only server admins can do that command.")
+		return
+	}
+
+	usage := yt.Quota.Usage()
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "Youtube API quota: %d/%d units used (%.1f%%), resets in %s.",
+		usage.Used, usage.Budget, float64(usage.Used)*100/float64(usage.Budget),
+		time.Until(usage.ResetAt).Round(time.Minute))
+
+	for _, call := range usage.Calls {
+		fmt.Fprintf(&builder, "\n%s: %d calls, %d units", call.Name, call.Calls, call.Units)
+	}
+
+	vi.sendMessageToChannel(m.ChannelID, builder.String())
+}
@@ -0,0 +1,149 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/hemreari/feanor-dcbot/util"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const playlistUsage = "Usage: !pl <alias>, !pl list, !pl add <alias> <playlist link or ID>, !pl remove <alias>"
+
+var aliasNameRegex = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+//handlePlaylistCommand handles !pl command that plays the playlists
+//that are defined with an alias in the config.
+func (vi *VoiceInstance) handlePlaylistCommand(args string, s *discordgo.Session, m *discordgo.MessageCreate) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || (len(fields) == 1 && fields[0] == "list") {
+		vi.listPlaylistAliases(m)
+		return
+	}
+
+	switch fields[0] {
+	case "add":
+		if len(fields) != 3 {
+			vi.sendMessageToChannel(m.ChannelID, playlistUsage)
+			return
+		}
+		vi.addPlaylistAlias(fields[1], fields[2], s, m)
+		return
+	case "remove":
+		if len(fields) != 2 {
+			vi.sendMessageToChannel(m.ChannelID, playlistUsage)
+			return
+		}
+		vi.removePlaylistAlias(fields[1], s, m)
+		return
+	}
+
+	if len(fields) != 1 {
+		vi.sendMessageToChannel(m.ChannelID, playlistUsage)
+		return
+	}
+
+	alias := strings.ToLower(fields[0])
+	target, ok := cfg.PlaylistAlias(alias)
+	if !ok {
+		vi.sendMessageToChannel(m.ChannelID, fmt.Sprintf("There is no playlist named \"%s\". Type !pl list to see the playlists.", alias))
+		return
+	}
+
+	ref, err := util.ParsePlaylistRef(target)
+	if err != nil {
+		log.Printf("Error while parsing playlist alias %s: %v\n", alias, err)
+		vi.sendMessageToChannel(m.ChannelID, fmt.Sprintf("Playlist \"%s\" has an invalid link in the config.", alias))
+		return
+	}
+
+	switch ref.Provider {
+	case util.ProviderSpotify:
+		vi.prepSpotifyPlaylist(ref, s, m)
+	case util.ProviderYoutube:
+		vi.prepYoutubePlaylist(ref, s, m)
+	}
+}
+
+func (vi *VoiceInstance) listPlaylistAliases(m *discordgo.MessageCreate) {
+	aliases := cfg.PlaylistAliases()
+	if len(aliases) == 0 {
+		vi.sendMessageToChannel(m.ChannelID, "There are no playlists yet. "+playlistUsage)
+		return
+	}
+
+	vi.sendMessageToChannel(m.ChannelID, "Playlists: "+strings.Join(aliases, ", "))
+}
+
+//addPlaylistAlias adds or replaces an alias, only admins can do it.
+func (vi *VoiceInstance) addPlaylistAlias(alias, target string, s *discordgo.Session, m *discordgo.MessageCreate) {
+	if !isAdmin(s, m) {
+		vi.sendMessageToChannel(m.ChannelID, "Only server admins can do that command.")
+		return
+	}
+
+	alias = strings.ToLower(alias)
+	err := validateAliasName(alias)
+	if err != nil {
+		vi.sendMessageToChannel(m.ChannelID, err.Error())
+		return
+	}
+
+	//links are stored as they are given, so the config stays readable.
+	target = strings.Trim(target, "<>")
+	_, err = util.ParsePlaylistRef(target)
+	if err != nil {
+		vi.sendMessageToChannel(m.ChannelID, "Please, Check Your URL and Try Again.")
+		return
+	}
+
+	err = cfg.SetPlaylistAlias(alias, target)
+	if err != nil {
+		log.Println(err)
+		vi.sendMessageToChannel(m.ChannelID, "Playlist couldn't be saved.")
+		return
+	}
+
+	log.Printf("Playlist alias %s is set to %s by %s-%s\n", alias, target, m.Author.Username, m.Author.ID)
+	vi.sendMessageToChannel(m.ChannelID, fmt.Sprintf("Playlist \"%s\" is saved. Play it with !pl %s", alias, alias))
+}
+
+//removePlaylistAlias removes an alias, only admins can do it.
+func (vi *VoiceInstance) removePlaylistAlias(alias string, s *discordgo.Session, m *discordgo.MessageCreate) {
+	if !isAdmin(s, m) {
+		vi.sendMessageToChannel(m.ChannelID, "Only server admins can do that command.")
+		return
+	}
+
+	alias = strings.ToLower(alias)
+	ok, err := cfg.RemovePlaylistAlias(alias)
+	if err != nil {
+		log.Println(err)
+		vi.sendMessageToChannel(m.ChannelID, "Playlist couldn't be removed.")
+		return
+	}
+	if !ok {
+		vi.sendMessageToChannel(m.ChannelID, fmt.Sprintf("There is no playlist named \"%s\".", alias))
+		return
+	}
+
+	log.Printf("Playlist alias %s is removed by %s-%s\n", alias, m.Author.Username, m.Author.ID)
+	vi.sendMessageToChannel(m.ChannelID, fmt.Sprintf("Playlist \"%s\" is removed.", alias))
+}
+
+//validateAliasName checks the alias can be typed after !pl
+//and doesn't hide one of the subcommands.
+func validateAliasName(alias string) error {
+	switch alias {
+	case "list", "add", "remove":
+		return fmt.Errorf("\"%s\" can't be used as a playlist name.", alias)
+	}
+
+	if !aliasNameRegex.MatchString(alias) {
+		return fmt.Errorf("Playlist names can only have letters, numbers, - and _, up to 32 characters.")
+	}
+	return nil
+}
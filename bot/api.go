@@ -0,0 +1,609 @@
+package bot
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/hemreari/feanor-dcbot/web"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/hemreari/go-datastructures/queue"
+)
+
+//apiPrefix is the path prefix of the guild players in the API.
+const apiPrefix = "/api/guilds/"
+
+//apiHandler handles the requests to the API route of a guild.
+//params are the values of the "{...}" segments of the route.
+type apiHandler func(w http.ResponseWriter, r *http.Request, guildID string, params []string)
+
+//apiRoute is an API endpoint under /api/guilds/{guildID}/.
+type apiRoute struct {
+	method  string
+	pattern string
+	handler apiHandler
+}
+
+//apiRoutes are the endpoints of the API.
+var apiRoutes = []apiRoute{
+	{http.MethodGet, "queue", apiGetQueue},
+	{http.MethodPost, "queue", apiEnqueue},
+	{http.MethodDelete, "queue/{position}", apiRemoveQueueEntry},
+	{http.MethodPost, "queue/{position}/move", apiMoveQueueEntry},
+	{http.MethodGet, "now-playing", apiGetNowPlaying},
+	{http.MethodGet, "history", apiGetHistory},
+	{http.MethodGet, "events", apiEvents},
+	{http.MethodPost, "skip", apiSkip},
+	{http.MethodPost, "stop", apiStop},
+	{http.MethodPost, "pause", apiPause},
+	{http.MethodPost, "resume", apiResume},
+	{http.MethodPost, "volume", apiVolume},
+}
+
+//apiSong is a song in the API responses.
+type apiSong struct {
+	Title         string  `json:"title"`
+	Artist        string  `json:"artist,omitempty"`
+	Duration      string  `json:"duration,omitempty"`
+	Length        float64 `json:"length"` //seconds, 0 if it is unknown
+	URL           string  `json:"url,omitempty"`
+	CoverURL      string  `json:"coverUrl,omitempty"`
+	Live          bool    `json:"live"`
+	Downloading   bool    `json:"downloading"`
+	RequesterID   string  `json:"requesterId,omitempty"`
+	RequesterName string  `json:"requesterName,omitempty"`
+}
+
+//apiHistoryEntry is a played song in the API responses.
+type apiHistoryEntry struct {
+	Title         string    `json:"title"`
+	Artist        string    `json:"artist,omitempty"`
+	RequesterName string    `json:"requesterName,omitempty"`
+	StartedAt     time.Time `json:"startedAt"`
+	Played        float64   `json:"played"` //seconds
+}
+
+//apiNowPlaying is the response of the now playing endpoint.
+//Song is null if nothing is playing.
+type apiNowPlaying struct {
+	Song     *apiSong `json:"song"`
+	Position float64  `json:"position"` //seconds
+	Paused   bool     `json:"paused"`
+	Volume   int64    `json:"volume"`
+}
+
+//startAPIServer starts the HTTP API if it is enabled in the config.
+//Returns nil if it is not enabled.
+func startAPIServer() (*http.Server, error) {
+	if !cfg.API.Enabled {
+		return nil, nil
+	}
+
+	if cfg.API.Token == "" {
+		return nil, fmt.Errorf("Error while starting API server: api.token is not set in the config.")
+	}
+
+	listener, err := net.Listen("tcp", cfg.API.Address)
+	if err != nil {
+		return nil, fmt.Errorf("Error while starting API server: %v", err)
+	}
+
+	server := &http.Server{Handler: newAPIHandler(cfg.API.Token)}
+	go func() {
+		err := server.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			log.Printf("Error while serving API: %v\n", err)
+		}
+	}()
+
+	log.Printf("API is listening on %s.\n", listener.Addr())
+	return server, nil
+}
+
+//newAPIHandler returns the handler of the API that accepts the requests
+//with the given token or a dashboard session, and the dashboard itself.
+func newAPIHandler(token string) http.Handler {
+	api := http.NewServeMux()
+	api.HandleFunc(apiPrefix, serveGuildAPI)
+	api.HandleFunc("/api/session", serveSession)
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/", requireAuth(token, api))
+	mux.HandleFunc("/login", serveLogin)
+	mux.Handle("/", http.FileServer(http.FS(web.Assets())))
+	return mux
+}
+
+//requireAuth rejects the requests that don't have the token in their
+//Authorization header or a dashboard session cookie.
+func requireAuth(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if given != "" && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if r, ok := withSession(r); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		writeAPIError(w, http.StatusUnauthorized, "Invalid token.")
+	})
+}
+
+//serveGuildAPI finds the route of the request and calls its handler.
+//Bot has a single player, it is in a voice channel of one guild at a time.
+//Other guilds get an empty player state and can't control or enqueue.
+func serveGuildAPI(w http.ResponseWriter, r *http.Request) {
+	guildID, path := splitGuildPath(r.URL.Path)
+	if guildID == "" {
+		writeAPIError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+
+	//dashboard sessions can only control their own guild.
+	if session, ok := requestSession(r); ok && session.guildID != guildID {
+		writeAPIError(w, http.StatusForbidden, "You are not logged in to this guild.")
+		return
+	}
+
+	methodAllowed := false
+	for _, route := range apiRoutes {
+		params, ok := matchRoute(route.pattern, path)
+		if !ok {
+			continue
+		}
+		if route.method != r.Method {
+			methodAllowed = true
+			continue
+		}
+
+		if _, err := vi.session.State.Guild(guildID); err != nil {
+			writeAPIError(w, http.StatusNotFound, "Unknown guild.")
+			return
+		}
+		route.handler(w, r, guildID, params)
+		return
+	}
+
+	if methodAllowed {
+		writeAPIError(w, http.StatusMethodNotAllowed, "Method not allowed.")
+		return
+	}
+	writeAPIError(w, http.StatusNotFound, "Not found.")
+}
+
+//splitGuildPath splits the request path like "/api/guilds/123/queue/2"
+//into the guild ID and the path of the route like "queue/2".
+func splitGuildPath(path string) (string, string) {
+	path = strings.Trim(strings.TrimPrefix(path, apiPrefix), "/")
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) < 2 {
+		return "", ""
+	}
+	return parts[0], parts[1]
+}
+
+//matchRoute checks the path matches the route pattern and returns the
+//values of the "{...}" segments of the pattern.
+func matchRoute(pattern, path string) ([]string, bool) {
+	patternParts := strings.Split(pattern, "/")
+	pathParts := strings.Split(path, "/")
+	if len(patternParts) != len(pathParts) {
+		return nil, false
+	}
+
+	params := []string{}
+	for i, part := range patternParts {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			params = append(params, pathParts[i])
+			continue
+		}
+		if part != pathParts[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func apiGetQueue(w http.ResponseWriter, r *http.Request, guildID string, params []string) {
+	songs := []apiSong{}
+	if vi.guildID() == guildID {
+		songs = vi.apiQueue()
+	}
+	writeAPIResponse(w, http.StatusOK, map[string]interface{}{"songs": songs})
+}
+
+func apiGetNowPlaying(w http.ResponseWriter, r *http.Request, guildID string, params []string) {
+	writeAPIResponse(w, http.StatusOK, vi.apiNowPlaying(guildID))
+}
+
+func apiGetHistory(w http.ResponseWriter, r *http.Request, guildID string, params []string) {
+	writeAPIResponse(w, http.StatusOK, map[string]interface{}{"entries": apiHistory(guildID)})
+}
+
+//apiEnqueue plays the query like !play on behalf of the given user, who
+//has to be in a voice channel of the guild. Default user is the user of
+//the dashboard session. Replies are sent to the given text channel, or
+//to the channel of the last play process.
+//	{"query": "link or query", "userId": "...", "channelId": "..."}
+func apiEnqueue(w http.ResponseWriter, r *http.Request, guildID string, params []string) {
+	request := struct {
+		Query     string `json:"query"`
+		UserID    string `json:"userId"`
+		ChannelID string `json:"channelId"`
+	}{}
+	if !readAPIRequest(w, r, &request) {
+		return
+	}
+
+	request.Query = strings.TrimSpace(request.Query)
+	if session, ok := requestSession(r); ok && request.UserID == "" {
+		request.UserID = session.userID
+	}
+	if request.Query == "" || request.UserID == "" {
+		writeAPIError(w, http.StatusBadRequest, "query and userId are required.")
+		return
+	}
+
+	//queue of the player belongs to the guild that bot is connected to.
+	if guild := vi.guildID(); guild != "" && guild != guildID {
+		writeAPIError(w, http.StatusConflict, "Bot is in a voice channel of another guild.")
+		return
+	}
+
+	if request.ChannelID == "" {
+		request.ChannelID = vi.textChannelID
+	}
+	channel, err := vi.session.State.Channel(request.ChannelID)
+	if err != nil || channel.GuildID != guildID {
+		writeAPIError(w, http.StatusBadRequest, "channelId has to be a text channel of the guild.")
+		return
+	}
+
+	m, err := apiMessage(vi.session, guildID, request.ChannelID, request.UserID, "!play "+request.Query)
+	if err != nil {
+		log.Println(err)
+		writeAPIError(w, http.StatusBadRequest, "Unknown user.")
+		return
+	}
+
+	if !checkAPIPermission(w, r, m, "play") {
+		return
+	}
+
+	guild, err := vi.session.State.Guild(guildID)
+	if err != nil || !vi.validateUserVoiceState(vi.session, m, guild) {
+		writeAPIError(w, http.StatusConflict, "User has to be in a voice channel of the guild.")
+		return
+	}
+
+	log.Printf("API request to play %s for %s-%s.\n", request.Query, m.Author.Username, m.Author.ID)
+
+	//play process runs until the queue ends, result of the
+	//request is sent to the text channel like the chat command.
+	go vi.playCommand(request.Query, vi.session, m)
+	writeAPIResponse(w, http.StatusAccepted, map[string]string{"status": "Request is accepted."})
+}
+
+func apiRemoveQueueEntry(w http.ResponseWriter, r *http.Request, guildID string, params []string) {
+	if !checkAPIGuild(w, guildID) || !checkAPISessionPermission(w, r, guildID, "clear") {
+		return
+	}
+
+	position, err := strconv.Atoi(params[0])
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Position has to be a number.")
+		return
+	}
+
+	song, err := vi.removeQueueEntry(position)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeAPIResponse(w, http.StatusOK, newAPISong(song, false))
+}
+
+//apiMoveQueueEntry moves the song to the given position.
+//	{"to": 0}
+func apiMoveQueueEntry(w http.ResponseWriter, r *http.Request, guildID string, params []string) {
+	if !checkAPIGuild(w, guildID) || !checkAPISessionPermission(w, r, guildID, "clear") {
+		return
+	}
+
+	from, err := strconv.Atoi(params[0])
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Position has to be a number.")
+		return
+	}
+
+	request := struct {
+		To *int `json:"to"`
+	}{}
+	if !readAPIRequest(w, r, &request) {
+		return
+	}
+	if request.To == nil {
+		writeAPIError(w, http.StatusBadRequest, "to is required.")
+		return
+	}
+
+	err = vi.moveQueueEntry(from, *request.To)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeAPIResponse(w, http.StatusOK, map[string]interface{}{"songs": vi.apiQueue()})
+}
+
+//apiSkip skips the song without voting, like a DJ does. Dashboard users
+//have to be DJs or the requester of the song.
+func apiSkip(w http.ResponseWriter, r *http.Request, guildID string, params []string) {
+	if !checkAPIPlaying(w, guildID) || !checkAPISessionPermission(w, r, guildID, "skip") {
+		return
+	}
+
+	if session, ok := requestSession(r); ok {
+		m, err := apiMessage(vi.session, guildID, vi.textChannelID, session.userID, "!skip")
+		nowPlaying := vi.nowPlaying
+		if err != nil || !(isDJ(vi.session, m) || nowPlaying != nil && nowPlaying.requesterID == session.userID) {
+			writeAPIError(w, http.StatusForbidden, "Only DJs and the requester of the song can skip without voting. Use !skip to vote.")
+			return
+		}
+	}
+
+	vi.skip = true
+	writeAPIResponse(w, http.StatusOK, map[string]string{"status": "Song is skipped."})
+}
+
+func apiStop(w http.ResponseWriter, r *http.Request, guildID string, params []string) {
+	if !checkAPIPlaying(w, guildID) || !checkAPISessionPermission(w, r, guildID, "stop") {
+		return
+	}
+
+	vi.stop = true
+	writeAPIResponse(w, http.StatusOK, map[string]string{"status": "Song is stopped."})
+}
+
+//apiPause pauses the song, dashboard users need the rule of !stop.
+func apiPause(w http.ResponseWriter, r *http.Request, guildID string, params []string) {
+	if !checkAPIPlaying(w, guildID) || !checkAPISessionPermission(w, r, guildID, "stop") {
+		return
+	}
+
+	vi.setPaused(true)
+	writeAPIResponse(w, http.StatusOK, map[string]string{"status": "Song is paused."})
+}
+
+func apiResume(w http.ResponseWriter, r *http.Request, guildID string, params []string) {
+	if !checkAPIPlaying(w, guildID) || !checkAPISessionPermission(w, r, guildID, "stop") {
+		return
+	}
+
+	vi.setPaused(false)
+	writeAPIResponse(w, http.StatusOK, map[string]string{"status": "Song is resumed."})
+}
+
+//apiVolume sets the volume in percent.
+//	{"volume": 80}
+func apiVolume(w http.ResponseWriter, r *http.Request, guildID string, params []string) {
+	if !checkAPIGuild(w, guildID) || !checkAPISessionPermission(w, r, guildID, "volume") {
+		return
+	}
+
+	request := struct {
+		Volume *int `json:"volume"`
+	}{}
+	if !readAPIRequest(w, r, &request) {
+		return
+	}
+	if request.Volume == nil {
+		writeAPIError(w, http.StatusBadRequest, "volume is required.")
+		return
+	}
+
+	err := vi.changeVolume(*request.Volume)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeAPIResponse(w, http.StatusOK, map[string]int{"volume": *request.Volume})
+}
+
+//apiQueue returns the songs in the play queue, followed by
+//the songs that are still downloading.
+func (vi *VoiceInstance) apiQueue() []apiSong {
+	songs := []apiSong{}
+	for _, q := range []*queue.Queue{vi.playQueue, vi.downloadQueue} {
+		items, err := q.PeekAll()
+		if err != nil {
+			log.Printf("Error while getting queued songs: %v", err)
+			continue
+		}
+
+		for _, item := range items {
+			if song := getSongInstanceFromInterface(item); song != nil {
+				songs = append(songs, *newAPISong(song, q == vi.downloadQueue))
+			}
+		}
+	}
+	return songs
+}
+
+//apiNowPlaying returns the now playing song of the guild with its position.
+//Player state is empty if bot is not in a voice channel of the guild.
+func (vi *VoiceInstance) apiNowPlaying(guildID string) apiNowPlaying {
+	if vi.guildID() != guildID {
+		return apiNowPlaying{}
+	}
+
+	nowPlaying := apiNowPlaying{
+		Paused: vi.isPaused(),
+		Volume: atomic.LoadInt64(&vi.volume),
+	}
+
+	song := vi.nowPlaying
+	if song != nil {
+		nowPlaying.Song = newAPISong(song, false)
+		nowPlaying.Position = resumePosition(song.startTime, atomic.LoadInt64(&vi.sentFrames)).Seconds()
+	}
+	return nowPlaying
+}
+
+//apiHistory returns the recently played songs in the guild.
+func apiHistory(guildID string) []apiHistoryEntry {
+	entries := []apiHistoryEntry{}
+	if historyDB == nil {
+		return entries
+	}
+
+	records, err := historyDB.Recent(guildID, defaultHistoryCount)
+	if err != nil {
+		log.Println(err)
+		return entries
+	}
+
+	for _, record := range records {
+		entries = append(entries, apiHistoryEntry{
+			Title:         record.Title,
+			Artist:        record.Artist,
+			RequesterName: record.RequesterName,
+			StartedAt:     record.StartedAt,
+			Played:        record.Played.Seconds(),
+		})
+	}
+	return entries
+}
+
+//newAPISong creates the API response of the song.
+func newAPISong(song *SongInstance, downloading bool) *apiSong {
+	url := song.streamUrl
+	if song.videoID != "" {
+		url = youtubeUrlPrefix + song.videoID
+	}
+
+	return &apiSong{
+		Title:         song.title,
+		Artist:        strings.TrimSpace(song.artist),
+		Duration:      song.duration,
+		Length:        songLength(song).Seconds(),
+		URL:           url,
+		CoverURL:      song.coverUrl,
+		Live:          song.isLive,
+		Downloading:   downloading,
+		RequesterID:   song.requesterID,
+		RequesterName: song.requesterName,
+	}
+}
+
+//apiMessage creates the message of an API request as if the given
+//user sent it to the given channel, so chat commands can handle it.
+func apiMessage(s *discordgo.Session, guildID, channelID, userID, content string) (*discordgo.MessageCreate, error) {
+	member, err := s.State.Member(guildID, userID)
+	if err != nil {
+		member, err = s.GuildMember(guildID, userID)
+		if err != nil {
+			return nil, fmt.Errorf("Error while getting member %s: %v", userID, err)
+		}
+	}
+
+	return &discordgo.MessageCreate{Message: &discordgo.Message{
+		ChannelID: channelID,
+		GuildID:   guildID,
+		Content:   content,
+		Author:    member.User,
+		Member:    member,
+	}}, nil
+}
+
+//checkAPISessionPermission checks the user of the dashboard session can use
+//the chat command of the request, permission rules are the same as in chat.
+//Requests with the API token are not checked.
+func checkAPISessionPermission(w http.ResponseWriter, r *http.Request, guildID, command string) bool {
+	session, ok := requestSession(r)
+	if !ok {
+		return true
+	}
+
+	m, err := apiMessage(vi.session, guildID, vi.textChannelID, session.userID, "!"+command)
+	if err != nil {
+		log.Println(err)
+		writeAPIError(w, http.StatusForbidden, "Unknown user.")
+		return false
+	}
+	return checkAPIPermission(w, r, m, command)
+}
+
+//checkAPIPermission checks the author of the message can use the command
+//if the request is made from the dashboard.
+func checkAPIPermission(w http.ResponseWriter, r *http.Request, m *discordgo.MessageCreate, command string) bool {
+	if _, ok := requestSession(r); !ok {
+		return true
+	}
+
+	denial := permissionDenial(vi.session, m, command)
+	if denial != "" {
+		log.Printf("%s-%s is denied to use !%s from the dashboard\n", m.Author.Username, m.Author.ID, command)
+		writeAPIError(w, http.StatusForbidden, denial)
+		return false
+	}
+	return true
+}
+
+//checkAPIGuild checks bot is in a voice channel of the guild.
+func checkAPIGuild(w http.ResponseWriter, guildID string) bool {
+	if vi.guildID() != guildID {
+		writeAPIError(w, http.StatusConflict, "Bot is not in a voice channel of the guild.")
+		return false
+	}
+	return true
+}
+
+//checkAPIPlaying checks a song is playing in the guild.
+func checkAPIPlaying(w http.ResponseWriter, guildID string) bool {
+	if !checkAPIGuild(w, guildID) {
+		return false
+	}
+
+	if !vi.isPlaying {
+		writeAPIError(w, http.StatusConflict, "No song is playing.")
+		return false
+	}
+	return true
+}
+
+//readAPIRequest decodes the JSON body of the request, an empty
+//body is allowed. Sends an error response if it is invalid.
+func readAPIRequest(w http.ResponseWriter, r *http.Request, request interface{}) bool {
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(request)
+	if err != nil && err != io.EOF {
+		writeAPIError(w, http.StatusBadRequest, "Invalid JSON body.")
+		return false
+	}
+	return true
+}
+
+func writeAPIResponse(w http.ResponseWriter, status int, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(response)
+	if err != nil {
+		log.Printf("Error while writing API response: %v\n", err)
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeAPIResponse(w, status, map[string]string{"error": message})
+}
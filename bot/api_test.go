@@ -0,0 +1,106 @@
+package bot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestRequireAuth(t *testing.T) {
+	handler := requireAuth("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	token, err := sessions.newLogin("1", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionID, _, err := sessions.login(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tables := []struct {
+		authorization string
+		session       string
+		status        int
+	}{
+		{"", "", http.StatusUnauthorized},
+		{"Bearer wrong", "", http.StatusUnauthorized},
+		{"Bearer secret", "", http.StatusNoContent},
+		{"", "wrong", http.StatusUnauthorized},
+		{"", sessionID, http.StatusNoContent},
+	}
+
+	for _, table := range tables {
+		r := httptest.NewRequest(http.MethodGet, "/api/guilds/1/queue", nil)
+		if table.authorization != "" {
+			r.Header.Set("Authorization", table.authorization)
+		}
+		if table.session != "" {
+			r.AddCookie(&http.Cookie{Name: sessionCookie, Value: table.session})
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != table.status {
+			t.Errorf("%q %q: got %d, want %d.", table.authorization, table.session, w.Code, table.status)
+		}
+	}
+}
+
+func TestMatchRoute(t *testing.T) {
+	tables := []struct {
+		urlPath string
+		pattern string
+		params  []string
+		ok      bool
+	}{
+		{"/api/guilds/1/queue", "queue", []string{}, true},
+		{"/api/guilds/1/queue/", "queue", []string{}, true},
+		{"/api/guilds/1/queue/3", "queue/{position}", []string{"3"}, true},
+		{"/api/guilds/1/queue/3/move", "queue/{position}/move", []string{"3"}, true},
+		{"/api/guilds/1/queue/3/move", "queue/{position}", nil, false},
+		{"/api/guilds/1/skip", "stop", nil, false},
+	}
+
+	for _, table := range tables {
+		guildID, path := splitGuildPath(table.urlPath)
+		if guildID != "1" {
+			t.Errorf("%s: got guild %q, want \"1\".", table.urlPath, guildID)
+		}
+
+		params, ok := matchRoute(table.pattern, path)
+		if ok != table.ok || !reflect.DeepEqual(params, table.params) {
+			t.Errorf("%s with %s: got %v %t, want %v %t.", table.urlPath, table.pattern, params, ok, table.params, table.ok)
+		}
+	}
+}
+
+func TestAPINowPlayingGuild(t *testing.T) {
+	vi := &VoiceInstance{
+		dgv:    &discordgo.VoiceConnection{GuildID: "1"},
+		volume: 80,
+		paused: 1,
+	}
+
+	tables := []struct {
+		guildID    string
+		nowPlaying apiNowPlaying
+	}{
+		{"1", apiNowPlaying{Paused: true, Volume: 80}},
+		//player state of the voice guild isn't shown to other guilds.
+		{"2", apiNowPlaying{}},
+	}
+
+	for _, table := range tables {
+		nowPlaying := vi.apiNowPlaying(table.guildID)
+
+		if !reflect.DeepEqual(nowPlaying, table.nowPlaying) {
+			t.Errorf("Now playing is incorrect for guild %s, got: %+v, want: %+v", table.guildID, nowPlaying, table.nowPlaying)
+		}
+	}
+}
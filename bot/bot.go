@@ -4,20 +4,26 @@ import (
 	"bufio"
 	"container/list"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"os"
 	"os/exec"
 	"os/signal"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/hemreari/feanor-dcbot/config"
+	"github.com/hemreari/feanor-dcbot/history"
+	"github.com/hemreari/feanor-dcbot/playlist"
 	"github.com/hemreari/feanor-dcbot/spotify"
+	"github.com/hemreari/feanor-dcbot/stream"
 	"github.com/hemreari/feanor-dcbot/util"
 	"github.com/hemreari/feanor-dcbot/youtube"
 
@@ -36,6 +42,11 @@ const (
 	DefaultCoverPath string = "default.jpg"
 )
 
+const (
+	defaultVolume = 100 //percent
+	maxVolume     = 200
+)
+
 type VoiceInstance struct {
 	dgv                 *discordgo.VoiceConnection
 	session             *discordgo.Session
@@ -47,6 +58,36 @@ type VoiceInstance struct {
 	errQueue            *queue.Queue
 	nowPlayingMessageID string
 	playHistoryList     *list.List
+	nowPlaying          *SongInstance //song that is playing at the moment
+	sentFrames          int64         //audio frames sent of the now playing song, accessed atomically
+
+	//skip votes of the now playing song by user IDs.
+	skipVotes   map[string]bool
+	skipVotesMu sync.Mutex
+
+	//volume of the songs in percent, accessed atomically.
+	volume int64
+	//lastRequesterID is the requester of the last played song, used by fair queue.
+	lastRequesterID string
+	//queueChanged is 1 while a queue event is waiting to be sent, accessed atomically.
+	queueChanged int32
+	//preparing is the number of prepareSongs runs, accessed atomically.
+	preparing int32
+	//queueMu guards the queues while they are edited and songs are taken from them.
+	queueMu sync.Mutex
+	//reconnectMu lets only one voice reconnect run at a time.
+	reconnectMu sync.Mutex
+	//paused is 1 while the song is paused, accessed atomically.
+	paused int32
+	//textChannelID is the channel that play process messages are sent to.
+	textChannelID string
+
+	//timers that leave the voice channel, guarded by timerMu.
+	timerMu    sync.Mutex
+	emptyTimer *time.Timer
+	idleTimer  *time.Timer
+	autoPaused bool //song is paused because everyone left
+	leaving    bool //bot leaves when the play process ends
 }
 
 type SongInstance struct {
@@ -57,6 +98,21 @@ type SongInstance struct {
 	coverPath string
 	videoID   string
 	duration  string
+	streamUrl string        //direct audio file or radio stream URL
+	isLive    bool          //true for endless radio streams
+	isLocal   bool          //true for music library files, they are not deleted after playing
+	spotifyID string        //Spotify track ID of the songs that are played from Spotify
+	explicit  bool          //true for the songs that are explicit on Spotify
+	startTime time.Duration //position that the song starts playing from
+	//duration of the Spotify track, used to find the same recording on Youtube.
+	expectedDuration time.Duration
+	//user that requested the song.
+	requesterID   string
+	requesterName string
+	//collects the result of preparing the songs of a playlist file.
+	report *playlistReport
+	//location of the playlist file entry, listed if the song fails.
+	location string
 }
 
 var (
@@ -64,14 +120,30 @@ var (
 	opusEncoder *gopus.Encoder
 	mu          sync.Mutex
 	yt          *youtube.YoutubeAPI
+	spotifyAPI  *spotify.SpotifyAPI
 	cfg         *config.Config
+	playlists   *playlist.Store
+	historyDB   *history.DB
 	vi          *VoiceInstance
 )
 
-func InitBot(botToken string, ytAPI *youtube.YoutubeAPI, config *config.Config) error {
+func InitBot(botToken string, ytAPI *youtube.YoutubeAPI, spAPI *spotify.SpotifyAPI, config *config.Config) error {
 	yt = ytAPI
+	spotifyAPI = spAPI
 	cfg = config
 
+	var err error
+	playlists, err = playlist.Open(cfg.Playlists.Path)
+	if err != nil {
+		return err
+	}
+
+	historyDB, err = history.Open(cfg.History.Path)
+	if err != nil {
+		return err
+	}
+	defer historyDB.Close()
+
 	dg, err := discordgo.New("Bot " + botToken)
 	if err != nil {
 		return fmt.Errorf("Error while creating discord session: %v", err)
@@ -79,7 +151,13 @@ func InitBot(botToken string, ytAPI *youtube.YoutubeAPI, config *config.Config)
 
 	dg.AddHandler(ready)
 	dg.AddHandler(messageCreate)
+	dg.AddHandler(searchReplyHandler)
+	dg.AddHandler(searchReactionHandler)
 	dg.AddHandler(guildCreate)
+	dg.AddHandler(voiceStateUpdate)
+	dg.AddHandler(voiceServerUpdate)
+	dg.AddHandler(disconnect)
+	dg.AddHandler(resumed)
 
 	err = dg.Open()
 	if err != nil {
@@ -101,6 +179,12 @@ func InitBot(botToken string, ytAPI *youtube.YoutubeAPI, config *config.Config)
 		errQueue:            errQueue,
 		nowPlayingMessageID: "",
 		playHistoryList:     list.New(),
+		volume:              defaultVolume,
+	}
+
+	apiServer, err := startAPIServer()
+	if err != nil {
+		return err
 	}
 
 	//create cover and song folder if they are not exist
@@ -119,15 +203,13 @@ func InitBot(botToken string, ytAPI *youtube.YoutubeAPI, config *config.Config)
 	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt, os.Kill)
 	<-sc
 
+	if apiServer != nil {
+		apiServer.Close()
+	}
 	dg.Close()
 	return nil
 }
 
-func initSpotifyAPI() *spotify.SpotifyAPI {
-	spotifyAPI := spotify.NewSpotifyAPI(cfg.Spotify.ClientID, cfg.Spotify.ClientSecretID)
-	return spotifyAPI
-}
-
 func ready(s *discordgo.Session, event *discordgo.Ready) {
 	s.UpdateStatus(0, "Valinor'dan sevgiler.")
 }
@@ -155,37 +237,58 @@ func messageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
 		return
 	}
 
+	//permission rules are checked before the commands run. Commands
+	//with arguments are dispatched by the same name that is checked.
+	command := commandName(m.Content)
+	if command != "" && !vi.checkPermission(s, m, command) {
+		return
+	}
+
+	//playlist command saves and loads playlists. It has to be
+	//handled before !play, since it starts with !play too.
+	if m.Content == "!playlist" || strings.HasPrefix(m.Content, "!playlist ") {
+		vi.handleSavedPlaylistCommand(strings.TrimPrefix(m.Content, "!playlist"), s, m)
+		return
+	}
+
 	//play commands searchs after !play command
 	//and plays the first result.
-	if strings.HasPrefix(m.Content, "!play") {
-		query := strings.Trim(m.Content, "!play ")
-		if strings.Compare(query, "") == 0 {
-			return
-		}
+	if command == "play" {
+		query := commandArgs(m.Content)
 
-		if util.IsSpotifyUrl(query) {
-			vi.prepSpotifyPlaylist(query, s, m)
+		//playlist files can be attached to the !play command.
+		if len(m.Attachments) > 0 && playlist.IsPlaylistFile(m.Attachments[0].Filename) {
+			vi.prepPlaylistFile(m.Attachments[0], s, m)
 			return
 		}
 
-		if util.IsYoutubeUrl(query) {
-			vi.prepYoutubePlaylist(query, s, m)
+		if strings.Compare(query, "") == 0 {
 			return
 		}
 
-		vi.prepQuery(query, s, m)
+		vi.playCommand(query, s, m)
 		return
 	}
 
 	//search commands searchs query on yt and if it's
 	//finds anything related plays.
-	if strings.HasPrefix(m.Content, "!search") {
-		query := strings.Trim(m.Content, "!search ")
+	if command == "search" {
+		query, opts, err := parseSearchArgs(commandArgs(m.Content))
+		if err != nil {
+			vi.sendMessageToChannel(m.ChannelID, err.Error()+" Usage: !search [--playlist|--channel] [--min 2m] [--max 10m] [--region TR] query")
+			return
+		}
 		if query == "" {
 			vi.sendMessageToChannel(m.ChannelID, "Unsufficient query. Try again, with query.")
 			return
 		}
-		vi.searchOnYoutube(query, s, m)
+		vi.searchOnYoutube(query, opts, s, m)
+	}
+
+	//pl command plays the playlists that have an alias in the config.
+	if m.Content == "!pl" || strings.HasPrefix(m.Content, "!pl ") {
+		vi.handlePlaylistCommand(strings.TrimPrefix(m.Content, "!pl"), s, m)
+		return
 	}
 
 	//skip commands plays next song
@@ -198,14 +301,92 @@ func messageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
 		vi.stopSong(m)
 	}
 
+	//clear commands removes the songs in the play queue.
+	if strings.Compare(m.Content, "!clear") == 0 {
+		vi.clearQueue(m)
+	}
+
+	if m.Content == "!volume" || strings.HasPrefix(m.Content, "!volume ") {
+		vi.setVolume(strings.TrimPrefix(m.Content, "!volume"), m)
+	}
+
 	if strings.Compare(m.Content, "!show") == 0 {
 		vi.showPlayQueue(m)
 	}
 
+	//history commands show what is played in the guild.
+	if m.Content == "!history" || strings.HasPrefix(m.Content, "!history ") {
+		vi.showHistory(strings.TrimPrefix(m.Content, "!history"), m)
+	}
+
+	if m.Content == "!top" || strings.HasPrefix(m.Content, "!top ") {
+		vi.showTopTracks(strings.TrimPrefix(m.Content, "!top"), m)
+	}
+
+	if m.Content == "!stats" || strings.HasPrefix(m.Content, "!stats ") {
+		vi.showUserStats(m)
+	}
+
+	//quota command shows the Youtube Data API usage to admins.
+	if strings.Compare(m.Content, "!quota") == 0 {
+		vi.showQuota(s, m)
+	}
+
+	//join, leave and move commands change the voice channel of the bot.
+	if strings.Compare(m.Content, "!join") == 0 {
+		vi.joinCommand(s, m)
+	}
+
+	if strings.Compare(m.Content, "!leave") == 0 {
+		vi.leaveCommand(s, m)
+	}
+
+	if m.Content == "!move" || strings.HasPrefix(m.Content, "!move ") {
+		vi.moveCommand(strings.TrimPrefix(m.Content, "!move"), s, m)
+	}
+
+	//dashboard command sends a login link of the web dashboard.
+	if strings.Compare(m.Content, "!dashboard") == 0 {
+		vi.dashboardCommand(s, m)
+	}
+
+	//perm command edits who can use the commands.
+	if m.Content == "!perm" || strings.HasPrefix(m.Content, "!perm ") {
+		vi.handlePermissionCommand(strings.TrimPrefix(m.Content, "!perm"), s, m)
+	}
+
 	if strings.Compare(m.Content, "!testreddit") == 0 {
 	}
 }
 
+//playCommand plays the given Spotify or Youtube link, audio file
+//or radio stream URL, or searches the query and plays the first result.
+func (vi *VoiceInstance) playCommand(query string, s *discordgo.Session, m *discordgo.MessageCreate) {
+	ref, err := util.ParseMediaRef(query)
+	if err == nil {
+		switch ref.Provider {
+		case util.ProviderSpotify:
+			vi.prepSpotifyPlaylist(ref, s, m)
+		case util.ProviderYoutube:
+			vi.prepYoutubePlaylist(ref, s, m)
+		}
+		return
+	}
+
+	if err != util.ErrNotMediaRef {
+		log.Println(err)
+		vi.sendMessageToChannel(m.ChannelID, "Please, Check Your URL and Try Again.")
+		return
+	}
+
+	if stream.IsHttpUrl(query) {
+		vi.prepStream(query, s, m)
+		return
+	}
+
+	vi.prepQuery(query, s, m)
+}
+
 func (vi *VoiceInstance) validateMessage(s *discordgo.Session, m *discordgo.MessageCreate) (*discordgo.Guild, error) {
 	log.Printf("Message typed by %s-%s\n", m.Author.Username, m.Author.ID)
 	c, err := s.State.Channel(m.ChannelID)
@@ -239,13 +420,7 @@ func (vi *VoiceInstance) validateUserVoiceState(s *discordgo.Session, m *discord
 func (vi *VoiceInstance) channelVoiceJoin(s *discordgo.Session, m *discordgo.MessageCreate, g *discordgo.Guild) bool {
 	for _, vs := range g.VoiceStates {
 		if vs.UserID == m.Author.ID {
-			dgv, err := s.ChannelVoiceJoin(g.ID, vs.ChannelID, false, true)
-			if err != nil {
-				fmt.Printf("Couldn't join the voice channel: %v\n", err)
-				return false
-			}
-			vi.dgv = dgv
-			return true
+			return vi.joinVoiceChannel(s, m, g.ID, vs.ChannelID)
 		}
 	}
 	return false
@@ -276,7 +451,7 @@ func (vi *VoiceInstance) validateMessageAndJoinVoiceChannel(s *discordgo.Session
 	return true
 }
 
-func (vi *VoiceInstance) searchOnYoutube(query string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (vi *VoiceInstance) searchOnYoutube(query string, opts youtube.SearchOptions, s *discordgo.Session, m *discordgo.MessageCreate) {
 	retGuild, err := vi.validateMessage(s, m)
 	if err != nil {
 		log.Println(err)
@@ -290,81 +465,67 @@ func (vi *VoiceInstance) searchOnYoutube(query string, s *discordgo.Session, m *
 		return
 	}
 
-	resultsMap := make(map[int]youtube.SearchResult)
-
-	results := yt.GetVideoResults(query)
-	resultCounter := 1
+	results, err := yt.GetSearchResults(query, opts)
+	if err != nil {
+		log.Println(err)
+		vi.sendMessageToChannel(m.ChannelID, youtubeErrorMessage(err))
+		return
+	}
 
-	for _, value := range *results {
-		resultsMap[resultCounter] = value
-		resultCounter++
+	if len(*results) == 0 {
+		vi.sendMessageToChannel(m.ChannelID, "Query is insufficient to find a result. Try again.")
+		return
 	}
 
 	messageID := vi.sendSearchResultMessageToChannel(m.ChannelID, results)
-
-	s.AddHandlerOnce(func(s *discordgo.Session, m *discordgo.MessageCreate) {
-		userResponseInt, err := strconv.Atoi(m.Content)
-		if err != nil {
-			log.Println(err)
-			vi.sendMessageToChannel(m.ChannelID, "I accept only numbers.")
-		}
-		if userResponseInt < 1 && userResponseInt > resultCounter {
-			vi.sendMessageToChannel(m.ChannelID, "Not an available option.")
-		}
-		if strings.HasPrefix(m.Content, "!done") {
-			return
-		}
-		result := resultsMap[userResponseInt]
-		vi.sentMessageEditEmbed(m.ChannelID, messageID, &result)
-		vi.prepSearchSelectionPlay(&result, s, m)
+	if messageID == "" {
+		vi.sendErrorMessageToChannel(m.ChannelID)
 		return
-	})
+	}
+
+	vi.startSearchSession(m, messageID, *results)
 }
 
-//prepSpotifyPlaylist gets tracks information from the given Spotify URL
+//prepSpotifyPlaylist gets tracks information from the given Spotify reference
 //and parses them as youtube queries to add to the download queue.
 //finally starts the play process.
-func (vi *VoiceInstance) prepSpotifyPlaylist(url string, s *discordgo.Session, m *discordgo.MessageCreate) {
+func (vi *VoiceInstance) prepSpotifyPlaylist(ref *util.MediaRef, s *discordgo.Session, m *discordgo.MessageCreate) {
 	if !vi.validateMessageAndJoinVoiceChannel(s, m) {
 		return
 	}
 
-	id := util.GetSpotifyID(url)
-	if strings.Compare(id, "") == 0 {
-		log.Printf("Given URL \"%s\" is not an accepted spotify URL.\n", url)
-		vi.sendMessageToChannel(m.ChannelID, "Unexpected thing happend when playing the link. Try Again.")
-		return
-	}
-
-	urlType := util.GetSpotifyUrlType(url)
-	if urlType == util.UNKNOWNURL {
-		log.Printf("Error. Coulnd't find type of Spotify URL.\n", url)
-		vi.sendMessageToChannel(m.ChannelID, "Please, Check Your URL and Try Again.")
-		return
-	}
-
-	//initialize spotify api.
-	spotifyAPI := initSpotifyAPI()
-
-	playlistList, err := spotifyAPI.GetSpotifyPlaylist(id, urlType)
+	playlistList, err := spotifyAPI.GetSpotifyPlaylist(ref)
 	if err != nil {
 		log.Printf("Error while getting Spotify playlist tracks: %v", err)
-		vi.sendMessageToChannel(m.ChannelID, "Unexpected thing is happened. Please, Try again.")
+		vi.sendMessageToChannel(m.ChannelID, spotifyErrorMessage(err))
 		return
 	}
 
-	//when !play command is received stop the play process
-	//if bot has on going play job.
-	if vi.isPlaying == true {
-		vi.stopSong(m)
-	}
+	//songs are added after the queue of the on going play process.
+	playing := vi.isPlaying
+	later := []*SongInstance{}
+	limit := vi.newQueueLimit(m.Author.ID)
 
 	//parse playlist tracks to artist and track name.
 	for _, item := range playlistList {
 		songInstance := SongInstance{
-			title:    item.TrackName,
-			artist:   item.ArtistNames,
-			coverUrl: item.CoverUrl,
+			requesterID:      m.Author.ID,
+			requesterName:    requesterName(m),
+			title:            item.TrackName,
+			artist:           item.ArtistNames,
+			spotifyID:        item.TrackID,
+			coverUrl:         item.CoverUrl,
+			expectedDuration: item.Duration,
+			explicit:         item.Explicit,
+		}
+
+		if !vi.checkPolicy(m.GuildID, m.ChannelID, &songInstance, nil) || !limit.allow(&songInstance) {
+			continue
+		}
+
+		if playing {
+			later = append(later, &songInstance)
+			continue
 		}
 
 		//first playlist track is not putting in to the download queue.
@@ -374,7 +535,17 @@ func (vi *VoiceInstance) prepSpotifyPlaylist(url string, s *discordgo.Session, m
 			continue
 		}
 
-		vi.downloadQueue.Put(&songInstance)
+		vi.queueSong(vi.downloadQueue, &songInstance)
+	}
+
+	vi.sendLimitMessage(m.ChannelID, limit)
+	if playing {
+		vi.prepareSongs(later, m.ChannelID)
+		return
+	}
+
+	if vi.playQueue.Empty() && vi.downloadQueue.Empty() {
+		return
 	}
 
 	//start the play process.
@@ -382,40 +553,53 @@ func (vi *VoiceInstance) prepSpotifyPlaylist(url string, s *discordgo.Session, m
 	return
 }
 
-func (vi *VoiceInstance) prepYoutubePlaylist(url string, s *discordgo.Session, m *discordgo.MessageCreate) {
-	if !vi.validateMessageAndJoinVoiceChannel(s, m) {
+func (vi *VoiceInstance) prepYoutubePlaylist(ref *util.MediaRef, s *discordgo.Session, m *discordgo.MessageCreate) {
+	//mixes are generated for each user by Youtube,
+	//they can't be listed by the Youtube Data API.
+	if ref.Kind == util.KindMix {
+		vi.sendMessageToChannel(m.ChannelID, "Youtube mixes are not supported. Try a video or playlist link.")
 		return
 	}
 
-	//when !play command is received stop the play process
-	//if bot has on going play job.
-	if vi.isPlaying == true {
-		vi.stopSong(m)
-	}
-
-	playlistID := util.GetYoutubeID(url)
-	if strings.Compare(playlistID, "") == 0 {
-		log.Printf("Given URL \"%s\" is not a youtube playlist url.\n", url)
-		vi.sendMessageToChannel(m.ChannelID, "Given URL is not a valid URL.")
+	if !vi.validateMessageAndJoinVoiceChannel(s, m) {
 		return
 	}
 
-	urlType := util.GetYoutubeUrlType(url)
-
-	playlistList, err := yt.GetYoutubePlaylist(playlistID, urlType)
+	playlistList, err := yt.GetYoutubePlaylist(ref)
 	if err != nil {
 		log.Println(err)
-		vi.sendMessageToChannel(m.ChannelID, "Unexpected thing when playing playlist. Try Again.")
+		vi.sendMessageToChannel(m.ChannelID, youtubeErrorMessage(err))
 		return
 	}
 
+	//songs are added after the queue of the on going play process.
+	playing := vi.isPlaying
+	later := []*SongInstance{}
+	limit := vi.newQueueLimit(m.Author.ID)
+
 	//add tracks to download queue.
 	for _, item := range playlistList {
 		songInstance := SongInstance{
-			title:    item.VideoTitle,
-			duration: item.Duration,
-			coverUrl: item.CoverUrl,
-			videoID:  item.VideoID,
+			title:         item.VideoTitle,
+			duration:      item.Duration,
+			coverUrl:      item.CoverUrl,
+			videoID:       item.VideoID,
+			requesterID:   m.Author.ID,
+			requesterName: requesterName(m),
+		}
+
+		//start time is only meaningful for the video of the link.
+		if ref.Kind == util.KindTrack && item.VideoID == ref.ID {
+			songInstance.startTime = ref.StartTime
+		}
+
+		if !vi.checkPolicy(m.GuildID, m.ChannelID, &songInstance, &item) || !limit.allow(&songInstance) {
+			continue
+		}
+
+		if playing {
+			later = append(later, &songInstance)
+			continue
 		}
 
 		//first playlist track is not putting in to the download queue.
@@ -425,7 +609,17 @@ func (vi *VoiceInstance) prepYoutubePlaylist(url string, s *discordgo.Session, m
 			continue
 		}
 
-		vi.downloadQueue.Put(&songInstance)
+		vi.queueSong(vi.downloadQueue, &songInstance)
+	}
+
+	vi.sendLimitMessage(m.ChannelID, limit)
+	if playing {
+		vi.prepareSongs(later, m.ChannelID)
+		return
+	}
+
+	if vi.playQueue.Empty() && vi.downloadQueue.Empty() {
+		return
 	}
 
 	vi.playQueueFunc(m.ChannelID)
@@ -437,8 +631,15 @@ func (vi *VoiceInstance) prepQuery(query string, s *discordgo.Session, m *discor
 		return
 	}
 
+	//length of the song is unknown until it is found.
+	limit := vi.newQueueLimit(m.Author.ID)
+	if !limit.allow(&SongInstance{}) {
+		vi.sendLimitMessage(m.ChannelID, limit)
+		return
+	}
+
 	if vi.isPlaying == false {
-		err := vi.downloadPlayQuery(query, m.ChannelID)
+		err := vi.downloadPlayQuery(query, m)
 		if err != nil {
 			log.Println(err)
 			return
@@ -446,7 +647,7 @@ func (vi *VoiceInstance) prepQuery(query string, s *discordgo.Session, m *discor
 	}
 
 	if vi.isPlaying == true {
-		go vi.downloadPlayQuery(query, m.ChannelID)
+		go vi.downloadPlayQuery(query, m)
 		return
 	}
 
@@ -454,6 +655,52 @@ func (vi *VoiceInstance) prepQuery(query string, s *discordgo.Session, m *discor
 	return
 }
 
+//prepStream prepares direct audio file URLs and internet radio streams
+//to play. Streams are played from the URL by ffmpeg, so there is no
+//download step.
+func (vi *VoiceInstance) prepStream(url string, s *discordgo.Session, m *discordgo.MessageCreate) {
+	if !vi.validateMessageAndJoinVoiceChannel(s, m) {
+		return
+	}
+
+	info, err := stream.Probe(url)
+	if err != nil {
+		log.Println(err)
+		vi.sendMessageToChannel(m.ChannelID, "Given URL is not a playable audio file or radio stream.")
+		return
+	}
+
+	songInstance := &SongInstance{
+		title:         info.Title,
+		coverUrl:      DefaultCoverUrl,
+		duration:      info.Duration,
+		streamUrl:     info.URL,
+		isLive:        info.Live,
+		requesterID:   m.Author.ID,
+		requesterName: requesterName(m),
+	}
+
+	if !vi.checkPolicy(m.GuildID, m.ChannelID, songInstance, nil) {
+		return
+	}
+
+	limit := vi.newQueueLimit(m.Author.ID)
+	if !limit.allow(songInstance) {
+		vi.sendLimitMessage(m.ChannelID, limit)
+		return
+	}
+
+	vi.queueSong(vi.playQueue, songInstance)
+
+	//stream is going to be played after the song that
+	//is playing at the moment by on going play process.
+	if vi.isPlaying == true {
+		return
+	}
+
+	vi.playQueueFunc(m.ChannelID)
+}
+
 func (vi *VoiceInstance) prepPlay(query string, s *discordgo.Session, m *discordgo.MessageCreate) {
 	// Find the channel where the message came from.
 	c, err := s.State.Channel(m.ChannelID)
@@ -472,7 +719,7 @@ func (vi *VoiceInstance) prepPlay(query string, s *discordgo.Session, m *discord
 	}
 
 	if vi.isPlaying == false {
-		err = vi.downloadPlayQuery(query, m.ChannelID)
+		err = vi.downloadPlayQuery(query, m)
 		if err != nil {
 			log.Println(err)
 			return
@@ -480,7 +727,7 @@ func (vi *VoiceInstance) prepPlay(query string, s *discordgo.Session, m *discord
 	}
 
 	if vi.isPlaying == true {
-		go vi.downloadPlayQuery(query, m.ChannelID)
+		go vi.downloadPlayQuery(query, m)
 		return
 	}
 
@@ -498,40 +745,99 @@ func (vi *VoiceInstance) prepPlay(query string, s *discordgo.Session, m *discord
 	}
 }
 
-func (vi *VoiceInstance) prepSearchSelectionPlay(searchResult *youtube.SearchResult, s *discordgo.Session, m *discordgo.MessageCreate) {
+//prepSearchSelectionPlay plays the chosen search results in the given order.
+//Whole playlist is played for a playlist result and latest uploads are
+//played for a channel result.
+func (vi *VoiceInstance) prepSearchSelectionPlay(searchResults []youtube.SearchResult, s *discordgo.Session, m *discordgo.MessageCreate) {
 	if !vi.validateMessageAndJoinVoiceChannel(s, m) {
 		return
 	}
 
-	if vi.isPlaying == false {
-		err := vi.downloadSelection(searchResult, m.ChannelID)
+	tracks := []youtube.SearchResult{}
+	for _, result := range searchResults {
+		resultTracks, err := yt.ResultTracks(result)
 		if err != nil {
 			log.Println(err)
-			return
+			vi.sendMessageToChannel(m.ChannelID, youtubeErrorMessage(err))
+			continue
 		}
+		tracks = append(tracks, resultTracks...)
 	}
 
-	if vi.isPlaying == true {
-		vi.stopSong(m)
-		vi.downloadSelection(searchResult, m.ChannelID)
+	if len(tracks) == 0 {
+		return
+	}
+
+	//songs are added after the queue of the on going play process.
+	playing := vi.isPlaying
+	later := []*SongInstance{}
+	limit := vi.newQueueLimit(m.Author.ID)
+
+	for _, item := range tracks {
+		songInstance := SongInstance{
+			title:         item.VideoTitle,
+			duration:      item.Duration,
+			coverUrl:      item.CoverUrl,
+			videoID:       item.VideoID,
+			requesterID:   m.Author.ID,
+			requesterName: requesterName(m),
+		}
+
+		if !vi.checkPolicy(m.GuildID, m.ChannelID, &songInstance, &item) || !limit.allow(&songInstance) {
+			continue
+		}
+
+		if playing {
+			later = append(later, &songInstance)
+			continue
+		}
+
+		//first chosen track is not putting in to the download queue.
+		//it's going to be downloaded directly.
+		if vi.playQueue.Empty() {
+			err := vi.downloadID(&songInstance, m.ChannelID)
+			if err != nil {
+				log.Println(err)
+			}
+			continue
+		}
+
+		vi.queueSong(vi.downloadQueue, &songInstance)
+	}
+
+	vi.sendLimitMessage(m.ChannelID, limit)
+	if playing {
+		vi.prepareSongs(later, m.ChannelID)
+		return
+	}
+
+	if vi.playQueue.Empty() && vi.downloadQueue.Empty() {
+		return
 	}
 
 	vi.playQueueFunc(m.ChannelID)
 }
 
 func (vi *VoiceInstance) playQueueFunc(channelID string) {
+	vi.stopIdleTimer()
+	vi.textChannelID = channelID
+
 	err := vi.dgv.Speaking(true)
 	if err != nil {
 		log.Println("Couldn't set speaking", err)
 	}
 
+	//bot waits in the voice channel for new songs
+	//before leaving when the play process ends.
 	defer func() {
-		vi.disconnectBot()
+		vi.startIdleTimer()
 	}()
 
 	chanPlayStat := make(chan int)
 	for {
 		if !vi.downloadQueue.Empty() {
+			//songs are downloaded in turn of their requesters.
+			vi.applyFairQueue()
 			maxNumberofGoroutines := 2
 
 			conGoroutines := make(chan struct{}, maxNumberofGoroutines)
@@ -577,8 +883,6 @@ func (vi *VoiceInstance) playQueueFunc(channelID string) {
 			playStat := <-chanPlayStat
 			log.Println("playStat:", playStat)
 			if playStat == 0 {
-				vi.sendMessageToChannel(channelID, "See you later.")
-
 				//play process is finished so we can set nowPlayingMessageID
 				//to empty string to trigger send new now playing message in
 				//new play process.
@@ -603,65 +907,96 @@ func (vi *VoiceInstance) playQueueFunc(channelID string) {
 //in to one.
 //This function is suitable for handling spotify playlist download process.
 func (vi *VoiceInstance) processDownloadQueue(channelID string) {
-	if vi.downloadQueue.Empty() {
+	songInstance := vi.takeSong(vi.downloadQueue)
+	if songInstance == nil {
 		return
 	}
 
-	nextItem, err := vi.downloadQueue.Get(1)
+	err := vi.prepareSong(songInstance, channelID)
 	if err != nil {
 		log.Println(err)
 		return
 	}
+}
 
-	songInstance := getSongInstanceFromInterface(nextItem[0])
-	if songInstance == nil {
-		log.Println("Error while converting interface {} to SongInstance{}.")
-		return
+//prepareSongs prepares the songs one by one in background for the on going
+//play process. Play process ends when the play queue is empty, so the songs
+//are put into the play queue instead of the download queue, and the play
+//process waits for them while they are being prepared.
+func (vi *VoiceInstance) prepareSongs(songs []*SongInstance, channelID string) {
+	atomic.AddInt32(&vi.preparing, 1)
+	playQueue := vi.playQueue
+
+	go func() {
+		defer atomic.AddInt32(&vi.preparing, -1)
+
+		for _, song := range songs {
+			//play queue is replaced when it is cleared by !stop or !clear.
+			if vi.playQueue != playQueue {
+				return
+			}
+
+			err := vi.prepareSong(song, channelID)
+			if err != nil {
+				log.Println(err)
+			}
+		}
+	}()
+}
+
+//waitPreparedSong waits while the play queue is empty and songs are being
+//prepared for the play process, so that the play process doesn't end before
+//they are put into the play queue. !stop ends the wait.
+func (vi *VoiceInstance) waitPreparedSong() {
+	for vi.playQueue.Empty() && atomic.LoadInt32(&vi.preparing) > 0 && !vi.stop {
+		time.Sleep(pausePollInterval)
+	}
+}
+
+//prepareSong downloads the given song if it needs to be downloaded,
+//then adds it to the play queue. Result is recorded to the report of
+//the song if it is from a playlist file.
+func (vi *VoiceInstance) prepareSong(songInstance *SongInstance, channelID string) error {
+	err := vi.loadSong(songInstance, channelID)
+	songInstance.report.finish(songInstance, err)
+	return err
+}
+
+//loadSong downloads the given song if it needs to be downloaded,
+//then adds it to the play queue.
+func (vi *VoiceInstance) loadSong(songInstance *SongInstance, channelID string) error {
+	//local files and streams are played from where they are.
+	if songInstance.isLocal || songInstance.streamUrl != "" {
+		vi.queueSong(vi.playQueue, songInstance)
+		return nil
 	}
 
 	//if songInstance struct's videoID field is not empty,
 	//then it is Youtube related song(playlist or track),
 	//so no need get video ID again.
 	if strings.Compare(songInstance.videoID, "") != 0 {
-		err = vi.downloadID(songInstance, channelID)
-		if err != nil {
-			log.Println(err)
-			return
-		}
-	} else {
-		err = vi.downloadQuery(songInstance, channelID)
-		if err != nil {
-			log.Println(err)
-			return
-		}
+		return vi.downloadID(songInstance, channelID)
 	}
+	return vi.downloadQuery(songInstance, channelID)
 }
 
-func (vi *VoiceInstance) downloadSelection(searchResult *youtube.SearchResult, channelID string) error {
-	songPath, err := youtube.DownloadVideo(searchResult.VideoTitle, searchResult.VideoID)
+//vide supra processDownloadQueue function comment.
+//Song is matched to the Youtube video that is the most likely
+//to be the same recording instead of the first search result.
+func (vi *VoiceInstance) downloadQuery(songInstance *SongInstance, channelID string) error {
+	searchResult, _, err := yt.MatchTrack(songInstance.artist, songInstance.title, songInstance.expectedDuration)
 	if err != nil {
-		vi.sendMessageToChannel(channelID, "Unexpected thing happend. Try again.")
+		vi.sendMessageToChannel(channelID, youtubeErrorMessage(err))
 		return err
 	}
 
-	coverPath := DefaultCoverPath
-
-	songInstance := &SongInstance{
-		songPath:  songPath,
-		coverPath: coverPath,
-		coverUrl:  DefaultCoverUrl,
+	if !vi.checkPolicy(vi.guildID(), channelID, songInstance, searchResult) {
+		return fmt.Errorf("%s is rejected by the content policy", songInstance.title)
 	}
 
-	vi.playQueue.Put(songInstance)
-	return nil
-}
-
-//vide supra processDownloadQueue function comment.
-func (vi *VoiceInstance) downloadQuery(songInstance *SongInstance, channelID string) error {
-	query := songInstance.artist + " " + songInstance.title
-	searchResult, err := yt.SearchDownload(query)
+	searchResult.VideoPath, err = youtube.DownloadVideo(searchResult.VideoTitle, searchResult.VideoID)
 	if err != nil {
-		vi.sendMessageToChannel(channelID, "Query is insufficient to find a result. Try again.")
+		vi.sendMessageToChannel(channelID, "Unexpected thing happend. Try again.")
 		return err
 	}
 
@@ -678,13 +1013,29 @@ func (vi *VoiceInstance) downloadQuery(songInstance *SongInstance, channelID str
 	songInstance.duration = searchResult.Duration
 	songInstance.videoID = searchResult.VideoID
 
-	vi.playQueue.Put(songInstance)
+	vi.queueSong(vi.playQueue, songInstance)
 	return nil
 }
 
 //downloadID calls the function that download video in the given songIntance argument,
 //then add download songInstance to playQueue.
 func (vi *VoiceInstance) downloadID(songInstance *SongInstance, channelID string) error {
+	//songs of saved playlists only have their video IDs,
+	//so channel and age restriction of the video are asked.
+	var video *youtube.SearchResult
+	if needsVideoInfo(vi.guildID()) {
+		info, err := yt.GetInfoByID(songInstance.videoID)
+		if err != nil {
+			log.Println(err)
+		} else {
+			video = info
+		}
+	}
+
+	if !vi.checkPolicy(vi.guildID(), channelID, songInstance, video) {
+		return fmt.Errorf("%s is rejected by the content policy", songInstance.title)
+	}
+
 	songPath, err := youtube.DownloadVideo(songInstance.title, songInstance.videoID)
 	if err != nil {
 		return err
@@ -699,54 +1050,76 @@ func (vi *VoiceInstance) downloadID(songInstance *SongInstance, channelID string
 	songInstance.songPath = songPath
 	songInstance.coverPath = coverPath
 
-	vi.playQueue.Put(songInstance)
+	vi.queueSong(vi.playQueue, songInstance)
 	return nil
 }
 
-func (vi *VoiceInstance) downloadPlayQuery(query, channelID string) error {
-	searchResult, err := yt.SearchDownload(query)
+func (vi *VoiceInstance) downloadPlayQuery(query string, m *discordgo.MessageCreate) error {
+	searchResult, err := yt.GetVideoID(query)
 	if err != nil {
-		vi.sendMessageToChannel(channelID, "Query is insufficient to find a result. Try again.")
+		vi.sendMessageToChannel(m.ChannelID, youtubeErrorMessage(err))
 		log.Printf("Putting %s to the error queue.", query)
 		vi.errQueue.Put(query)
 		return err
 	}
 
 	songInstance := &SongInstance{
-		title:     searchResult.VideoTitle,
-		songPath:  searchResult.VideoPath,
-		coverPath: DefaultCoverPath,
-		videoID:   searchResult.VideoID,
-		duration:  searchResult.Duration,
+		title:         searchResult.VideoTitle,
+		coverPath:     DefaultCoverPath,
+		videoID:       searchResult.VideoID,
+		duration:      searchResult.Duration,
+		requesterID:   m.Author.ID,
+		requesterName: requesterName(m),
+	}
+
+	//found video is checked before it is downloaded.
+	if !vi.checkPolicy(m.GuildID, m.ChannelID, songInstance, searchResult) {
+		return fmt.Errorf("%s is rejected by the content policy", songInstance.title)
+	}
+
+	songInstance.songPath, err = youtube.DownloadVideo(searchResult.VideoTitle, searchResult.VideoID)
+	if err != nil {
+		vi.sendMessageToChannel(m.ChannelID, "Unexpected thing happend. Try again.")
+		return err
 	}
 
-	vi.playQueue.Put(songInstance)
+	vi.queueSong(vi.playQueue, songInstance)
 	return nil
 }
 
 func (vi *VoiceInstance) processPlayQueue(playStat chan<- int, messageChannelID string) {
 	stop := make(chan int)
-	nextItem, err := vi.playQueue.Get(1)
-	if err != nil {
-		log.Printf("Error while getting item from playlist queue: %v", err)
-		return
-	}
+	vi.applyFairQueue()
 
-	songInstance := getSongInstanceFromInterface(nextItem[0])
+	//songs could be removed from the queue after the play process checked it.
+	songInstance := vi.takeSong(vi.playQueue)
 	if songInstance == nil {
-		log.Println("Error while converting interface {} to SongInstance{}.")
+		vi.isPlaying = false
+		playStat <- 0
 		return
 	}
 
 	songPath := songInstance.songPath
 	coverPath := songInstance.coverPath
 
-	err = vi.sendEmbedNowPlayingMessage(messageChannelID, songInstance)
+	vi.resetSkipVotes()
+	err := vi.sendEmbedNowPlayingMessage(messageChannelID, songInstance)
 	if err != nil {
 		log.Println(err)
 	}
-	go vi.playAudioFile(songPath, stop)
+	vi.nowPlaying = songInstance
+	vi.publishNowPlaying()
+	vi.publishQueue()
+	startedAt := time.Now()
+	atomic.StoreInt64(&vi.sentFrames, 0)
+	go vi.playAudioFile(songInstance, messageChannelID, stop)
 	stat := <-stop
+	vi.nowPlaying = nil
+	vi.lastRequesterID = songInstance.requesterID
+
+	vi.recordPlay(songInstance, startedAt)
+	vi.publishNowPlaying()
+	vi.publishHistory()
 
 	vi.playHistoryList.PushBack(songInstance)
 
@@ -756,15 +1129,73 @@ func (vi *VoiceInstance) processPlayQueue(playStat chan<- int, messageChannelID
 			log.Println(embedPlayHistoryErr)
 		}
 		vi.playHistoryList = list.New()
-		util.DeleteSoundAndCoverFile(songPath, coverPath)
+
+		//streams and local files are not downloaded, so there is no file to delete.
+		if songInstance.streamUrl == "" && !songInstance.isLocal {
+			util.DeleteSoundAndCoverFile(songPath, coverPath)
+		}
 	}
 	playStat <- stat
 }
 
-func (vi *VoiceInstance) playAudioFile(filename string, stop chan<- int) {
-	// Create a shell command "object" to run.
-	run := exec.Command("ffmpeg", "-i", filename, "-f", "s16le", "-ar",
+//ffmpegInputArgs returns ffmpeg input arguments of the given song.
+//Downloaded songs are read from the disk, direct audio file URLs are
+//read by ffmpeg itself and radio streams are fed through stdin after
+//their ICY metadata is stripped.
+func ffmpegInputArgs(songInstance *SongInstance) []string {
+	if songInstance.isLive {
+		return []string{"-i", "pipe:0"}
+	}
+
+	args := []string{}
+	if songInstance.startTime > 0 {
+		args = append(args, "-ss", strconv.Itoa(int(songInstance.startTime.Seconds())))
+	}
+
+	if songInstance.streamUrl == "" {
+		return append(args, "-i", songInstance.songPath)
+	}
+
+	return append(args, "-reconnect", "1", "-reconnect_streamed", "1",
+		"-reconnect_delay_max", "5", "-i", songInstance.streamUrl)
+}
+
+func (vi *VoiceInstance) playAudioFile(songInstance *SongInstance, messageChannelID string, stop chan<- int) {
+	ffmpegArgs := append(ffmpegInputArgs(songInstance), "-f", "s16le", "-ar",
 		strconv.Itoa(frameRate), "-ac", strconv.Itoa(channels), "pipe:1")
+
+	// Create a shell command "object" to run.
+	run := exec.Command("ffmpeg", ffmpegArgs...)
+
+	//radio streams are read here to show stream titles as now playing.
+	//titles are read in another goroutine, they are passed to the play
+	//loop so that the song is only changed here. Only the last title is kept.
+	var titles chan string
+	if songInstance.isLive {
+		titles = make(chan string, 1)
+		radio, err := stream.Open(songInstance.streamUrl, func(title string) {
+			select {
+			case <-titles:
+			default:
+			}
+			titles <- title
+		})
+		if err != nil {
+			log.Println(err)
+			vi.sendMessageToChannel(messageChannelID, "Couldn't connect to the radio stream. Please, Try again later.")
+			vi.isPlaying = false
+			//continue with the next song like the radio is ended.
+			if !vi.playQueue.Empty() {
+				stop <- 1
+				return
+			}
+			stop <- 0
+			return
+		}
+		defer radio.Close()
+		run.Stdin = radio
+	}
+
 	ffmpegout, err := run.StdoutPipe()
 	if err != nil {
 		log.Println("StdoutPipe Error:", err)
@@ -782,14 +1213,41 @@ func (vi *VoiceInstance) playAudioFile(filename string, stop chan<- int) {
 	}
 
 	send := make(chan []int16, 2)
+	defer close(send)
 
+	//frames sent to Discord from the start time of the song in this call.
+	//Frames waiting in the send channel are not counted, so the song is
+	//resumed from the last frame that is actually sent.
+	var sent int64
+
+	//SendPCM returns when the voice connection is lost.
+	pcmDone := make(chan struct{})
 	go func() {
-		SendPCM(vi.dgv, send)
+		SendPCM(vi.dgv, send, func() {
+			sent++
+			atomic.AddInt64(&vi.sentFrames, 1)
+		})
+		close(pcmDone)
 	}()
 
 	for {
+		//wait while the song is paused, skip and stop are still handled.
+		for vi.isPaused() && !vi.skip && !vi.stop {
+			time.Sleep(pausePollInterval)
+		}
+
+		select {
+		case title := <-titles:
+			vi.updateStreamTitle(songInstance, messageChannelID, title)
+		default:
+		}
+
 		audiobuf := make([]int16, frameSize*channels)
 		err = binary.Read(ffmpegbuf, binary.LittleEndian, &audiobuf)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			vi.waitPreparedSong()
+		}
+
 		//song is played and there is still song to play in the play queue
 		if (err == io.EOF || err == io.ErrUnexpectedEOF) && !vi.playQueue.Empty() {
 			vi.isPlaying = false
@@ -816,6 +1274,8 @@ func (vi *VoiceInstance) playAudioFile(filename string, stop chan<- int) {
 
 		//handle !skip
 		if vi.skip == true {
+			vi.waitPreparedSong()
+
 			//if playqueue is not empty send 1(int) to the channel
 			//to play next song on the queue.
 			if vi.isPlaying == true && !vi.playQueue.Empty() {
@@ -842,16 +1302,46 @@ func (vi *VoiceInstance) playAudioFile(filename string, stop chan<- int) {
 			err = run.Process.Kill()
 			vi.downloadQueue = createNewQueue()
 			vi.playQueue = clearPlaylistQueue(vi.playQueue)
+			vi.publishQueue()
 			stop <- 0
 			return
 		}
 
+		if volume := atomic.LoadInt64(&vi.volume); volume != defaultVolume {
+			applyVolume(audiobuf, volume)
+		}
+
 		select {
 		case send <- audiobuf:
+		case <-pcmDone:
+			err = run.Process.Kill()
+			go vi.resumeSong(songInstance, messageChannelID, stop, sent)
+			return
 		}
 	}
 }
 
+//updateStreamTitle shows the given radio stream title as the
+//title of the playing song in the now playing message. It is called
+//from the play loop, message is sent in background not to delay audio.
+func (vi *VoiceInstance) updateStreamTitle(songInstance *SongInstance, channelID, title string) {
+	if title == "" {
+		return
+	}
+
+	log.Printf("Radio stream title changed: %s\n", title)
+	songInstance.artist = ""
+	songInstance.title = title
+
+	shown := *songInstance
+	go func() {
+		err := vi.sendEmbedNowPlayingMessage(channelID, &shown)
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+}
+
 //disconnectBot disconnects bot from the voice channel
 func (vi *VoiceInstance) disconnectBot() {
 	err := vi.dgv.Speaking(false)
@@ -862,12 +1352,14 @@ func (vi *VoiceInstance) disconnectBot() {
 	log.Printf("Bot disconnected from the voice channel.\n")
 	vi.stop = false
 	vi.isPlaying = false
+	vi.dgv = nil
 	return
 }
 
 // SendPCM will receive on the provied channel encode
 // received PCM data into Opus then send that to Discordgo
-func SendPCM(v *discordgo.VoiceConnection, pcm <-chan []int16) {
+// onSent is called after each frame is sent.
+func SendPCM(v *discordgo.VoiceConnection, pcm <-chan []int16, onSent func()) {
 	if pcm == nil {
 		return
 	}
@@ -895,20 +1387,20 @@ func SendPCM(v *discordgo.VoiceConnection, pcm <-chan []int16) {
 			return
 		}
 
-		if v.Ready == false || v.OpusSend == nil {
-			// OnError(fmt.Sprintf("Discordgo not ready for opus packets. %+v : %+v", v.Ready, v.OpusSend), nil)
-			// Sending errors here might not be suited
+		// send encoded opus data to the sendOpus channel, exit if
+		// the voice connection isn't ready in time.
+		if !sendOpus(v, opus) {
+			log.Println("Voice connection is not ready, stopped sending audio.")
 			return
 		}
-		// send encoded opus data to the sendOpus channel
-		v.OpusSend <- opus
+		onSent()
 	}
 }
 
-func (vi *VoiceInstance) skipSong(m *discordgo.MessageCreate) {
-	//if currently no song is playing, no need to skip it.
+func (vi *VoiceInstance) stopSong(m *discordgo.MessageCreate) {
+	//if currently no song is playing, no need to stop it.
 	if vi.isPlaying == false {
-		log.Println("No song is playing. skip returning.")
+		log.Println("No song is playing. stop returning.")
 		return
 	}
 
@@ -927,37 +1419,68 @@ func (vi *VoiceInstance) skipSong(m *discordgo.MessageCreate) {
 	}
 	for _, vs := range g.VoiceStates {
 		if vs.UserID == m.Author.ID {
-			vi.skip = true
+			vi.stop = true
 			return
 		}
 	}
 }
 
-func (vi *VoiceInstance) stopSong(m *discordgo.MessageCreate) {
-	//if currently no song is playing, no need to stop it.
-	if vi.isPlaying == false {
-		log.Println("No song is playing. stop returning.")
+//clearQueue removes the songs that are waiting in the play
+//and download queues. Now playing song is not stopped.
+func (vi *VoiceInstance) clearQueue(m *discordgo.MessageCreate) {
+	if vi.playQueue.Empty() && vi.downloadQueue.Empty() {
+		vi.sendMessageToChannel(m.ChannelID, "Play queue is already empty.")
 		return
 	}
 
-	c, err := vi.session.State.Channel(m.ChannelID)
-	if err != nil {
-		log.Printf("Couldn't find channel: %v\n", err)
-		// Could not find channel.
+	vi.downloadQueue = createNewQueue()
+	vi.playQueue = clearPlaylistQueue(vi.playQueue)
+	vi.publishQueue()
+	vi.sendMessageToChannel(m.ChannelID, "Play queue is cleared.")
+}
+
+//setVolume sets the volume of the songs in percent,
+//or sends the current volume if no volume is given.
+func (vi *VoiceInstance) setVolume(args string, m *discordgo.MessageCreate) {
+	args = strings.TrimSuffix(strings.TrimSpace(args), "%")
+	if args == "" {
+		vi.sendMessageToChannel(m.ChannelID, fmt.Sprintf("Volume is %d%%.", atomic.LoadInt64(&vi.volume)))
 		return
 	}
 
-	g, err := vi.session.Guild(c.GuildID)
+	volume, err := strconv.Atoi(args)
+	if err == nil {
+		err = vi.changeVolume(volume)
+	}
 	if err != nil {
-		log.Printf("Couldn't find guild: %v\n", err)
-		// Could not find guild.
+		vi.sendMessageToChannel(m.ChannelID, fmt.Sprintf("Usage: !volume [0-%d]", maxVolume))
 		return
 	}
-	for _, vs := range g.VoiceStates {
-		if vs.UserID == m.Author.ID {
-			vi.stop = true
-			return
+
+	vi.sendMessageToChannel(m.ChannelID, fmt.Sprintf("Volume is set to %d%%.", volume))
+}
+
+//changeVolume sets the volume of the songs in percent.
+func (vi *VoiceInstance) changeVolume(volume int) error {
+	if volume < 0 || volume > maxVolume {
+		return fmt.Errorf("Volume must be between 0 and %d.", maxVolume)
+	}
+
+	atomic.StoreInt64(&vi.volume, int64(volume))
+	vi.publishNowPlaying()
+	return nil
+}
+
+//applyVolume scales the PCM samples by the given volume in percent.
+func applyVolume(samples []int16, volume int64) {
+	for i, sample := range samples {
+		scaled := int64(sample) * volume / 100
+		if scaled > math.MaxInt16 {
+			scaled = math.MaxInt16
+		} else if scaled < math.MinInt16 {
+			scaled = math.MinInt16
 		}
+		samples[i] = int16(scaled)
 	}
 }
 
@@ -1009,23 +1532,29 @@ func (vi *VoiceInstance) sendFileWithMessage(channelID, text, coverPath string)
 }
 
 //sentMessageEditEmbed edits last sent message(in our case this message is sendSearchResult)
-//to show now playing message.
-func (vi *VoiceInstance) sentMessageEditEmbed(channelID, messageID string, searchResult *youtube.SearchResult) {
+//to show now playing message and the other chosen results.
+func (vi *VoiceInstance) sentMessageEditEmbed(channelID, messageID string, searchResults []youtube.SearchResult) {
+	fields := []*discordgo.MessageEmbedField{}
+	for i, searchResult := range searchResults {
+		name := "Queued: "
+		if i == 0 {
+			name = "Now Playing: "
+		}
+
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   name,
+			Value:  formatSearchResultText(&searchResult),
+			Inline: false,
+		})
+	}
+
 	embed := &discordgo.MessageEmbed{
-		Author: &discordgo.MessageEmbedAuthor{},
-		Color:  0x26e232,
-		Fields: []*discordgo.MessageEmbedField{
-			&discordgo.MessageEmbedField{
-				Name: "Now Playing: ",
-				Value: formatEmbededLinkText(searchResult.VideoTitle,
-					searchResult.Duration,
-					searchResult.VideoID),
-				Inline: false,
-			},
-		},
+		Author:    &discordgo.MessageEmbedAuthor{},
+		Color:     0x26e232,
+		Fields:    fields,
 		Timestamp: time.Now().Format(time.RFC3339),
 		Image: &discordgo.MessageEmbedImage{
-			URL: searchResult.CoverPath,
+			URL: searchResults[0].CoverUrl,
 		},
 	}
 	_, err := vi.session.ChannelMessageEditEmbed(channelID, messageID, embed)
@@ -1062,6 +1591,13 @@ func (vi *VoiceInstance) sendEmbedPlayHistory(channelID string) error {
 
 func (vi *VoiceInstance) sendEmbedNowPlayingMessage(channelID string, songInstance *SongInstance) error {
 	embedContent := createEmbedNowPlayingMessage(songInstance)
+	if votes := vi.skipVoteStatus(); votes != "" {
+		embedContent.Fields = append(embedContent.Fields, &discordgo.MessageEmbedField{
+			Name:   "Skip Votes",
+			Value:  votes,
+			Inline: false,
+		})
+	}
 
 	//if vi.nowPlayingMessageID is a empty string than
 	//we have to create a new embed message.
@@ -1092,10 +1628,8 @@ func createEmbedNowPlayingMessage(songInstance *SongInstance) *discordgo.Message
 		Color:  0x26e232,
 		Fields: []*discordgo.MessageEmbedField{
 			&discordgo.MessageEmbedField{
-				Name: "Now Playing",
-				Value: formatEmbededLinkText(songInstance.title,
-					songInstance.duration,
-					songInstance.videoID),
+				Name:   "Now Playing",
+				Value:  formatEmbededSongText(songInstance) + requestedByText(songInstance),
 				Inline: false,
 			},
 		},
@@ -1152,6 +1686,9 @@ func (vi *VoiceInstance) sendSearchResultMessageToChannel(channelID string, sear
 		Color:     0xfd0057,
 		Fields:    createMessageEmbedFields(searchResults),
 		Timestamp: time.Now().Format(time.RFC3339),
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: "Type the numbers of the songs like \"1 3\" or react with a number. Type cancel to cancel.",
+		},
 	}
 
 	message, err := vi.sendEmbeddedMessageToChannel(channelID, embed)
@@ -1169,7 +1706,7 @@ func createMessageEmbedFields(searchResults *[]youtube.SearchResult) []*discordg
 	for _, element := range *searchResults {
 		embedField := &discordgo.MessageEmbedField{
 			Name:   strconv.Itoa(resultCounter) + ")",
-			Value:  formatEmbededLinkText(element.VideoTitle, element.Duration, element.VideoID),
+			Value:  formatSearchResultText(&element),
 			Inline: false,
 		}
 		messageEmbedFields = append(messageEmbedFields, embedField)
@@ -1194,7 +1731,7 @@ func createMessageEmbedFieldsPlayQueue(songInstInterfaceArray []interface{}) []*
 
 		embedField := &discordgo.MessageEmbedField{
 			Name:   strconv.Itoa(counter) + ")",
-			Value:  formatEmbededLinkText(instance.title, instance.duration, instance.videoID),
+			Value:  formatEmbededSongText(instance) + requestedByText(instance),
 			Inline: false,
 		}
 		messageEmbedFields = append(messageEmbedFields, embedField)
@@ -1208,6 +1745,48 @@ func formatEmbededLinkText(title, duration, id string) string {
 	return "[" + title + "(" + duration + ")" + "](" + youtubeUrlPrefix + id + ")"
 }
 
+//formatEmbededSongText is a helper function to create embeded link
+//text of the given song. Streams are linked to their own URLs.
+func formatEmbededSongText(songInstance *SongInstance) string {
+	if songInstance.streamUrl == "" {
+		return formatEmbededLinkText(songInstance.title, songInstance.duration, songInstance.videoID)
+	}
+
+	duration := songInstance.duration
+	if songInstance.isLive {
+		duration = "live"
+	}
+	return "[" + songInstance.title + "(" + duration + ")" + "](" + songInstance.streamUrl + ")"
+}
+
+//spotifyErrorMessage returns the message that is shown to
+//users when a Spotify request fails with the given error.
+func spotifyErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, spotify.ErrNotFound):
+		return "Couldn't find it on Spotify. It may be private or removed."
+	case errors.Is(err, spotify.ErrRateLimited):
+		return "Spotify is busy right now. Please, Try again in a few minutes."
+	case errors.Is(err, spotify.ErrUnauthorized):
+		return "Couldn't sign in to Spotify. Please, Tell the bot owner to check Spotify credentials."
+	}
+	return "Unexpected thing is happened. Please, Try again."
+}
+
+//youtubeErrorMessage returns the message that is shown to
+//users when a Youtube request fails with the given error.
+func youtubeErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, youtube.ErrNotFound):
+		return "Query is insufficient to find a result. It may be private or removed. Try again."
+	case errors.Is(err, youtube.ErrQuotaExceeded):
+		return "Youtube search limit of the bot is reached for today. Please, Try again later."
+	case errors.Is(err, youtube.ErrTransport):
+		return "Couldn't reach Youtube. Please, Try again in a few minutes."
+	}
+	return "Unexpected thing is happened. Please, Try again."
+}
+
 //createNewQueue creates new queue and
 //returns newly created queue.
 func createNewQueue() *queue.Queue {
@@ -1231,6 +1810,10 @@ func clearPlaylistQueue(playlistQueue *queue.Queue) *queue.Queue {
 			return createNewQueue()
 		}
 
+		if songInstance.isLocal {
+			continue
+		}
+
 		util.DeleteFile(songInstance.songPath)
 		util.DeleteFile(songInstance.coverPath)
 	}
@@ -1249,15 +1832,7 @@ func getSongInstanceFromInterface(object interface{}) *SongInstance {
 	inst, ok := object.(*SongInstance)
 
 	if ok {
-		newInstance := SongInstance{
-			title:     inst.title,
-			artist:    inst.artist,
-			songPath:  inst.songPath,
-			coverUrl:  inst.coverUrl,
-			coverPath: inst.coverPath,
-			videoID:   inst.videoID,
-			duration:  inst.duration,
-		}
+		newInstance := *inst
 		return &newInstance
 	}
 	return nil
@@ -0,0 +1,123 @@
+package bot
+
+import (
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var channelMentionRegex = regexp.MustCompile(`^<#(\d+)>$`)
+
+//joinVoiceChannel joins the bot to the given voice channel. Bot is not
+//pulled away from the listeners of the playing song unless the author
+//of the message is a DJ.
+func (vi *VoiceInstance) joinVoiceChannel(s *discordgo.Session, m *discordgo.MessageCreate, guildID, channelID string) bool {
+	dgv := vi.dgv
+	if dgv != nil && dgv.GuildID == guildID && dgv.ChannelID == channelID {
+		return true
+	}
+
+	if dgv != nil && vi.isPlaying && !isDJ(s, m) {
+		log.Printf("Refusing to move to %s for %s-%s: Playing in %s.\n", channelID, m.Author.Username, m.Author.ID, dgv.ChannelID)
+		vi.sendMessageToChannel(m.ChannelID, "I'm playing in <#"+dgv.ChannelID+">. Join that channel, or ask a DJ to move me.")
+		return false
+	}
+
+	dgv, err := s.ChannelVoiceJoin(guildID, channelID, false, true)
+	if err != nil {
+		log.Printf("Couldn't join the voice channel: %v\n", err)
+		vi.sendMessageToChannel(m.ChannelID, "Couldn't join the voice channel. Please, Try again.")
+		return false
+	}
+	vi.dgv = dgv
+	return true
+}
+
+//joinCommand joins the bot to the voice channel of the author.
+func (vi *VoiceInstance) joinCommand(s *discordgo.Session, m *discordgo.MessageCreate) {
+	if !vi.validateMessageAndJoinVoiceChannel(s, m) {
+		return
+	}
+
+	vi.sendMessageToChannel(m.ChannelID, "Joined <#"+vi.dgv.ChannelID+">.")
+
+	//bot leaves if nothing is played after joining.
+	if !vi.isPlaying {
+		vi.textChannelID = m.ChannelID
+		vi.startIdleTimer()
+	}
+}
+
+//leaveCommand stops playing and leaves the voice channel. While a song is
+//playing, only DJs and the only listener of the song can make bot leave.
+func (vi *VoiceInstance) leaveCommand(s *discordgo.Session, m *discordgo.MessageCreate) {
+	if vi.dgv == nil {
+		vi.sendMessageToChannel(m.ChannelID, "I'm not in a voice channel.")
+		return
+	}
+
+	if vi.isPlaying && !isDJ(s, m) {
+		listeners := vi.listeners()
+		if len(listeners) != 1 || !listeners[m.Author.ID] {
+			vi.sendMessageToChannel(m.ChannelID, "Only DJs can make me leave while others are listening.")
+			return
+		}
+	}
+
+	vi.textChannelID = m.ChannelID
+	vi.leave()
+}
+
+//moveCommand moves the bot to the given voice channel. Channel can be
+//given with its mention, ID or name.
+func (vi *VoiceInstance) moveCommand(args string, s *discordgo.Session, m *discordgo.MessageCreate) {
+	args = strings.TrimSpace(args)
+	if args == "" || m.GuildID == "" {
+		vi.sendMessageToChannel(m.ChannelID, "Usage: !move <voice channel>")
+		return
+	}
+
+	channel, ok := findVoiceChannel(s, m.GuildID, args)
+	if !ok {
+		vi.sendMessageToChannel(m.ChannelID, "Couldn't find the voice channel: "+args+". Please, Try again.")
+		return
+	}
+
+	wasConnected := vi.dgv != nil
+	if !vi.joinVoiceChannel(s, m, m.GuildID, channel.ID) {
+		return
+	}
+	vi.sendMessageToChannel(m.ChannelID, "Moved to <#"+channel.ID+">.")
+
+	//bot leaves if nothing is played after joining.
+	if !wasConnected && !vi.isPlaying {
+		vi.textChannelID = m.ChannelID
+		vi.startIdleTimer()
+	}
+}
+
+//findVoiceChannel finds the voice channel in the guild with the given
+//mention, ID or name.
+func findVoiceChannel(s *discordgo.Session, guildID, value string) (*discordgo.Channel, bool) {
+	guild, err := s.State.Guild(guildID)
+	if err != nil {
+		log.Printf("Couldn't find guild: %v\n", err)
+		return nil, false
+	}
+
+	if match := channelMentionRegex.FindStringSubmatch(value); match != nil {
+		value = match[1]
+	}
+
+	for _, channel := range guild.Channels {
+		if channel.Type != discordgo.ChannelTypeGuildVoice {
+			continue
+		}
+		if channel.ID == value || strings.EqualFold(channel.Name, value) {
+			return channel, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,305 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	//loginLinkDuration is how long a login link can be used.
+	loginLinkDuration = 10 * time.Minute
+	//sessionDuration is how long a dashboard login lasts.
+	sessionDuration = 24 * time.Hour
+	//sessionCookie keeps the session ID of the dashboard.
+	sessionCookie = "feanor_session"
+
+	//eventPingInterval is how often the event sockets are pinged.
+	eventPingInterval = 30 * time.Second
+	//eventWriteTimeout is how long an event can take to be written.
+	eventWriteTimeout = 10 * time.Second
+)
+
+//dashboardSession is a dashboard login of a user, it can
+//only control the player of the guild that it is created in.
+type dashboardSession struct {
+	guildID string
+	userID  string
+	expires time.Time
+}
+
+//sessionKey is the request context key of the dashboard session.
+type sessionKey struct{}
+
+//sessionStore keeps the login links and sessions of the dashboard in memory,
+//so users have to log in again after bot is restarted.
+type sessionStore struct {
+	mu sync.Mutex
+	//sessions by login link tokens, they are removed when they are used.
+	logins map[string]dashboardSession
+	//sessions by session IDs.
+	sessions map[string]dashboardSession
+}
+
+var sessions = newSessionStore()
+
+//upgrader rejects the event sockets from other origins.
+var upgrader = websocket.Upgrader{}
+
+func newSessionStore() *sessionStore {
+	return &sessionStore{
+		logins:   map[string]dashboardSession{},
+		sessions: map[string]dashboardSession{},
+	}
+}
+
+//newLogin creates a login link token for the user in the guild.
+func (st *sessionStore) newLogin(guildID, userID string) (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.removeExpired()
+	st.logins[token] = dashboardSession{
+		guildID: guildID,
+		userID:  userID,
+		expires: time.Now().Add(loginLinkDuration),
+	}
+	return token, nil
+}
+
+//login uses the login link token and returns the ID of the new session.
+//Tokens can only be used once.
+func (st *sessionStore) login(token string) (string, dashboardSession, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	login, ok := st.logins[token]
+	delete(st.logins, token)
+	if !ok || time.Now().After(login.expires) {
+		return "", dashboardSession{}, fmt.Errorf("Login link is invalid or expired.")
+	}
+
+	sessionID, err := randomToken()
+	if err != nil {
+		return "", dashboardSession{}, err
+	}
+
+	session := dashboardSession{
+		guildID: login.guildID,
+		userID:  login.userID,
+		expires: time.Now().Add(sessionDuration),
+	}
+	st.sessions[sessionID] = session
+	return sessionID, session, nil
+}
+
+//session returns the session with the given ID if it is not expired.
+func (st *sessionStore) session(sessionID string) (dashboardSession, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	session, ok := st.sessions[sessionID]
+	if !ok || time.Now().After(session.expires) {
+		delete(st.sessions, sessionID)
+		return dashboardSession{}, false
+	}
+	return session, true
+}
+
+//removeExpired removes the expired login links and sessions.
+//Caller must hold the lock.
+func (st *sessionStore) removeExpired() {
+	now := time.Now()
+	for token, login := range st.logins {
+		if now.After(login.expires) {
+			delete(st.logins, token)
+		}
+	}
+	for sessionID, session := range st.sessions {
+		if now.After(session.expires) {
+			delete(st.sessions, sessionID)
+		}
+	}
+}
+
+//randomToken returns a random hex token that can't be guessed.
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("Error while creating token: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+//requestSession returns the dashboard session of the request,
+//it is false for the requests that use the API token.
+func requestSession(r *http.Request) (dashboardSession, bool) {
+	session, ok := r.Context().Value(sessionKey{}).(dashboardSession)
+	return session, ok
+}
+
+//withSession adds the session of the cookie to the request context
+//if the request has a valid session cookie.
+func withSession(r *http.Request) (*http.Request, bool) {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return r, false
+	}
+
+	session, ok := sessions.session(cookie.Value)
+	if !ok {
+		return r, false
+	}
+	return r.WithContext(context.WithValue(r.Context(), sessionKey{}, session)), true
+}
+
+//dashboardURL returns the address that the login links point to.
+func dashboardURL() string {
+	if cfg.API.PublicURL != "" {
+		return strings.TrimSuffix(cfg.API.PublicURL, "/")
+	}
+	return "http://" + cfg.API.Address
+}
+
+//dashboardCommand sends a login link of the dashboard to the author in DM.
+//Dashboard can only control the player of the guild that link is asked in.
+func (vi *VoiceInstance) dashboardCommand(s *discordgo.Session, m *discordgo.MessageCreate) {
+	if !cfg.API.Enabled {
+		vi.sendMessageToChannel(m.ChannelID, "Dashboard is not enabled.")
+		return
+	}
+
+	if m.GuildID == "" {
+		vi.sendMessageToChannel(m.ChannelID, "Dashboard can only be opened from a server.")
+		return
+	}
+
+	token, err := sessions.newLogin(m.GuildID, m.Author.ID)
+	if err != nil {
+		log.Println(err)
+		vi.sendErrorMessageToChannel(m.ChannelID)
+		return
+	}
+
+	dm, err := s.UserChannelCreate(m.Author.ID)
+	if err == nil {
+		//link is not embedded, so it isn't opened by Discord before the user.
+		_, err = s.ChannelMessageSend(dm.ID, fmt.Sprintf("Open the dashboard: <%s/login?token=%s>\nLink can be used once in %s.",
+			dashboardURL(), token, loginLinkDuration))
+	}
+	if err != nil {
+		log.Printf("Error while sending dashboard link to %s-%s: %v\n", m.Author.Username, m.Author.ID, err)
+		vi.sendMessageToChannel(m.ChannelID, "Couldn't send you a DM. Please, check your privacy settings and Try again.")
+		return
+	}
+
+	log.Printf("Sent dashboard login link to %s-%s.\n", m.Author.Username, m.Author.ID)
+	vi.sendMessageToChannel(m.ChannelID, "Sent you a login link in DM.")
+}
+
+//serveLogin logs in with the token of the login link and
+//redirects to the dashboard.
+func serveLogin(w http.ResponseWriter, r *http.Request) {
+	sessionID, session, err := sessions.login(r.URL.Query().Get("token"))
+	if err != nil {
+		http.Error(w, err.Error()+" Use !dashboard to get a new one.", http.StatusUnauthorized)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    sessionID,
+		Path:     "/",
+		MaxAge:   int(sessionDuration.Seconds()),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(dashboardURL(), "https://"),
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	log.Printf("User %s logged in to the dashboard of %s.\n", session.userID, session.guildID)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+//serveSession returns the guild and user of the dashboard session.
+func serveSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := requestSession(r)
+	if !ok {
+		writeAPIError(w, http.StatusUnauthorized, "Not logged in.")
+		return
+	}
+	writeAPIResponse(w, http.StatusOK, map[string]string{"guildId": session.guildID, "userId": session.userID})
+}
+
+//apiEvents sends the state of the player, then its changes as they
+//happen over a WebSocket connection.
+func apiEvents(w http.ResponseWriter, r *http.Request, guildID string, params []string) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		//upgrader sends the error response.
+		log.Printf("Error while opening event socket: %v\n", err)
+		return
+	}
+	defer conn.Close()
+
+	//events are subscribed before the state is sent, so no change is missed.
+	ch := events.subscribe(guildID)
+	defer events.unsubscribe(ch)
+
+	for _, event := range vi.playerState(guildID) {
+		conn.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+		if err := conn.WriteJSON(event); err != nil {
+			return
+		}
+	}
+
+	//messages from the dashboard are not used, they are read
+	//to know when the connection is closed.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(eventPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case event, ok := <-ch:
+			//dashboard is disconnected since it is too slow.
+			if !ok {
+				return
+			}
+
+			conn.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(eventWriteTimeout)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package bot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionStore(t *testing.T) {
+	st := newSessionStore()
+
+	token, err := st.newLogin("guild", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessionID, session, err := st.login(token)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	if session.guildID != "guild" || session.userID != "user" {
+		t.Errorf("Got session of %s in %s, want user in guild.", session.userID, session.guildID)
+	}
+
+	if _, ok := st.session(sessionID); !ok {
+		t.Error("Session is not found.")
+	}
+
+	//login links can only be used once.
+	if _, _, err := st.login(token); err == nil {
+		t.Error("Login link is used twice.")
+	}
+
+	expired, err := st.newLogin("guild", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	login := st.logins[expired]
+	login.expires = time.Now().Add(-time.Second)
+	st.logins[expired] = login
+	if _, _, err := st.login(expired); err == nil {
+		t.Error("Expired login link is used.")
+	}
+
+	session = st.sessions[sessionID]
+	session.expires = time.Now().Add(-time.Second)
+	st.sessions[sessionID] = session
+	if _, ok := st.session(sessionID); ok {
+		t.Error("Expired session is found.")
+	}
+}
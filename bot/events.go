@@ -0,0 +1,147 @@
+package bot
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hemreari/go-datastructures/queue"
+)
+
+const (
+	//eventBufferSize is how many events can wait for a dashboard. Slow
+	//dashboards are disconnected, they get the current state when they
+	//connect again.
+	eventBufferSize = 16
+	//queueEventDelay is how long queue changes are collected before they
+	//are sent, so adding a playlist doesn't send an event for every song.
+	queueEventDelay = 200 * time.Millisecond
+)
+
+//playerEvent is sent to the dashboards when the player changes.
+//Types are "nowPlaying", "queue" and "history".
+type playerEvent struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+//eventHub sends the player events to the dashboards of the guilds.
+type eventHub struct {
+	mu sync.Mutex
+	//guild IDs of the dashboards by their event channels.
+	subscribers map[chan playerEvent]string
+}
+
+var events = &eventHub{subscribers: map[chan playerEvent]string{}}
+
+//subscribe returns the channel that the events of the guild are sent to.
+func (h *eventHub) subscribe(guildID string) chan playerEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan playerEvent, eventBufferSize)
+	h.subscribers[ch] = guildID
+	return ch
+}
+
+//unsubscribe stops sending events to the channel and closes it.
+func (h *eventHub) unsubscribe(ch chan playerEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscribers[ch]; ok {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
+
+//hasSubscribers checks a dashboard of the guild is connected.
+func (h *eventHub) hasSubscribers(guildID string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, subscriberGuildID := range h.subscribers {
+		if subscriberGuildID == guildID {
+			return true
+		}
+	}
+	return false
+}
+
+//publish sends the event to the dashboards of the guild.
+func (h *eventHub) publish(guildID string, event playerEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch, subscriberGuildID := range h.subscribers {
+		if subscriberGuildID != guildID {
+			continue
+		}
+
+		select {
+		case ch <- event:
+		default:
+			log.Println("Dashboard is too slow for player events, disconnecting it.")
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+//playerState returns the events that show the current state of the player.
+func (vi *VoiceInstance) playerState(guildID string) []playerEvent {
+	songs := []apiSong{}
+	if vi.guildID() == guildID {
+		songs = vi.apiQueue()
+	}
+
+	return []playerEvent{
+		{"nowPlaying", vi.apiNowPlaying(guildID)},
+		{"queue", map[string]interface{}{"songs": songs}},
+		{"history", map[string]interface{}{"entries": apiHistory(guildID)}},
+	}
+}
+
+//publishNowPlaying sends the now playing song, pause state and volume to the dashboards.
+func (vi *VoiceInstance) publishNowPlaying() {
+	guildID := vi.guildID()
+	if !events.hasSubscribers(guildID) {
+		return
+	}
+	events.publish(guildID, playerEvent{"nowPlaying", vi.apiNowPlaying(guildID)})
+}
+
+//publishQueue sends the queue to the dashboards after queueEventDelay.
+func (vi *VoiceInstance) publishQueue() {
+	if !atomic.CompareAndSwapInt32(&vi.queueChanged, 0, 1) {
+		return
+	}
+
+	time.AfterFunc(queueEventDelay, func() {
+		atomic.StoreInt32(&vi.queueChanged, 0)
+
+		guildID := vi.guildID()
+		if !events.hasSubscribers(guildID) {
+			return
+		}
+		events.publish(guildID, playerEvent{"queue", map[string]interface{}{"songs": vi.apiQueue()}})
+	})
+}
+
+//publishHistory sends the recently played songs to the dashboards.
+func (vi *VoiceInstance) publishHistory() {
+	guildID := vi.guildID()
+	if !events.hasSubscribers(guildID) {
+		return
+	}
+	events.publish(guildID, playerEvent{"history", map[string]interface{}{"entries": apiHistory(guildID)}})
+}
+
+//queueSong adds the song to the given queue and sends the queue to the dashboards.
+func (vi *VoiceInstance) queueSong(q *queue.Queue, song *SongInstance) {
+	vi.queueMu.Lock()
+	q.Put(song)
+	vi.queueMu.Unlock()
+	vi.publishQueue()
+}
@@ -0,0 +1,272 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/hemreari/feanor-dcbot/history"
+	"github.com/hemreari/feanor-dcbot/playlist"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	//duration of an audio frame that is sent to Discord.
+	frameDuration = time.Duration(frameSize) * time.Second / time.Duration(frameRate)
+
+	defaultHistoryCount = 10
+	maxHistoryCount     = 25
+	topTrackCount       = 10
+	statsTopTrackCount  = 5
+)
+
+//recordPlay adds the given song to the play history with how long it is played.
+func (vi *VoiceInstance) recordPlay(songInstance *SongInstance, startedAt time.Time) {
+	//bot may leave the voice channel while the song ends.
+	dgv := vi.dgv
+	if historyDB == nil || dgv == nil {
+		return
+	}
+
+	played := time.Duration(atomic.LoadInt64(&vi.sentFrames)) * frameDuration
+	//song couldn't be started.
+	if played == 0 {
+		return
+	}
+
+	record := history.Record{
+		GuildID:       dgv.GuildID,
+		ChannelID:     dgv.ChannelID,
+		RequesterID:   songInstance.requesterID,
+		RequesterName: songInstance.requesterName,
+		Title:         songInstance.title,
+		Artist:        strings.TrimSpace(songInstance.artist),
+		StartedAt:     startedAt,
+		EndedAt:       time.Now(),
+		Played:        played,
+	}
+
+	entry, ok := songEntry(songInstance)
+	if ok {
+		record.Source = string(entry.Source)
+		record.SourceID = entry.ID
+	} else {
+		record.Source = string(playlist.SourceQuery)
+		record.SourceID = entry.DisplayTitle()
+	}
+
+	err := historyDB.Add(record)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+//showHistory sends the last played songs of the guild.
+func (vi *VoiceInstance) showHistory(args string, m *discordgo.MessageCreate) {
+	if m.GuildID == "" {
+		vi.sendMessageToChannel(m.ChannelID, "History can only be shown in a server.")
+		return
+	}
+
+	count := defaultHistoryCount
+	if args = strings.TrimSpace(args); args != "" {
+		n, err := strconv.Atoi(args)
+		if err != nil || n < 1 {
+			vi.sendMessageToChannel(m.ChannelID, "Usage: !history [number of songs]")
+			return
+		}
+		count = n
+	}
+	if count > maxHistoryCount {
+		count = maxHistoryCount
+	}
+
+	records, err := historyDB.Recent(m.GuildID, count)
+	if err != nil {
+		log.Println(err)
+		vi.sendErrorMessageToChannel(m.ChannelID)
+		return
+	}
+
+	if len(records) == 0 {
+		vi.sendMessageToChannel(m.ChannelID, "No songs are played yet.")
+		return
+	}
+
+	fields := []*discordgo.MessageEmbedField{}
+	for i, record := range records {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   fmt.Sprintf("%d) %s", i+1, formatAgo(time.Since(record.StartedAt))),
+			Value:  formatHistoryRecord(record),
+			Inline: false,
+		})
+	}
+
+	vi.sendListEmbed(m.ChannelID, "Play History:", fields)
+}
+
+//showTopTracks sends the most played songs of the guild in the given period.
+func (vi *VoiceInstance) showTopTracks(args string, m *discordgo.MessageCreate) {
+	if m.GuildID == "" {
+		vi.sendMessageToChannel(m.ChannelID, "Top songs can only be shown in a server.")
+		return
+	}
+
+	period := strings.ToLower(strings.TrimSpace(args))
+	since := time.Time{}
+	switch period {
+	case "", "week":
+		period = "week"
+		since = time.Now().AddDate(0, 0, -7)
+	case "month":
+		since = time.Now().AddDate(0, -1, 0)
+	case "all":
+	default:
+		vi.sendMessageToChannel(m.ChannelID, "Usage: !top [week|month|all]")
+		return
+	}
+
+	tracks, err := historyDB.Top(m.GuildID, since, topTrackCount)
+	if err != nil {
+		log.Println(err)
+		vi.sendErrorMessageToChannel(m.ChannelID)
+		return
+	}
+
+	if len(tracks) == 0 {
+		vi.sendMessageToChannel(m.ChannelID, "No songs are played in this period.")
+		return
+	}
+
+	titles := map[string]string{
+		"week":  "Top Songs of the Week:",
+		"month": "Top Songs of the Month:",
+		"all":   "Top Songs of All Time:",
+	}
+	vi.sendListEmbed(m.ChannelID, titles[period], trackCountFields(tracks))
+}
+
+//showUserStats sends the listening statistics of the mentioned user,
+//or the author if no one is mentioned.
+func (vi *VoiceInstance) showUserStats(m *discordgo.MessageCreate) {
+	if m.GuildID == "" {
+		vi.sendMessageToChannel(m.ChannelID, "Statistics can only be shown in a server.")
+		return
+	}
+
+	user := m.Author
+	if len(m.Mentions) > 0 {
+		user = m.Mentions[0]
+	}
+
+	stats, err := historyDB.Stats(m.GuildID, user.ID, statsTopTrackCount)
+	if err != nil {
+		log.Println(err)
+		vi.sendErrorMessageToChannel(m.ChannelID)
+		return
+	}
+
+	if stats.Plays == 0 {
+		vi.sendMessageToChannel(m.ChannelID, fmt.Sprintf("%s hasn't requested any songs yet.", user.Username))
+		return
+	}
+
+	fields := []*discordgo.MessageEmbedField{
+		&discordgo.MessageEmbedField{
+			Name: "Requested Songs",
+			Value: fmt.Sprintf("%d songs, %s listened. First %s, last %s.", stats.Plays,
+				stats.Played.Round(time.Second), formatAgo(time.Since(stats.FirstPlay)), formatAgo(time.Since(stats.LastPlay))),
+			Inline: false,
+		},
+	}
+	fields = append(fields, trackCountFields(stats.TopTracks)...)
+
+	vi.sendListEmbed(m.ChannelID, "Statistics of "+user.Username+":", fields)
+}
+
+//sendListEmbed sends an embeded message with the given title and fields.
+func (vi *VoiceInstance) sendListEmbed(channelID, title string, fields []*discordgo.MessageEmbedField) {
+	embed := &discordgo.MessageEmbed{
+		Title:     title,
+		Author:    &discordgo.MessageEmbedAuthor{},
+		Color:     0xff5733,
+		Fields:    fields,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	_, err := vi.sendEmbeddedMessageToChannel(channelID, embed)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+//trackCountFields is a helper function to create MessageEmbedField
+//array of the most played tracks.
+func trackCountFields(tracks []history.TrackCount) []*discordgo.MessageEmbedField {
+	fields := []*discordgo.MessageEmbedField{}
+	for i, track := range tracks {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name: fmt.Sprintf("%d) %d plays, %s", i+1, track.Plays, track.Played.Round(time.Second)),
+			Value: formatTrackTitle(playlist.Entry{
+				Source: playlist.Source(track.Source),
+				ID:     track.SourceID,
+				Title:  track.Title,
+				Artist: track.Artist,
+			}),
+			Inline: false,
+		})
+	}
+	return fields
+}
+
+//formatHistoryRecord is a helper function to create the text
+//of a played song with how long it is played and its requester.
+func formatHistoryRecord(record history.Record) string {
+	text := formatTrackTitle(playlist.Entry{
+		Source: playlist.Source(record.Source),
+		ID:     record.SourceID,
+		Title:  record.Title,
+		Artist: record.Artist,
+	}) + " played " + record.Played.Round(time.Second).String()
+
+	if record.RequesterName != "" {
+		text += ", requested by " + record.RequesterName
+	}
+	return text
+}
+
+//formatTrackTitle is a helper function to create the title of
+//the given track, linked to it if it has a link.
+func formatTrackTitle(entry playlist.Entry) string {
+	switch entry.Source {
+	case playlist.SourceYoutube, playlist.SourceSpotify, playlist.SourceURL:
+		return "[" + entry.DisplayTitle() + "](" + entry.Location() + ")"
+	}
+	return entry.DisplayTitle()
+}
+
+//formatAgo formats durations like "5m ago", "3h ago" and "2d ago".
+func formatAgo(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return strconv.Itoa(int(d.Minutes())) + "m ago"
+	case d < 24*time.Hour:
+		return strconv.Itoa(int(d.Hours())) + "h ago"
+	}
+	return strconv.Itoa(int(d.Hours()/24)) + "d ago"
+}
+
+//requesterName returns the server nickname of the author of
+//the message, or its username if it doesn't have one.
+func requesterName(m *discordgo.MessageCreate) string {
+	if m.Member != nil && m.Member.Nick != "" {
+		return m.Member.Nick
+	}
+	return m.Author.Username
+}
@@ -0,0 +1,24 @@
+package bot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatAgo(t *testing.T) {
+	tables := []struct {
+		duration time.Duration
+		text     string
+	}{
+		{30 * time.Second, "just now"},
+		{5*time.Minute + 30*time.Second, "5m ago"},
+		{3*time.Hour + 59*time.Minute, "3h ago"},
+		{50 * time.Hour, "2d ago"},
+	}
+
+	for _, table := range tables {
+		if text := formatAgo(table.duration); text != table.text {
+			t.Errorf("%s: got %s, want %s.", table.duration, text, table.text)
+		}
+	}
+}
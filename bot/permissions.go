@@ -0,0 +1,373 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/hemreari/feanor-dcbot/config"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+//everyoneRule is the target of a permission rule that allows
+//every member. It is saved as the @everyone role, which has
+//the same ID with the guild.
+const everyoneRule = "everyone"
+
+//commands are the commands that permission rules can be set for.
+var commands = []string{
+	"clear", "dashboard", "history", "join", "leave", "move", "pl", "play", "playlist",
+	"quota", "search", "show", "skip", "stats", "stop", "top", "volume",
+}
+
+//defaultRules are used for the commands that don't have
+//a rule in the guild. Other commands can be used by everyone.
+var defaultRules = map[string]config.PermissionRule{
+	"stop":      config.PermissionRule{Roles: []string{config.DJRoleRule}},
+	"clear":     config.PermissionRule{Roles: []string{config.DJRoleRule}},
+	"volume":    config.PermissionRule{Roles: []string{config.DJRoleRule}},
+	"dashboard": config.PermissionRule{Roles: []string{config.DJRoleRule}},
+}
+
+var (
+	roleMentionRegex = regexp.MustCompile(`^<@&(\d+)>$`)
+	userMentionRegex = regexp.MustCompile(`^<@!?(\d+)>$`)
+)
+
+//commandName returns the name of the command in the message
+//without "!", or an empty string if it isn't a command.
+func commandName(content string) string {
+	if !strings.HasPrefix(content, "!") {
+		return ""
+	}
+
+	fields := strings.Fields(strings.TrimPrefix(content, "!"))
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToLower(fields[0])
+}
+
+//commandArgs returns the arguments after the command name in the message.
+func commandArgs(content string) string {
+	if commandName(content) == "" {
+		return ""
+	}
+
+	args := strings.TrimSpace(strings.TrimPrefix(content, "!"))
+	i := strings.IndexFunc(args, unicode.IsSpace)
+	if i < 0 {
+		return ""
+	}
+	return strings.TrimSpace(args[i:])
+}
+
+//commandRule returns the rule of the command in the guild,
+//or its default rule if the guild doesn't have one.
+func commandRule(guildID, command string) (config.PermissionRule, bool) {
+	rule, ok := cfg.CommandRule(guildID, command)
+	if ok {
+		return rule, true
+	}
+
+	rule, ok = defaultRules[command]
+	return rule, ok
+}
+
+//checkPermission checks the author of the message can use the given
+//command and sends a denial message if not. Admins can use all commands.
+func (vi *VoiceInstance) checkPermission(s *discordgo.Session, m *discordgo.MessageCreate, command string) bool {
+	denial := permissionDenial(s, m, command)
+	if denial == "" {
+		return true
+	}
+
+	log.Printf("%s-%s is denied to use !%s\n", m.Author.Username, m.Author.ID, command)
+	vi.sendMessageToChannel(m.ChannelID, denial)
+	return false
+}
+
+//permissionDenial returns the message that tells the author of the message
+//can't use the given command, or an empty string if the author can use it.
+func permissionDenial(s *discordgo.Session, m *discordgo.MessageCreate, command string) string {
+	if m.GuildID == "" {
+		return ""
+	}
+
+	rule, ok := commandRule(m.GuildID, command)
+	if !ok {
+		return ""
+	}
+
+	roles := []string{m.GuildID}
+	if m.Member != nil {
+		roles = append(roles, m.Member.Roles...)
+	} else if member, err := s.State.Member(m.GuildID, m.Author.ID); err == nil {
+		roles = append(roles, member.Roles...)
+	}
+
+	dj := false
+	for _, role := range rule.Roles {
+		if role == config.DJRoleRule {
+			dj = hasDJRole(s, m.GuildID, m.Author.ID)
+		}
+	}
+
+	if ruleAllows(rule, m.Author.ID, roles, dj) || isAdmin(s, m) {
+		return ""
+	}
+
+	return fmt.Sprintf("You don't have permission to use !%s. It can be used by %s.",
+		command, describeRule(s, m.GuildID, rule))
+}
+
+//ruleAllows checks the rule allows the user with the given roles.
+//dj tells the user has the DJ role.
+func ruleAllows(rule config.PermissionRule, userID string, roles []string, dj bool) bool {
+	for _, id := range rule.Users {
+		if id == userID {
+			return true
+		}
+	}
+
+	for _, ruleRole := range rule.Roles {
+		if ruleRole == config.DJRoleRule {
+			if dj {
+				return true
+			}
+			continue
+		}
+
+		for _, role := range roles {
+			if role == ruleRole {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//describeRule returns who can use a command with the given rule
+//like "DJ role, Moderator role, hemreari and server admins".
+func describeRule(s *discordgo.Session, guildID string, rule config.PermissionRule) string {
+	names := []string{}
+	for _, roleID := range rule.Roles {
+		switch {
+		case roleID == config.DJRoleRule:
+			names = append(names, "DJ role")
+		case roleID == guildID:
+			names = append(names, "everyone")
+		default:
+			name := roleID
+			if role, err := s.State.Role(guildID, roleID); err == nil {
+				name = role.Name
+			}
+			names = append(names, name+" role")
+		}
+	}
+
+	for _, userID := range rule.Users {
+		name := userID
+		if member, err := s.State.Member(guildID, userID); err == nil && member.User != nil {
+			name = member.User.Username
+		}
+		names = append(names, name)
+	}
+
+	names = append(names, "server admins")
+	if len(names) == 1 {
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+//handlePermissionCommand handles !perm command. Rules can only be
+//changed by admins.
+//	!perm [list]
+//	!perm set <command> <@role|@user|role name|dj|everyone>...
+//	!perm reset <command>
+func (vi *VoiceInstance) handlePermissionCommand(args string, s *discordgo.Session, m *discordgo.MessageCreate) {
+	if m.GuildID == "" {
+		vi.sendMessageToChannel(m.ChannelID, "Permissions can only be set in a server.")
+		return
+	}
+
+	if !isAdmin(s, m) {
+		vi.sendMessageToChannel(m.ChannelID, "Only server admins can do that command.")
+		return
+	}
+
+	fields := strings.Fields(args)
+	if len(fields) == 0 || fields[0] == "list" {
+		vi.listPermissions(s, m)
+		return
+	}
+
+	usage := "Usage: !perm [list] | !perm set <command> <@role|@user|role name|dj|everyone>... | !perm reset <command>"
+	if len(fields) < 2 {
+		vi.sendMessageToChannel(m.ChannelID, usage)
+		return
+	}
+
+	command := strings.ToLower(strings.TrimPrefix(fields[1], "!"))
+	if !isCommand(command) {
+		vi.sendMessageToChannel(m.ChannelID, fmt.Sprintf("Unknown command: %s. Commands: %s", command, strings.Join(commands, ", ")))
+		return
+	}
+
+	switch fields[0] {
+	case "set":
+		if len(fields) < 3 {
+			vi.sendMessageToChannel(m.ChannelID, usage)
+			return
+		}
+
+		rule, err := parseRuleTargets(s, m.GuildID, fields[2:])
+		if err != nil {
+			vi.sendMessageToChannel(m.ChannelID, err.Error())
+			return
+		}
+
+		err = cfg.SetCommandRule(m.GuildID, command, rule)
+		if err != nil {
+			log.Println(err)
+			vi.sendErrorMessageToChannel(m.ChannelID)
+			return
+		}
+		vi.sendMessageToChannel(m.ChannelID, fmt.Sprintf("!%s can be used by %s.", command, describeRule(s, m.GuildID, rule)))
+	case "reset":
+		_, err := cfg.RemoveCommandRule(m.GuildID, command)
+		if err != nil {
+			log.Println(err)
+			vi.sendErrorMessageToChannel(m.ChannelID)
+			return
+		}
+
+		if rule, ok := commandRule(m.GuildID, command); ok {
+			vi.sendMessageToChannel(m.ChannelID, fmt.Sprintf("!%s is reset, it can be used by %s.", command, describeRule(s, m.GuildID, rule)))
+			return
+		}
+		vi.sendMessageToChannel(m.ChannelID, fmt.Sprintf("!%s is reset, it can be used by everyone.", command))
+	default:
+		vi.sendMessageToChannel(m.ChannelID, usage)
+	}
+}
+
+//listPermissions sends the rules of the guild, including default rules.
+func (vi *VoiceInstance) listPermissions(s *discordgo.Session, m *discordgo.MessageCreate) {
+	ruleCommands := cfg.RuleCommands(m.GuildID)
+	for command := range defaultRules {
+		if _, ok := cfg.CommandRule(m.GuildID, command); !ok {
+			ruleCommands = append(ruleCommands, command)
+		}
+	}
+	sort.Strings(ruleCommands)
+
+	var builder strings.Builder
+	builder.WriteString("Command permissions, other commands can be used by everyone:")
+	for _, command := range ruleCommands {
+		rule, _ := commandRule(m.GuildID, command)
+		fmt.Fprintf(&builder, "\n!%s: %s", command, describeRule(s, m.GuildID, rule))
+	}
+	vi.sendMessageToChannel(m.ChannelID, builder.String())
+}
+
+//parseRuleTargets creates a rule from role and user mentions, role
+//names, "dj" for the DJ role and "everyone" for all members.
+func parseRuleTargets(s *discordgo.Session, guildID string, targets []string) (config.PermissionRule, error) {
+	rule := config.PermissionRule{}
+	for _, target := range targets {
+		if match := roleMentionRegex.FindStringSubmatch(target); match != nil {
+			rule.Roles = append(rule.Roles, match[1])
+			continue
+		}
+		if match := userMentionRegex.FindStringSubmatch(target); match != nil {
+			rule.Users = append(rule.Users, match[1])
+			continue
+		}
+
+		switch strings.ToLower(target) {
+		case config.DJRoleRule:
+			rule.Roles = append(rule.Roles, config.DJRoleRule)
+			continue
+		case everyoneRule, "@everyone":
+			rule.Roles = append(rule.Roles, guildID)
+			continue
+		}
+
+		roleID, ok := findRoleByName(s, guildID, target)
+		if !ok {
+			return rule, fmt.Errorf("Unknown role or user: %s. Please, Try again.", target)
+		}
+		rule.Roles = append(rule.Roles, roleID)
+	}
+	return rule, nil
+}
+
+//isDJ checks the author of the message has the configured DJ role
+//or is an admin. DJs can skip songs without voting.
+func isDJ(s *discordgo.Session, m *discordgo.MessageCreate) bool {
+	if hasDJRole(s, m.GuildID, m.Author.ID) {
+		return true
+	}
+	return isAdmin(s, m)
+}
+
+//hasDJRole checks the given member has the configured DJ role.
+//DJ role can be given with its name or ID.
+func hasDJRole(s *discordgo.Session, guildID, userID string) bool {
+	djRole := cfg.Player.DJRole
+	if djRole == "" || guildID == "" {
+		return false
+	}
+
+	member, err := s.State.Member(guildID, userID)
+	if err != nil {
+		member, err = s.GuildMember(guildID, userID)
+		if err != nil {
+			log.Printf("Error while getting member %s: %v\n", userID, err)
+			return false
+		}
+	}
+
+	for _, roleID := range member.Roles {
+		if roleID == djRole {
+			return true
+		}
+
+		role, err := s.State.Role(guildID, roleID)
+		if err == nil && strings.EqualFold(role.Name, djRole) {
+			return true
+		}
+	}
+	return false
+}
+
+//findRoleByName returns the ID of the role with the given name in the guild.
+func findRoleByName(s *discordgo.Session, guildID, name string) (string, bool) {
+	guild, err := s.State.Guild(guildID)
+	if err != nil {
+		log.Printf("Couldn't find guild: %v\n", err)
+		return "", false
+	}
+
+	for _, role := range guild.Roles {
+		if strings.EqualFold(role.Name, name) {
+			return role.ID, true
+		}
+	}
+	return "", false
+}
+
+func isCommand(command string) bool {
+	for _, c := range commands {
+		if c == command {
+			return true
+		}
+	}
+	return false
+}
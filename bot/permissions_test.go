@@ -0,0 +1,78 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/hemreari/feanor-dcbot/config"
+)
+
+func TestCommandName(t *testing.T) {
+	tables := []struct {
+		content string
+		command string
+	}{
+		{"!stop", "stop"},
+		{"!volume 50", "volume"},
+		{"!Playlist save rock", "playlist"},
+		{"!", ""},
+		{"hello !stop", ""},
+		//command name ends with a space, otherwise it is another command.
+		{"!playhttps://youtu.be/x", "playhttps://youtu.be/x"},
+		{"!searchfoo", "searchfoo"},
+	}
+
+	for _, table := range tables {
+		if command := commandName(table.content); command != table.command {
+			t.Errorf("%q: got %q, want %q.", table.content, command, table.command)
+		}
+	}
+}
+
+func TestCommandArgs(t *testing.T) {
+	tables := []struct {
+		content string
+		args    string
+	}{
+		{"!play", ""},
+		{"!play  never gonna give you up ", "never gonna give you up"},
+		{"!search\t--min 2m rock", "--min 2m rock"},
+		{"!searchfoo", ""},
+		{"hello", ""},
+	}
+
+	for _, table := range tables {
+		if args := commandArgs(table.content); args != table.args {
+			t.Errorf("%q: got %q, want %q.", table.content, args, table.args)
+		}
+	}
+}
+
+func TestRuleAllows(t *testing.T) {
+	rule := config.PermissionRule{
+		Roles: []string{config.DJRoleRule, "mod"},
+		Users: []string{"alice"},
+	}
+
+	tables := []struct {
+		userID  string
+		roles   []string
+		dj      bool
+		allowed bool
+	}{
+		{"alice", nil, false, true},
+		{"bob", []string{"guild", "mod"}, false, true},
+		{"bob", []string{"guild"}, true, true},
+		{"bob", []string{"guild", "member"}, false, false},
+	}
+
+	for _, table := range tables {
+		if allowed := ruleAllows(rule, table.userID, table.roles, table.dj); allowed != table.allowed {
+			t.Errorf("%s with roles %v and dj %t: got %t, want %t.", table.userID, table.roles, table.dj, allowed, table.allowed)
+		}
+	}
+
+	everyone := config.PermissionRule{Roles: []string{"guild"}}
+	if !ruleAllows(everyone, "bob", []string{"guild"}, false) {
+		t.Errorf("Everyone rule doesn't allow a member.")
+	}
+}
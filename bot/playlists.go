@@ -0,0 +1,814 @@
+package bot
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hemreari/feanor-dcbot/playlist"
+	"github.com/hemreari/feanor-dcbot/stream"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/hemreari/go-datastructures/queue"
+)
+
+const (
+	savedPlaylistUsage = "Usage: !playlist save|load|delete|export|share <name>, !playlist rename <name> <new name>, " +
+		"!playlist import [name] with a JSON, M3U, PLS or XSPF attachment, !playlist list. Add --guild to use the server playlists."
+	//attachments bigger than this are not imported.
+	maxAttachmentSize = 1 << 20
+	//max number of tracks that are played from an attached playlist file.
+	maxPlaylistFileTracks = 20
+	//max number of unresolved entries that are listed to the user.
+	maxListedUnresolved = 5
+)
+
+var (
+	playlistNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,32}$`)
+	attachmentClient  = &http.Client{Timeout: 15 * time.Second}
+)
+
+//handleSavedPlaylistCommand handles !playlist command that saves the play
+//queue as a playlist of the user or the guild and plays it again later.
+func (vi *VoiceInstance) handleSavedPlaylistCommand(args string, s *discordgo.Session, m *discordgo.MessageCreate) {
+	fields := []string{}
+	guildScope := false
+	for _, field := range strings.Fields(args) {
+		if field == "--guild" {
+			guildScope = true
+			continue
+		}
+		fields = append(fields, field)
+	}
+
+	if guildScope && m.GuildID == "" {
+		vi.sendMessageToChannel(m.ChannelID, "Server playlists can only be used in a server.")
+		return
+	}
+
+	if len(fields) == 0 {
+		vi.sendMessageToChannel(m.ChannelID, savedPlaylistUsage)
+		return
+	}
+
+	command, fields := fields[0], fields[1:]
+	switch {
+	case command == "list" && len(fields) == 0:
+		vi.listSavedPlaylists(m)
+	case command == "import" && len(fields) <= 1:
+		vi.importPlaylist(strings.Join(fields, ""), guildScope, s, m)
+	case command == "rename" && len(fields) == 2:
+		vi.renamePlaylist(fields[0], fields[1], guildScope, s, m)
+	case command == "export" && len(fields) == 2:
+		vi.exportPlaylist(fields[0], fields[1], guildScope, m)
+	case len(fields) != 1:
+		vi.sendMessageToChannel(m.ChannelID, savedPlaylistUsage)
+	case command == "save":
+		vi.savePlaylist(fields[0], guildScope, s, m)
+	case command == "load":
+		vi.loadPlaylist(fields[0], guildScope, s, m)
+	case command == "delete":
+		vi.deletePlaylist(fields[0], guildScope, s, m)
+	case command == "export":
+		vi.exportPlaylist(fields[0], "json", guildScope, m)
+	case command == "share":
+		vi.sharePlaylist(fields[0], s, m)
+	default:
+		vi.sendMessageToChannel(m.ChannelID, savedPlaylistUsage)
+	}
+}
+
+//savePlaylist saves the now playing song and the songs in the queues.
+func (vi *VoiceInstance) savePlaylist(name string, guildScope bool, s *discordgo.Session, m *discordgo.MessageCreate) {
+	if !playlistNameRegex.MatchString(name) {
+		vi.sendMessageToChannel(m.ChannelID, "Playlist names can only have letters, numbers, - and _, up to 32 characters.")
+		return
+	}
+
+	songs := vi.queuedSongs()
+	entries := []playlist.Entry{}
+	for _, song := range songs {
+		if entry, ok := songEntry(song); ok {
+			entries = append(entries, entry)
+		}
+	}
+
+	if len(entries) == 0 {
+		vi.sendMessageToChannel(m.ChannelID, "There is nothing to save in the play queue.")
+		return
+	}
+
+	owner := playlist.UserOwner(m.Author.ID)
+	if guildScope {
+		owner = playlist.GuildOwner(m.GuildID)
+		if !vi.canReplacePlaylist(owner, name, s, m) {
+			return
+		}
+	}
+
+	err := playlists.Save(&playlist.Playlist{
+		Name:      name,
+		Owner:     owner,
+		CreatedBy: m.Author.ID,
+		Entries:   entries,
+	})
+	if err != nil {
+		log.Println(err)
+		vi.sendMessageToChannel(m.ChannelID, "Playlist couldn't be saved.")
+		return
+	}
+
+	text := fmt.Sprintf("Playlist \"%s\" is saved with %d tracks.", name, len(entries))
+	if skipped := len(songs) - len(entries); skipped > 0 {
+		text += fmt.Sprintf(" %d tracks couldn't be saved.", skipped)
+	}
+	vi.sendMessageToChannel(m.ChannelID, text)
+}
+
+//loadPlaylist adds the songs of the given playlist to the play queue.
+func (vi *VoiceInstance) loadPlaylist(name string, guildScope bool, s *discordgo.Session, m *discordgo.MessageCreate) {
+	p, err := vi.findPlaylist(name, guildScope, m)
+	if err != nil {
+		return
+	}
+
+	songs := []*SongInstance{}
+	unresolved := 0
+	for _, entry := range p.Entries {
+		song, err := entrySong(entry)
+		if err != nil {
+			log.Printf("Error while loading entry of playlist %s: %v\n", p.Name, err)
+			unresolved++
+			continue
+		}
+		songs = append(songs, song)
+	}
+
+	if len(songs) == 0 {
+		vi.sendMessageToChannel(m.ChannelID, fmt.Sprintf("Playlist \"%s\" has no playable tracks.", p.Name))
+		return
+	}
+
+	text := fmt.Sprintf("Loading %d tracks of \"%s\".", len(songs), p.Name)
+	if unresolved > 0 {
+		text += fmt.Sprintf(" %d tracks couldn't be found.", unresolved)
+	}
+	vi.sendMessageToChannel(m.ChannelID, text)
+
+	vi.enqueueSongs(songs, s, m, nil)
+}
+
+//prepPlaylistFile plays the tracks of the M3U, PLS or XSPF playlist that
+//is attached to the message. Entries that can't be played are listed.
+func (vi *VoiceInstance) prepPlaylistFile(attachment *discordgo.MessageAttachment, s *discordgo.Session, m *discordgo.MessageCreate) {
+	data, err := downloadAttachment(attachment)
+	if err != nil {
+		log.Println(err)
+		vi.sendMessageToChannel(m.ChannelID, "Couldn't download the attached playlist.")
+		return
+	}
+
+	entries, unresolved, err := playlist.DecodeFile(attachment.Filename, data)
+	if err != nil {
+		log.Println(err)
+		vi.sendMessageToChannel(m.ChannelID, "Attached playlist couldn't be read.")
+		return
+	}
+
+	songs, failed, leftOut := resolveFileEntries(entries)
+	unresolved = append(unresolved, failed...)
+
+	found := len(songs) + leftOut
+	if found == 0 {
+		vi.sendMessageToChannel(m.ChannelID, playlistFileSummary(attachment.Filename, found, 0, unresolved))
+		return
+	}
+
+	//summary is sent after the songs are downloaded or matched,
+	//so that the entries failed to be prepared are listed too.
+	report := &playlistReport{
+		done: func(queued int, failed []string) {
+			summary := playlistFileSummary(attachment.Filename, found, queued, append(unresolved, failed...))
+			vi.sendMessageToChannel(m.ChannelID, summary)
+		},
+	}
+	vi.enqueueSongs(songs, s, m, report)
+}
+
+//playlistReport collects the results of preparing the songs of a playlist
+//file and calls done after the last one. Songs that are removed from the
+//queue before they are prepared are never reported.
+type playlistReport struct {
+	mu      sync.Mutex
+	pending int
+	queued  int
+	failed  []string //playlist file entries of the failed songs
+	done    func(queued int, failed []string)
+}
+
+//start waits for the given songs to be prepared.
+func (r *playlistReport) start(songs []*SongInstance) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	r.pending = len(songs)
+	r.mu.Unlock()
+	for _, song := range songs {
+		song.report = r
+	}
+
+	if len(songs) == 0 {
+		r.done(0, nil)
+	}
+}
+
+//finish records the result of preparing the song.
+func (r *playlistReport) finish(song *SongInstance, err error) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	if err != nil {
+		r.failed = append(r.failed, song.location)
+	} else {
+		r.queued++
+	}
+	r.pending--
+	last := r.pending == 0
+	queued, failed := r.queued, r.failed
+	r.mu.Unlock()
+
+	if last {
+		r.done(queued, failed)
+	}
+}
+
+//resolveFileEntries creates the songs of the playlist file entries until
+//maxPlaylistFileTracks songs are found. URLs are probed one by one, so the
+//rest of a long playlist is not requested at all. Locations of the entries
+//that can't be resolved and the number of entries left out are returned.
+func resolveFileEntries(entries []playlist.Entry) ([]*SongInstance, []string, int) {
+	songs := []*SongInstance{}
+	unresolved := []string{}
+	for i, entry := range entries {
+		if len(songs) == maxPlaylistFileTracks {
+			return songs, unresolved, len(entries) - i
+		}
+
+		song, err := fileEntrySong(entry)
+		if err != nil {
+			log.Printf("Error while loading playlist file entry: %v\n", err)
+			unresolved = append(unresolved, entry.Location())
+			continue
+		}
+		song.location = entry.Location()
+		songs = append(songs, song)
+	}
+	return songs, unresolved, 0
+}
+
+//fileEntrySong creates the song of a playlist file entry. Local files that
+//are not in the music library are searched by their titles. URLs are probed,
+//durations in playlist files don't tell whether it is a radio stream.
+func fileEntrySong(entry playlist.Entry) (*SongInstance, error) {
+	if entry.Source == playlist.SourceURL {
+		return urlEntrySong(entry)
+	}
+
+	song, err := entrySong(entry)
+	if err == nil || entry.Source != playlist.SourceLocal || entry.Title == "" {
+		return song, err
+	}
+
+	entry.Source = playlist.SourceQuery
+	entry.ID = entry.DisplayTitle()
+	return entrySong(entry)
+}
+
+//urlEntrySong creates the song of an audio file or radio stream URL entry.
+func urlEntrySong(entry playlist.Entry) (*SongInstance, error) {
+	info, err := stream.Probe(entry.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	song, err := entrySong(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	song.isLive = info.Live
+	if song.title == "" {
+		song.title = info.Title
+	}
+	if song.duration == "" || info.Live {
+		song.duration = info.Duration
+	}
+	return song, nil
+}
+
+//playlistFileSummary returns the message that tells how many tracks
+//of a playlist file are queued and which entries couldn't be resolved.
+func playlistFileSummary(fileName string, found, queued int, unresolved []string) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "Queued %d tracks from %s.", queued, fileName)
+	if found > maxPlaylistFileTracks {
+		fmt.Fprintf(&builder, " Only the first %d tracks are played, %d are left out.", maxPlaylistFileTracks, found-maxPlaylistFileTracks)
+	}
+
+	if len(unresolved) > 0 {
+		fmt.Fprintf(&builder, "\n%d entries couldn't be resolved:", len(unresolved))
+		for i, location := range unresolved {
+			if i == maxListedUnresolved {
+				fmt.Fprintf(&builder, "\n...and %d more", len(unresolved)-maxListedUnresolved)
+				break
+			}
+			if location == "" {
+				location = "(empty entry)"
+			}
+			fmt.Fprintf(&builder, "\n%s", location)
+		}
+	}
+	return builder.String()
+}
+
+//enqueueSongs adds the given songs to the end of the play queue and
+//starts the play process if bot is not playing. Results of the songs
+//are collected by the given report, it could be nil.
+func (vi *VoiceInstance) enqueueSongs(songs []*SongInstance, s *discordgo.Session, m *discordgo.MessageCreate, report *playlistReport) {
+	if !vi.validateMessageAndJoinVoiceChannel(s, m) {
+		return
+	}
+
+	limit := vi.newQueueLimit(m.Author.ID)
+	allowed := []*SongInstance{}
+	for _, song := range songs {
+		song.requesterID = m.Author.ID
+		song.requesterName = requesterName(m)
+		if vi.checkPolicy(m.GuildID, m.ChannelID, song, nil) && limit.allow(song) {
+			allowed = append(allowed, song)
+		}
+	}
+	songs = allowed
+
+	vi.sendLimitMessage(m.ChannelID, limit)
+	report.start(songs)
+	if len(songs) == 0 {
+		return
+	}
+
+	//on going play process ends when the play queue is empty,
+	//so songs are put into the play queue one by one.
+	if vi.isPlaying == true {
+		vi.prepareSongs(songs, m.ChannelID)
+		return
+	}
+
+	for _, song := range songs {
+		//first song is not putting in to the download queue.
+		//it's going to be downloaded directly.
+		if vi.playQueue.Empty() {
+			err := vi.prepareSong(song, m.ChannelID)
+			if err != nil {
+				log.Println(err)
+			}
+			continue
+		}
+
+		vi.queueSong(vi.downloadQueue, song)
+	}
+
+	if vi.playQueue.Empty() {
+		return
+	}
+	vi.playQueueFunc(m.ChannelID)
+}
+
+func (vi *VoiceInstance) listSavedPlaylists(m *discordgo.MessageCreate) {
+	var builder strings.Builder
+	writeList := func(title string, list []*playlist.Playlist) {
+		if len(list) == 0 {
+			return
+		}
+
+		names := []string{}
+		for _, p := range list {
+			names = append(names, fmt.Sprintf("%s (%d)", p.Name, len(p.Entries)))
+		}
+		fmt.Fprintf(&builder, "%s: %s\n", title, strings.Join(names, ", "))
+	}
+
+	writeList("Your playlists", playlists.List(playlist.UserOwner(m.Author.ID)))
+	if m.GuildID != "" {
+		writeList("Server playlists", playlists.List(playlist.GuildOwner(m.GuildID)))
+	}
+
+	if builder.Len() == 0 {
+		vi.sendMessageToChannel(m.ChannelID, "There are no saved playlists. Save the play queue with !playlist save <name>.")
+		return
+	}
+	vi.sendMessageToChannel(m.ChannelID, builder.String())
+}
+
+func (vi *VoiceInstance) deletePlaylist(name string, guildScope bool, s *discordgo.Session, m *discordgo.MessageCreate) {
+	p, err := vi.findPlaylist(name, guildScope, m)
+	if err != nil || !vi.canModifyPlaylist(p, s, m) {
+		return
+	}
+
+	err = playlists.Delete(p.Owner, p.Name)
+	if err != nil {
+		log.Println(err)
+		vi.sendMessageToChannel(m.ChannelID, "Playlist couldn't be deleted.")
+		return
+	}
+	vi.sendMessageToChannel(m.ChannelID, fmt.Sprintf("Playlist \"%s\" is deleted.", p.Name))
+}
+
+func (vi *VoiceInstance) renamePlaylist(name, newName string, guildScope bool, s *discordgo.Session, m *discordgo.MessageCreate) {
+	if !playlistNameRegex.MatchString(newName) {
+		vi.sendMessageToChannel(m.ChannelID, "Playlist names can only have letters, numbers, - and _, up to 32 characters.")
+		return
+	}
+
+	p, err := vi.findPlaylist(name, guildScope, m)
+	if err != nil || !vi.canModifyPlaylist(p, s, m) {
+		return
+	}
+
+	err = playlists.Rename(p.Owner, p.Name, newName)
+	if err == playlist.ErrExists {
+		vi.sendMessageToChannel(m.ChannelID, fmt.Sprintf("There is already a playlist named \"%s\".", newName))
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		vi.sendMessageToChannel(m.ChannelID, "Playlist couldn't be renamed.")
+		return
+	}
+	vi.sendMessageToChannel(m.ChannelID, fmt.Sprintf("Playlist \"%s\" is renamed to \"%s\".", p.Name, newName))
+}
+
+//sharePlaylist copies a playlist of the user to the server playlists,
+//so everyone in the server can load it.
+func (vi *VoiceInstance) sharePlaylist(name string, s *discordgo.Session, m *discordgo.MessageCreate) {
+	if m.GuildID == "" {
+		vi.sendMessageToChannel(m.ChannelID, "Playlists can only be shared in a server.")
+		return
+	}
+
+	p, err := playlists.Get(playlist.UserOwner(m.Author.ID), name)
+	if err != nil {
+		vi.sendMessageToChannel(m.ChannelID, fmt.Sprintf("You don't have a playlist named \"%s\".", name))
+		return
+	}
+
+	owner := playlist.GuildOwner(m.GuildID)
+	if !vi.canReplacePlaylist(owner, p.Name, s, m) {
+		return
+	}
+
+	err = playlists.Save(&playlist.Playlist{
+		Name:      p.Name,
+		Owner:     owner,
+		CreatedBy: m.Author.ID,
+		Entries:   p.Entries,
+	})
+	if err != nil {
+		log.Println(err)
+		vi.sendMessageToChannel(m.ChannelID, "Playlist couldn't be shared.")
+		return
+	}
+	vi.sendMessageToChannel(m.ChannelID, fmt.Sprintf("Playlist \"%s\" is shared with the server. Load it with !playlist load %s --guild", p.Name, p.Name))
+}
+
+//exportPlaylist sends the given playlist as a JSON or M3U file.
+func (vi *VoiceInstance) exportPlaylist(name, format string, guildScope bool, m *discordgo.MessageCreate) {
+	p, err := vi.findPlaylist(name, guildScope, m)
+	if err != nil {
+		return
+	}
+
+	var data []byte
+	switch strings.ToLower(format) {
+	case "json":
+		data, err = playlist.EncodeJSON(p)
+		if err != nil {
+			log.Println(err)
+			vi.sendErrorMessageToChannel(m.ChannelID)
+			return
+		}
+	case "m3u", "m3u8":
+		data = playlist.EncodeM3U(p)
+	default:
+		vi.sendMessageToChannel(m.ChannelID, "Playlists can be exported as json or m3u.")
+		return
+	}
+
+	fileName := p.Name + "." + strings.ToLower(format)
+	_, err = vi.session.ChannelFileSendWithMessage(m.ChannelID, fmt.Sprintf("Playlist \"%s\":", p.Name), fileName, bytes.NewReader(data))
+	if err != nil {
+		log.Printf("Error while sending playlist file: %v", err)
+		vi.sendErrorMessageToChannel(m.ChannelID)
+	}
+}
+
+//importPlaylist saves the playlist in the attachment of the message.
+func (vi *VoiceInstance) importPlaylist(name string, guildScope bool, s *discordgo.Session, m *discordgo.MessageCreate) {
+	if len(m.Attachments) == 0 {
+		vi.sendMessageToChannel(m.ChannelID, "Attach a JSON, M3U, PLS or XSPF playlist file to import it.")
+		return
+	}
+
+	attachment := m.Attachments[0]
+	data, err := downloadAttachment(attachment)
+	if err != nil {
+		log.Println(err)
+		vi.sendMessageToChannel(m.ChannelID, "Couldn't download the attached playlist.")
+		return
+	}
+
+	extension := strings.ToLower(filepath.Ext(attachment.Filename))
+	entries := []playlist.Entry{}
+	skipped := 0
+	switch extension {
+	case ".json":
+		var importedName string
+		importedName, entries, err = playlist.DecodeJSON(data)
+		if err != nil {
+			log.Println(err)
+			vi.sendMessageToChannel(m.ChannelID, "Attached file is not a playlist that is exported by the bot.")
+			return
+		}
+		if name == "" {
+			name = importedName
+		}
+	default:
+		var skippedLocations []string
+		entries, skippedLocations, err = playlist.DecodeFile(attachment.Filename, data)
+		if err == playlist.ErrUnsupportedFormat {
+			vi.sendMessageToChannel(m.ChannelID, "Only JSON, M3U, PLS and XSPF playlists can be imported.")
+			return
+		}
+		if err != nil {
+			log.Println(err)
+			vi.sendMessageToChannel(m.ChannelID, "Attached playlist couldn't be read.")
+			return
+		}
+		skipped = len(skippedLocations)
+	}
+
+	if name == "" {
+		name = strings.TrimSuffix(attachment.Filename, filepath.Ext(attachment.Filename))
+	}
+	if !playlistNameRegex.MatchString(name) {
+		vi.sendMessageToChannel(m.ChannelID, "Give the playlist a name with letters, numbers, - and _ like !playlist import my-list")
+		return
+	}
+
+	if len(entries) == 0 {
+		vi.sendMessageToChannel(m.ChannelID, "Attached playlist has no tracks that can be imported.")
+		return
+	}
+
+	owner := playlist.UserOwner(m.Author.ID)
+	if guildScope {
+		owner = playlist.GuildOwner(m.GuildID)
+		if !vi.canReplacePlaylist(owner, name, s, m) {
+			return
+		}
+	}
+
+	err = playlists.Save(&playlist.Playlist{
+		Name:      name,
+		Owner:     owner,
+		CreatedBy: m.Author.ID,
+		Entries:   entries,
+	})
+	if err != nil {
+		log.Println(err)
+		vi.sendMessageToChannel(m.ChannelID, "Playlist couldn't be saved.")
+		return
+	}
+
+	text := fmt.Sprintf("Playlist \"%s\" is imported with %d tracks.", name, len(entries))
+	if skipped > 0 {
+		text += fmt.Sprintf(" %d entries couldn't be imported.", skipped)
+	}
+	vi.sendMessageToChannel(m.ChannelID, text)
+}
+
+//findPlaylist returns the playlist of the author with the given name, or
+//the server playlist if author doesn't have one. Only server playlists
+//are searched if guildScope is true. User is told if there is no such
+//playlist.
+func (vi *VoiceInstance) findPlaylist(name string, guildScope bool, m *discordgo.MessageCreate) (*playlist.Playlist, error) {
+	owners := []string{}
+	if !guildScope {
+		owners = append(owners, playlist.UserOwner(m.Author.ID))
+	}
+	if m.GuildID != "" {
+		owners = append(owners, playlist.GuildOwner(m.GuildID))
+	}
+
+	for _, owner := range owners {
+		p, err := playlists.Get(owner, name)
+		if err == nil {
+			return p, nil
+		}
+	}
+
+	vi.sendMessageToChannel(m.ChannelID, fmt.Sprintf("There is no playlist named \"%s\". Type !playlist list to see the playlists.", name))
+	return nil, playlist.ErrNotFound
+}
+
+//canModifyPlaylist checks the author can change the given playlist. Users can
+//change their own playlists, server playlists can be changed by the user
+//that saved them and by admins.
+func (vi *VoiceInstance) canModifyPlaylist(p *playlist.Playlist, s *discordgo.Session, m *discordgo.MessageCreate) bool {
+	if p.Owner == playlist.UserOwner(m.Author.ID) || p.CreatedBy == m.Author.ID || isAdmin(s, m) {
+		return true
+	}
+
+	vi.sendMessageToChannel(m.ChannelID, "Only the user that saved this playlist or server admins can change it.")
+	return false
+}
+
+//canReplacePlaylist checks the author can save a playlist with the given
+//name, when the owner already has one.
+func (vi *VoiceInstance) canReplacePlaylist(owner, name string, s *discordgo.Session, m *discordgo.MessageCreate) bool {
+	p, err := playlists.Get(owner, name)
+	if err != nil {
+		return true
+	}
+	return vi.canModifyPlaylist(p, s, m)
+}
+
+//queuedSongs returns the now playing song and the songs
+//that are waiting in the play and download queues.
+func (vi *VoiceInstance) queuedSongs() []*SongInstance {
+	songs := []*SongInstance{}
+	if vi.nowPlaying != nil {
+		songs = append(songs, vi.nowPlaying)
+	}
+
+	for _, q := range []*queue.Queue{vi.playQueue, vi.downloadQueue} {
+		items, err := q.PeekAll()
+		if err != nil {
+			log.Printf("Error while getting queued songs: %v", err)
+			continue
+		}
+
+		for _, item := range items {
+			if song := getSongInstanceFromInterface(item); song != nil {
+				songs = append(songs, song)
+			}
+		}
+	}
+	return songs
+}
+
+//songEntry creates a playlist entry of the given song. Songs are saved
+//with where they are played from, Spotify tracks are saved with their
+//track IDs to be matched again. Returns false if song can't be saved.
+func songEntry(song *SongInstance) (playlist.Entry, bool) {
+	entry := playlist.Entry{
+		Title:    song.title,
+		Artist:   strings.TrimSpace(song.artist),
+		Duration: song.duration,
+		CoverUrl: song.coverUrl,
+	}
+
+	switch {
+	case song.isLocal:
+		entry.Source = playlist.SourceLocal
+		entry.ID = song.songPath
+		//paths are saved relative to the library, so it can be moved.
+		if rel, err := filepath.Rel(cfg.MusicDir.LibraryPath, song.songPath); err == nil {
+			entry.ID = rel
+		}
+	case song.streamUrl != "":
+		entry.Source = playlist.SourceURL
+		entry.ID = song.streamUrl
+	case song.spotifyID != "":
+		entry.Source = playlist.SourceSpotify
+		entry.ID = song.spotifyID
+		if song.expectedDuration > 0 {
+			entry.Duration = song.expectedDuration.String()
+		}
+	case song.videoID != "":
+		entry.Source = playlist.SourceYoutube
+		entry.ID = song.videoID
+	default:
+		return entry, false
+	}
+	return entry, true
+}
+
+//entrySong creates the song of the given playlist entry.
+func entrySong(entry playlist.Entry) (*SongInstance, error) {
+	song := &SongInstance{
+		title:    entry.Title,
+		artist:   entry.Artist,
+		duration: entry.Duration,
+		coverUrl: entry.CoverUrl,
+	}
+	if song.coverUrl == "" {
+		song.coverUrl = DefaultCoverUrl
+	}
+
+	switch entry.Source {
+	case playlist.SourceYoutube:
+		song.videoID = entry.ID
+	case playlist.SourceSpotify:
+		song.spotifyID = entry.ID
+		song.duration = ""
+		song.expectedDuration, _ = time.ParseDuration(entry.Duration)
+	case playlist.SourceQuery:
+		song.expectedDuration, _ = time.ParseDuration(entry.Duration)
+		song.duration = ""
+	case playlist.SourceURL:
+		song.streamUrl = entry.ID
+		//only radio streams have no duration.
+		song.isLive = entry.Duration == ""
+	case playlist.SourceLocal:
+		path, err := libraryPath(entry.ID)
+		if err != nil {
+			return nil, err
+		}
+		song.songPath = path
+		song.coverPath = DefaultCoverPath
+		song.isLocal = true
+	default:
+		return nil, fmt.Errorf("Unknown playlist entry source: %s", entry.Source)
+	}
+	return song, nil
+}
+
+//libraryPath returns the path of the given file in the music library.
+//Files outside of the library are not played.
+func libraryPath(path string) (string, error) {
+	library := cfg.MusicDir.LibraryPath
+	if library == "" {
+		return "", fmt.Errorf("Music library is not configured, %s can't be played", path)
+	}
+
+	library, err := filepath.Abs(library)
+	if err != nil {
+		return "", fmt.Errorf("Error while getting music library path: %v", err)
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(library, path)
+	}
+	path = filepath.Clean(path)
+
+	rel, err := filepath.Rel(library, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is not in the music library", path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return "", fmt.Errorf("%s is not a file in the music library", path)
+	}
+	return path, nil
+}
+
+//downloadAttachment returns the content of the given message attachment.
+func downloadAttachment(attachment *discordgo.MessageAttachment) ([]byte, error) {
+	if attachment.Size > maxAttachmentSize {
+		return nil, fmt.Errorf("Attachment %s is too big: %d bytes", attachment.Filename, attachment.Size)
+	}
+
+	resp, err := attachmentClient.Get(attachment.URL)
+	if err != nil {
+		return nil, fmt.Errorf("Error while downloading attachment: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Error while downloading attachment: %s", resp.Status)
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxAttachmentSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("Error while reading attachment: %v", err)
+	}
+	if len(data) > maxAttachmentSize {
+		return nil, fmt.Errorf("Attachment %s is too big", attachment.Filename)
+	}
+	return data, nil
+}
@@ -0,0 +1,225 @@
+package bot
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hemreari/feanor-dcbot/config"
+	"github.com/hemreari/feanor-dcbot/playlist"
+)
+
+func TestSongEntryRoundTrip(t *testing.T) {
+	library := t.TempDir()
+	err := ioutil.WriteFile(filepath.Join(library, "song.mp3"), []byte("mp3"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg = &config.Config{MusicDir: config.MusicDirectory{LibraryPath: library}}
+
+	tables := []struct {
+		song   SongInstance
+		source playlist.Source
+		id     string
+	}{
+		{SongInstance{title: "Song", videoID: "B9v8jLBrvug", duration: "3m0s", coverUrl: "cover.jpg"}, playlist.SourceYoutube, "B9v8jLBrvug"},
+		{SongInstance{title: "Billie Jean", artist: "Michael Jackson ", spotifyID: "2az3iTNyJ1M1JJnsU2Gq6H",
+			videoID: "Zi_XLOBDo_Y", expectedDuration: 294 * time.Second}, playlist.SourceSpotify, "2az3iTNyJ1M1JJnsU2Gq6H"},
+		{SongInstance{title: "Radio", streamUrl: "http://radio.example.com/stream", isLive: true}, playlist.SourceURL, "http://radio.example.com/stream"},
+		{SongInstance{title: "Local", songPath: filepath.Join(library, "song.mp3"), isLocal: true}, playlist.SourceLocal, "song.mp3"},
+	}
+
+	for _, table := range tables {
+		entry, ok := songEntry(&table.song)
+		if !ok || entry.Source != table.source || entry.ID != table.id {
+			t.Errorf("%s: got entry %+v, want %s %s.", table.song.title, entry, table.source, table.id)
+			continue
+		}
+
+		song, err := entrySong(entry)
+		if err != nil {
+			t.Errorf("%s: got error: %v", table.song.title, err)
+			continue
+		}
+		if song.videoID != "" && song.videoID != table.song.videoID || song.spotifyID != table.song.spotifyID ||
+			song.streamUrl != table.song.streamUrl || song.isLive != table.song.isLive ||
+			song.songPath != table.song.songPath || song.expectedDuration != table.song.expectedDuration {
+			t.Errorf("%s: got song %+v, want %+v.", table.song.title, song, table.song)
+		}
+	}
+
+	if _, ok := songEntry(&SongInstance{title: "query only"}); ok {
+		t.Errorf("Song without a source is saved.")
+	}
+}
+
+func TestLibraryPath(t *testing.T) {
+	library := t.TempDir()
+	err := os.Mkdir(filepath.Join(library, "albums"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(library, "albums", "song.mp3"), []byte("mp3"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg = &config.Config{MusicDir: config.MusicDirectory{LibraryPath: library}}
+
+	tables := []struct {
+		path  string
+		isErr bool
+	}{
+		{"albums/song.mp3", false},
+		{filepath.Join(library, "albums", "song.mp3"), false},
+		{"albums/../albums/song.mp3", false},
+		{"albums", true},
+		{"missing.mp3", true},
+		{"../song.mp3", true},
+		{"/etc/passwd", true},
+	}
+
+	for _, table := range tables {
+		_, err := libraryPath(table.path)
+		if (err != nil) != table.isErr {
+			t.Errorf("%s: got error %v, want error: %t.", table.path, err, table.isErr)
+		}
+	}
+
+	cfg = &config.Config{}
+	if _, err := libraryPath("albums/song.mp3"); err == nil {
+		t.Errorf("Local file is played without a music library.")
+	}
+}
+
+func TestFileEntrySong(t *testing.T) {
+	cfg = &config.Config{MusicDir: config.MusicDirectory{LibraryPath: t.TempDir()}}
+
+	song, err := fileEntrySong(playlist.Entry{Source: playlist.SourceLocal, ID: "missing/Artist - Song.mp3", Artist: "Artist", Title: "Song"})
+	if err != nil || song.isLocal || song.artist != "Artist" || song.title != "Song" {
+		t.Errorf("Missing local file is not searched by its title, got %+v, %v", song, err)
+	}
+
+	_, err = fileEntrySong(playlist.Entry{Source: playlist.SourceLocal, ID: "missing/"})
+	if err == nil {
+		t.Errorf("Expected error for a missing local file without a title.")
+	}
+}
+
+func TestPlaylistFileSummary(t *testing.T) {
+	tables := []struct {
+		found      int
+		queued     int
+		unresolved []string
+		summary    string
+	}{
+		{3, 3, nil, "Queued 3 tracks from list.m3u."},
+		{25, 20, []string{"a"}, "Queued 20 tracks from list.m3u. Only the first 20 tracks are played, 5 are left out.\n1 entries couldn't be resolved:\na"},
+		{0, 0, []string{"a", "", "c", "d", "e", "f", "g"}, "Queued 0 tracks from list.m3u.\n7 entries couldn't be resolved:\na\n(empty entry)\nc\nd\ne\n...and 2 more"},
+		//entries failed to be downloaded are listed with the unresolved ones.
+		{3, 2, []string{"b"}, "Queued 2 tracks from list.m3u.\n1 entries couldn't be resolved:\nb"},
+	}
+
+	for _, table := range tables {
+		summary := playlistFileSummary("list.m3u", table.found, table.queued, table.unresolved)
+		if summary != table.summary {
+			t.Errorf("Got summary %q, want %q.", summary, table.summary)
+		}
+	}
+}
+
+func TestPlaylistReport(t *testing.T) {
+	songs := []*SongInstance{{title: "a", location: "a.mp3"}, {title: "b", location: "b.mp3"}, {title: "c", location: "c.mp3"}}
+	calls := 0
+	var queued int
+	var failed []string
+	report := &playlistReport{
+		done: func(q int, f []string) {
+			calls++
+			queued, failed = q, f
+		},
+	}
+
+	report.start(songs)
+	songs[0].report.finish(songs[0], nil)
+
+	//songs are copied when they are taken from the download queue.
+	downloadQueue := createNewQueue()
+	downloadQueue.Put(songs[1])
+	items, err := downloadQueue.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	downloaded := getSongInstanceFromInterface(items[0])
+	downloaded.report.finish(downloaded, errors.New("download failed"))
+	if calls != 0 {
+		t.Fatalf("Summary is sent before all songs are prepared.")
+	}
+
+	songs[2].report.finish(songs[2], nil)
+	if calls != 1 || queued != 2 || !reflect.DeepEqual(failed, []string{"b.mp3"}) {
+		t.Errorf("Report is incorrect, got calls: %d, queued: %d, failed: %v", calls, queued, failed)
+	}
+}
+
+func TestURLEntrySong(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		if r.URL.Path == "/radio" {
+			w.Header().Set("icy-name", "Test Radio")
+		}
+		w.Write([]byte("mp3"))
+	}))
+	defer server.Close()
+
+	tables := []struct {
+		entry  playlist.Entry
+		isLive bool
+	}{
+		//radio is played as live even if the playlist file gives a duration.
+		{playlist.Entry{Source: playlist.SourceURL, ID: server.URL + "/radio", Duration: "3m0s"}, true},
+		//file without a duration is not played as a radio stream.
+		{playlist.Entry{Source: playlist.SourceURL, ID: server.URL + "/song.mp3"}, false},
+	}
+
+	for _, table := range tables {
+		song, err := fileEntrySong(table.entry)
+		if err != nil {
+			t.Errorf("%s: got error: %v", table.entry.ID, err)
+			continue
+		}
+		if song.isLive != table.isLive || song.streamUrl != table.entry.ID {
+			t.Errorf("%s: got song %+v, want live: %t.", table.entry.ID, song, table.isLive)
+		}
+	}
+}
+
+func TestResolveFileEntries(t *testing.T) {
+	probes := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probes++
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Header().Set("icy-name", "Test Radio")
+	}))
+	defer server.Close()
+
+	entries := []playlist.Entry{{Source: playlist.SourceURL, ID: "ftp://example.com/song.mp3"}}
+	for i := 0; i < maxPlaylistFileTracks+5; i++ {
+		entries = append(entries, playlist.Entry{Source: playlist.SourceURL, ID: server.URL + "/radio"})
+	}
+
+	songs, unresolved, leftOut := resolveFileEntries(entries)
+	if len(songs) != maxPlaylistFileTracks || len(unresolved) != 1 || leftOut != 5 {
+		t.Errorf("Got %d songs, unresolved: %v, left out: %d.", len(songs), unresolved, leftOut)
+	}
+
+	//entries after the first maxPlaylistFileTracks songs are not probed.
+	if probes != maxPlaylistFileTracks {
+		t.Errorf("Got %d probes, want %d.", probes, maxPlaylistFileTracks)
+	}
+}
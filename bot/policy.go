@@ -0,0 +1,76 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hemreari/feanor-dcbot/policy"
+	"github.com/hemreari/feanor-dcbot/youtube"
+)
+
+//checkPolicy checks the song with the content policy of the guild before it
+//is added to the queue. video is the Youtube video that the song is played
+//from, it is nil if the video is not known yet. Rejections are sent to the
+//given channel and logged to the audit channel of the policy.
+func (vi *VoiceInstance) checkPolicy(guildID, channelID string, song *SongInstance, video *youtube.SearchResult) bool {
+	guildPolicy, ok := cfg.GuildPolicy(guildID)
+	if !ok {
+		return true
+	}
+
+	track := policy.Track{
+		Title:    song.title,
+		Artist:   strings.TrimSpace(song.artist),
+		VideoID:  song.videoID,
+		Length:   songLength(song),
+		Explicit: song.explicit,
+	}
+	if video != nil {
+		track.VideoID = video.VideoID
+		track.ChannelID = video.ChannelID
+		track.ChannelTitle = video.ChannelTitle
+		track.AgeRestricted = video.AgeRestricted
+		if video.Length > 0 {
+			track.Length = video.Length
+		}
+		if track.Title == "" {
+			track.Title = video.VideoTitle
+		}
+	}
+
+	violation := policy.Check(guildPolicy, track)
+	if violation == nil {
+		return true
+	}
+
+	log.Printf("Rejected %s requested by %s-%s: %v\n", track.Title, song.requesterName, song.requesterID, violation)
+	vi.sendMessageToChannel(channelID, fmt.Sprintf("Couldn't add \"%s\" because %s (%s rule).", track.Title, violation.Reason, violation.Rule))
+
+	if guildPolicy.AuditChannel != "" {
+		text := fmt.Sprintf("Rejected \"%s\"", track.Title)
+		if track.VideoID != "" {
+			text += " (" + youtubeUrlPrefix + track.VideoID + ")"
+		}
+		text += fmt.Sprintf(" requested by %s: %s (%s rule).", song.requesterName, violation.Reason, violation.Rule)
+		vi.sendMessageToChannel(guildPolicy.AuditChannel, text)
+	}
+	return false
+}
+
+//needsVideoInfo checks the policy of the guild has rules that need
+//the channel or the age restriction of the videos.
+func needsVideoInfo(guildID string) bool {
+	guildPolicy, ok := cfg.GuildPolicy(guildID)
+	return ok && (len(guildPolicy.BlockedChannels) > 0 || guildPolicy.BlockExplicit)
+}
+
+//guildID returns the guild of the voice connection, or an empty
+//string if bot is not in a voice channel.
+func (vi *VoiceInstance) guildID() string {
+	dgv := vi.dgv
+	if dgv == nil {
+		return ""
+	}
+	return dgv.GuildID
+}
@@ -0,0 +1,318 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hemreari/feanor-dcbot/util"
+	"github.com/hemreari/go-datastructures/queue"
+)
+
+var (
+	errQueuePosition = errors.New("There is no song at that position in the queue.")
+	errQueueMove     = errors.New("Songs that are still downloading can only be moved among themselves.")
+)
+
+//queueLimit keeps how many more songs and how long music
+//a user can add to the queue. Negative values are unlimited.
+type queueLimit struct {
+	entries    int
+	duration   time.Duration
+	maxEntries int
+	maxLength  time.Duration
+	rejected   int
+}
+
+//newQueueLimit creates the limit of the given user from the configured
+//limits and the songs of the user that are already in the queue.
+func (vi *VoiceInstance) newQueueLimit(userID string) *queueLimit {
+	maxLength, err := time.ParseDuration(cfg.Player.MaxUserDuration)
+	if err != nil && cfg.Player.MaxUserDuration != "" {
+		log.Printf("Error while parsing max user duration: %v", err)
+	}
+
+	limit := &queueLimit{
+		entries:    -1,
+		duration:   -1,
+		maxEntries: cfg.Player.MaxUserEntries,
+		maxLength:  maxLength,
+	}
+	if limit.maxEntries <= 0 && limit.maxLength <= 0 {
+		return limit
+	}
+
+	entries := 0
+	var duration time.Duration
+	for _, song := range vi.queuedSongs() {
+		if song.requesterID == userID {
+			entries++
+			duration += songLength(song)
+		}
+	}
+
+	if limit.maxEntries > 0 {
+		limit.entries = limit.maxEntries - entries
+		if limit.entries < 0 {
+			limit.entries = 0
+		}
+	}
+	if limit.maxLength > 0 {
+		limit.duration = limit.maxLength - duration
+		if limit.duration < 0 {
+			limit.duration = 0
+		}
+	}
+	return limit
+}
+
+//allow checks the song fits in the limit, and takes its share from
+//the limit if it fits. Songs with unknown length only count as entries.
+func (l *queueLimit) allow(song *SongInstance) bool {
+	length := songLength(song)
+	if l.entries == 0 || (l.duration >= 0 && (l.duration == 0 || length > l.duration)) {
+		l.rejected++
+		return false
+	}
+
+	if l.entries > 0 {
+		l.entries--
+	}
+	if l.duration > 0 {
+		l.duration -= length
+	}
+	return true
+}
+
+//message returns the text that tells how many songs are rejected
+//and why, or an empty string if all songs are allowed.
+func (l *queueLimit) message() string {
+	if l.rejected == 0 {
+		return ""
+	}
+
+	limits := []string{}
+	if l.maxEntries > 0 {
+		limits = append(limits, fmt.Sprintf("%d songs", l.maxEntries))
+	}
+	if l.maxLength > 0 {
+		limits = append(limits, l.maxLength.String()+" of music")
+	}
+
+	songs := "songs are"
+	if l.rejected == 1 {
+		songs = "song is"
+	}
+	return fmt.Sprintf("%d %s not added, you can have at most %s in the queue.", l.rejected, songs, strings.Join(limits, " and "))
+}
+
+//sendLimitMessage sends the message of the limit if any song is rejected.
+func (vi *VoiceInstance) sendLimitMessage(channelID string, limit *queueLimit) {
+	if text := limit.message(); text != "" {
+		vi.sendMessageToChannel(channelID, text)
+	}
+}
+
+//songLength returns the length of the song, or 0 if it is unknown.
+func songLength(song *SongInstance) time.Duration {
+	if length, err := time.ParseDuration(song.duration); err == nil {
+		return length
+	}
+	return song.expectedDuration
+}
+
+//applyFairQueue reorders the play queue and the download queue round-robin
+//by requesters if fair queue is enabled. Songs are moved to the play queue
+//in the order they are downloaded, so the download queue is reordered too.
+//Queues are locked, so songs that are put meanwhile are not left out of order.
+func (vi *VoiceInstance) applyFairQueue() {
+	if !cfg.Player.FairQueue {
+		return
+	}
+
+	vi.queueMu.Lock()
+	defer vi.queueMu.Unlock()
+
+	for _, q := range []*queue.Queue{vi.playQueue, vi.downloadQueue} {
+		if q.Len() < 2 {
+			continue
+		}
+
+		err := editQueue(q, func(songs []*SongInstance) ([]*SongInstance, error) {
+			return fairOrder(songs, vi.lastRequesterID), nil
+		})
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+//editQueue replaces the songs in the queue with the songs that edit returns.
+//Songs are put back unchanged if edit returns an error. Queue is empty while
+//it is edited, so callers have to hold queueMu.
+func editQueue(q *queue.Queue, edit func([]*SongInstance) ([]*SongInstance, error)) error {
+	songs := []*SongInstance{}
+	if !q.Empty() {
+		items, err := q.Get(q.Len())
+		if err != nil {
+			return fmt.Errorf("Error while getting items from queue: %v", err)
+		}
+
+		for _, item := range items {
+			if song := getSongInstanceFromInterface(item); song != nil {
+				songs = append(songs, song)
+			}
+		}
+	}
+
+	edited, err := edit(songs)
+	if err != nil {
+		edited = songs
+	}
+
+	for _, song := range edited {
+		q.Put(song)
+	}
+	return err
+}
+
+//queuePosition returns the queue that has the song at the given position and
+//the position of the song in it. Positions start from 0 and count the songs in
+//the play queue first, then the songs that are still downloading.
+func (vi *VoiceInstance) queuePosition(position int) (*queue.Queue, int) {
+	playLen := int(vi.playQueue.Len())
+	if position < playLen {
+		return vi.playQueue, position
+	}
+	return vi.downloadQueue, position - playLen
+}
+
+//removeQueueEntry removes the song at the given position of the queue.
+func (vi *VoiceInstance) removeQueueEntry(position int) (*SongInstance, error) {
+	vi.queueMu.Lock()
+	defer vi.queueMu.Unlock()
+
+	q, index := vi.queuePosition(position)
+
+	var removed *SongInstance
+	err := editQueue(q, func(songs []*SongInstance) ([]*SongInstance, error) {
+		if index < 0 || index >= len(songs) {
+			return nil, errQueuePosition
+		}
+		removed = songs[index]
+		return removeSong(songs, index), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	//songs in the play queue are downloaded, except streams and local files.
+	if q == vi.playQueue && removed.streamUrl == "" && !removed.isLocal {
+		util.DeleteSoundAndCoverFile(removed.songPath, removed.coverPath)
+	}
+	vi.publishQueue()
+	return removed, nil
+}
+
+//moveQueueEntry moves the song at the given position of the queue to the
+//other position. Songs can't be moved between the play and download queues.
+func (vi *VoiceInstance) moveQueueEntry(from, to int) error {
+	vi.queueMu.Lock()
+	defer vi.queueMu.Unlock()
+
+	q, fromIndex := vi.queuePosition(from)
+	toQueue, toIndex := vi.queuePosition(to)
+	if q != toQueue {
+		return errQueueMove
+	}
+
+	err := editQueue(q, func(songs []*SongInstance) ([]*SongInstance, error) {
+		if fromIndex < 0 || fromIndex >= len(songs) || toIndex < 0 || toIndex >= len(songs) {
+			return nil, errQueuePosition
+		}
+		return moveSong(songs, fromIndex, toIndex), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	vi.publishQueue()
+	return nil
+}
+
+//takeSong takes the next song from the queue. It returns nil if the queue
+//is empty, so a song removed by an edit doesn't block the caller.
+func (vi *VoiceInstance) takeSong(q *queue.Queue) *SongInstance {
+	vi.queueMu.Lock()
+	defer vi.queueMu.Unlock()
+
+	if q.Empty() {
+		return nil
+	}
+
+	items, err := q.Get(1)
+	if err != nil {
+		log.Printf("Error while getting item from queue: %v", err)
+		return nil
+	}
+
+	song := getSongInstanceFromInterface(items[0])
+	if song == nil {
+		log.Println("Error while converting interface {} to SongInstance{}.")
+	}
+	return song
+}
+
+//removeSong returns the songs without the song at the given index.
+func removeSong(songs []*SongInstance, index int) []*SongInstance {
+	return append(songs[:index:index], songs[index+1:]...)
+}
+
+//moveSong returns the songs with the song at from moved to the given index.
+func moveSong(songs []*SongInstance, from, to int) []*SongInstance {
+	song := songs[from]
+	moved := removeSong(songs, from)
+	return append(moved[:to:to], append([]*SongInstance{song}, moved[to:]...)...)
+}
+
+//fairOrder orders the songs round-robin by their requesters, keeping the
+//order of each requester's songs. Requesters take turns in the order of
+//their first song, the requester of the last played song goes last.
+func fairOrder(songs []*SongInstance, lastRequesterID string) []*SongInstance {
+	requesters := []string{}
+	byRequester := map[string][]*SongInstance{}
+	for _, song := range songs {
+		if _, ok := byRequester[song.requesterID]; !ok {
+			requesters = append(requesters, song.requesterID)
+		}
+		byRequester[song.requesterID] = append(byRequester[song.requesterID], song)
+	}
+
+	for i, requester := range requesters {
+		if requester == lastRequesterID {
+			requesters = append(append(requesters[:i:i], requesters[i+1:]...), requester)
+			break
+		}
+	}
+
+	ordered := make([]*SongInstance, 0, len(songs))
+	for len(ordered) < len(songs) {
+		for _, requester := range requesters {
+			if queued := byRequester[requester]; len(queued) > 0 {
+				ordered = append(ordered, queued[0])
+				byRequester[requester] = queued[1:]
+			}
+		}
+	}
+	return ordered
+}
+
+//requestedByText returns the text that shows who requested the song.
+func requestedByText(song *SongInstance) string {
+	if song.requesterName == "" {
+		return ""
+	}
+	return "\nRequested by " + song.requesterName
+}
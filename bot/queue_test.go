@@ -0,0 +1,170 @@
+package bot
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hemreari/feanor-dcbot/config"
+	"github.com/hemreari/go-datastructures/queue"
+)
+
+func TestFairOrder(t *testing.T) {
+	songs := []*SongInstance{
+		{title: "a1", requesterID: "a"},
+		{title: "a2", requesterID: "a"},
+		{title: "a3", requesterID: "a"},
+		{title: "b1", requesterID: "b"},
+		{title: "c1", requesterID: "c"},
+		{title: "b2", requesterID: "b"},
+	}
+
+	tables := []struct {
+		lastRequesterID string
+		titles          string
+	}{
+		{"", "a1 b1 c1 a2 b2 a3"},
+		{"a", "b1 c1 a1 b2 a2 a3"},
+		{"x", "a1 b1 c1 a2 b2 a3"},
+	}
+
+	for _, table := range tables {
+		titles := []string{}
+		for _, song := range fairOrder(songs, table.lastRequesterID) {
+			titles = append(titles, song.title)
+		}
+
+		if got := strings.Join(titles, " "); got != table.titles {
+			t.Errorf("Last requester %q: got %s, want %s.", table.lastRequesterID, got, table.titles)
+		}
+	}
+}
+
+func TestApplyFairQueue(t *testing.T) {
+	cfg = &config.Config{Player: config.PlayerConfig{FairQueue: true}}
+	vi := &VoiceInstance{playQueue: createNewQueue(), downloadQueue: createNewQueue()}
+	for _, title := range []string{"a1", "a2", "b1"} {
+		vi.playQueue.Put(&SongInstance{title: title, requesterID: title[:1]})
+	}
+	//songs of a playlist are waiting to be downloaded before the others.
+	for _, title := range []string{"a3", "a4", "a5", "c1"} {
+		vi.downloadQueue.Put(&SongInstance{title: title, requesterID: title[:1]})
+	}
+
+	vi.applyFairQueue()
+
+	for _, table := range []struct {
+		name   string
+		q      *queue.Queue
+		titles string
+	}{
+		{"play queue", vi.playQueue, "a1 b1 a2"},
+		{"download queue", vi.downloadQueue, "a3 c1 a4 a5"},
+	} {
+		titles := []string{}
+		for !table.q.Empty() {
+			items, _ := table.q.Get(1)
+			titles = append(titles, getSongInstanceFromInterface(items[0]).title)
+		}
+
+		if got := strings.Join(titles, " "); got != table.titles {
+			t.Errorf("%s: got %s, want %s.", table.name, got, table.titles)
+		}
+	}
+}
+
+func TestWaitPreparedSong(t *testing.T) {
+	vi := &VoiceInstance{playQueue: createNewQueue(), preparing: 1}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		vi.playQueue.Put(&SongInstance{title: "later"})
+		atomic.AddInt32(&vi.preparing, -1)
+	}()
+
+	//play process has to wait for the song that is being prepared.
+	vi.waitPreparedSong()
+	if vi.playQueue.Empty() {
+		t.Errorf("Play process didn't wait for the prepared song.")
+	}
+}
+
+func TestTakeSong(t *testing.T) {
+	cfg = &config.Config{}
+	vi := &VoiceInstance{playQueue: createNewQueue(), downloadQueue: createNewQueue()}
+	vi.playQueue.Put(&SongInstance{title: "a"})
+
+	//last song is removed after the play process saw the queue is not empty.
+	if _, err := vi.removeQueueEntry(0); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan *SongInstance)
+	go func() {
+		done <- vi.takeSong(vi.playQueue)
+	}()
+
+	select {
+	case song := <-done:
+		if song != nil {
+			t.Errorf("Got song %+v from an empty queue.", song)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Taking a song from an empty queue blocked.")
+	}
+}
+
+func TestQueueLimit(t *testing.T) {
+	limit := &queueLimit{entries: 2, duration: 10 * time.Minute, maxEntries: 3, maxLength: 15 * time.Minute}
+
+	tables := []struct {
+		duration string
+		allowed  bool
+	}{
+		{"11m0s", false}, //longer than what is left
+		{"4m0s", true},
+		{"", true}, //unknown length
+		{"1m0s", false},
+	}
+
+	for i, table := range tables {
+		if allowed := limit.allow(&SongInstance{duration: table.duration}); allowed != table.allowed {
+			t.Errorf("%d) %s: got %t, want %t.", i+1, table.duration, allowed, table.allowed)
+		}
+	}
+
+	want := "2 songs are not added, you can have at most 3 songs and 15m0s of music in the queue."
+	if message := limit.message(); message != want {
+		t.Errorf("Got message %q, want %q.", message, want)
+	}
+
+	unlimited := &queueLimit{entries: -1, duration: -1}
+	if !unlimited.allow(&SongInstance{duration: "3h0m0s"}) || unlimited.message() != "" {
+		t.Errorf("Unlimited queue limit rejected a song.")
+	}
+}
+
+func TestMoveSong(t *testing.T) {
+	tables := []struct {
+		from   int
+		to     int
+		titles string
+	}{
+		{0, 0, "a b c d"},
+		{0, 2, "b c a d"},
+		{3, 0, "d a b c"},
+		{1, 3, "a c d b"},
+	}
+
+	for _, table := range tables {
+		songs := []*SongInstance{{title: "a"}, {title: "b"}, {title: "c"}, {title: "d"}}
+		titles := []string{}
+		for _, song := range moveSong(songs, table.from, table.to) {
+			titles = append(titles, song.title)
+		}
+
+		if strings.Join(titles, " ") != table.titles {
+			t.Errorf("%d to %d: got %s, want %s.", table.from, table.to, strings.Join(titles, " "), table.titles)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package bot
+
+import (
+	"log"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	//voiceSendTimeout is how long an audio frame waits for the voice
+	//connection to be ready before the connection is counted as lost.
+	voiceSendTimeout = 5 * time.Second
+	//voiceReadyPollInterval is how often a waiting audio frame checks
+	//the voice connection is ready again.
+	voiceReadyPollInterval = 100 * time.Millisecond
+	//voiceReconnectAttempts is how many times bot tries to join
+	//the voice channel again after the connection is lost.
+	voiceReconnectAttempts = 5
+	//voiceReconnectDelay is the delay before the first reconnect
+	//attempt, it is doubled after every attempt.
+	voiceReconnectDelay = time.Second
+)
+
+//voiceReady checks the voice connection is ready to send audio.
+func voiceReady(v *discordgo.VoiceConnection) bool {
+	v.RLock()
+	defer v.RUnlock()
+	return v.Ready && v.OpusSend != nil
+}
+
+//sendOpus sends the opus packet to the voice connection. Discord closes
+//and opens the connection again when the voice server is changed, so the
+//packet waits for the connection to be ready for voiceSendTimeout.
+func sendOpus(v *discordgo.VoiceConnection, opus []byte) bool {
+	timeout := time.NewTimer(voiceSendTimeout)
+	defer timeout.Stop()
+
+	for !voiceReady(v) {
+		select {
+		case <-timeout.C:
+			return false
+		case <-time.After(voiceReadyPollInterval):
+		}
+	}
+
+	select {
+	case v.OpusSend <- opus:
+		return true
+	case <-timeout.C:
+		return false
+	}
+}
+
+//reconnectBackoff returns the delays before each reconnect attempt.
+func reconnectBackoff(attempts int, delay time.Duration) []time.Duration {
+	delays := make([]time.Duration, 0, attempts)
+	for i := 0; i < attempts; i++ {
+		delays = append(delays, delay)
+		delay *= 2
+	}
+	return delays
+}
+
+//resumePosition returns the position of the song after the given number
+//of frames are sent from its start time.
+func resumePosition(startTime time.Duration, frames int64) time.Duration {
+	return startTime + time.Duration(frames)*frameDuration
+}
+
+//reconnectVoice joins the voice channel again with backoff until the
+//voice connection is ready. It returns false if bot couldn't reconnect.
+//Only one reconnect runs at a time, others wait for it and check the
+//connection again.
+func (vi *VoiceInstance) reconnectVoice() bool {
+	vi.reconnectMu.Lock()
+	defer vi.reconnectMu.Unlock()
+
+	dgv := vi.dgv
+	if dgv == nil {
+		return false
+	}
+	guildID, channelID := dgv.GuildID, dgv.ChannelID
+
+	for i, delay := range reconnectBackoff(voiceReconnectAttempts, voiceReconnectDelay) {
+		time.Sleep(delay)
+
+		//discordgo may have reconnected already.
+		if voiceReady(dgv) {
+			return true
+		}
+
+		if !vi.session.DataReady {
+			log.Printf("Discord session is not ready, couldn't reconnect to the voice channel (attempt %d).\n", i+1)
+			continue
+		}
+
+		log.Printf("Reconnecting to the voice channel %s (attempt %d).\n", channelID, i+1)
+		v, err := vi.session.ChannelVoiceJoin(guildID, channelID, false, true)
+		if err != nil {
+			log.Printf("Error while reconnecting to the voice channel: %v\n", err)
+			continue
+		}
+		vi.dgv = v
+		return true
+	}
+	return false
+}
+
+//resumeSong reconnects the lost voice connection and plays the song again
+//from the last position that is sent. If bot can't reconnect, the queue is
+//cleared and the play process ends.
+func (vi *VoiceInstance) resumeSong(songInstance *SongInstance, channelID string, stop chan<- int, frames int64) {
+	log.Printf("Voice connection is lost while playing %s.\n", songInstance.title)
+	vi.sendMessageToChannel(channelID, "Lost the voice connection, reconnecting...")
+
+	if !vi.reconnectVoice() {
+		log.Println("Couldn't reconnect to the voice channel, ending play process.")
+		vi.sendMessageToChannel(channelID, "Couldn't reconnect to the voice channel, the queue is cleared. Please, Try again later.")
+		vi.downloadQueue = createNewQueue()
+		vi.playQueue = clearPlaylistQueue(vi.playQueue)
+		vi.publishQueue()
+		vi.isPlaying = false
+
+		vi.timerMu.Lock()
+		vi.leaving = true
+		vi.timerMu.Unlock()
+		stop <- 0
+		return
+	}
+
+	//radio streams can't be seeked, they are continued from now.
+	resumed := songInstance
+	if !songInstance.isLive {
+		copied := *songInstance
+		copied.startTime = resumePosition(songInstance.startTime, frames)
+		resumed = &copied
+	}
+
+	log.Printf("Reconnected to the voice channel, resuming %s from %s.\n", songInstance.title, resumed.startTime)
+	vi.sendMessageToChannel(channelID, "Reconnected, resuming the song.")
+	vi.playAudioFile(resumed, channelID, stop)
+}
+
+//voiceServerUpdate checks the voice connection after the voice server is
+//changed. discordgo connects to the new server itself, audio frames wait
+//for it in sendOpus. If it isn't connected in time, bot joins again.
+func voiceServerUpdate(s *discordgo.Session, v *discordgo.VoiceServerUpdate) {
+	if vi == nil || v.GuildID != vi.guildID() {
+		return
+	}
+
+	log.Printf("Voice server is changed to %s.\n", v.Endpoint)
+	go vi.checkVoiceConnection()
+}
+
+//disconnect logs the lost gateway connection, discordgo reconnects it itself.
+func disconnect(s *discordgo.Session, d *discordgo.Disconnect) {
+	log.Println("Disconnected from Discord, reconnecting.")
+}
+
+//resumed checks the voice connection after the gateway connection is
+//resumed. While a song is playing, the play process reconnects it.
+func resumed(s *discordgo.Session, r *discordgo.Resumed) {
+	log.Println("Connection to Discord is resumed.")
+	if vi == nil || vi.isPlaying {
+		return
+	}
+
+	dgv := vi.dgv
+	if dgv == nil || voiceReady(dgv) {
+		return
+	}
+
+	go vi.checkVoiceConnection()
+}
+
+//checkVoiceConnection reconnects the voice connection if it isn't ready.
+//If bot can't reconnect while a song is playing, the play process ends
+//itself, otherwise bot leaves the voice channel.
+func (vi *VoiceInstance) checkVoiceConnection() {
+	if vi.dgv == nil || vi.reconnectVoice() || vi.isPlaying {
+		return
+	}
+
+	log.Println("Couldn't reconnect to the voice channel, leaving.")
+	vi.sendMessageToChannel(vi.textChannelID, "Lost the voice connection and couldn't reconnect.")
+	vi.leaveVoiceChannel()
+}
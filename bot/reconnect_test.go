@@ -0,0 +1,42 @@
+package bot
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReconnectBackoff(t *testing.T) {
+	tables := []struct {
+		attempts int
+		delays   []time.Duration
+	}{
+		{0, []time.Duration{}},
+		{1, []time.Duration{time.Second}},
+		{5, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second}},
+	}
+
+	for _, table := range tables {
+		if delays := reconnectBackoff(table.attempts, time.Second); !reflect.DeepEqual(delays, table.delays) {
+			t.Errorf("%d attempts: got %v, want %v.", table.attempts, delays, table.delays)
+		}
+	}
+}
+
+func TestResumePosition(t *testing.T) {
+	tables := []struct {
+		startTime time.Duration
+		frames    int64
+		position  time.Duration
+	}{
+		{0, 0, 0},
+		{0, 50, time.Second},
+		{90 * time.Second, 1500, 2 * time.Minute},
+	}
+
+	for _, table := range tables {
+		if position := resumePosition(table.startTime, table.frames); position != table.position {
+			t.Errorf("%s + %d frames: got %s, want %s.", table.startTime, table.frames, position, table.position)
+		}
+	}
+}
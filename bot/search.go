@@ -0,0 +1,339 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/hemreari/feanor-dcbot/util"
+	"github.com/hemreari/feanor-dcbot/youtube"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	//search sessions are cancelled if user doesn't choose in this time.
+	searchSessionTimeout = time.Minute
+	cancelEmoji          = "❌"
+)
+
+//numberEmojis are the reactions that are used to choose search results.
+var numberEmojis = []string{"1️⃣", "2️⃣", "3️⃣", "4️⃣", "5️⃣", "6️⃣", "7️⃣", "8️⃣", "9️⃣", "🔟"}
+
+//searchSession is a !search command that is waiting for its user to
+//choose from the results. Only the replies and reactions of the same
+//user in the same channel are taken as a selection.
+type searchSession struct {
+	request   *discordgo.MessageCreate //the !search message
+	messageID string                   //search results message
+	results   []youtube.SearchResult
+	timer     *time.Timer
+}
+
+var (
+	searchSessions   = map[string]*searchSession{}
+	searchSessionsMu sync.Mutex
+)
+
+func searchSessionKey(channelID, userID string) string {
+	return channelID + ":" + userID
+}
+
+//startSearchSession waits for the author of the given !search message to
+//choose from the results in the given message. A previous search of the
+//same user in the same channel is replaced.
+func (vi *VoiceInstance) startSearchSession(m *discordgo.MessageCreate, messageID string, results []youtube.SearchResult) {
+	key := searchSessionKey(m.ChannelID, m.Author.ID)
+	session := &searchSession{
+		request:   m,
+		messageID: messageID,
+		results:   results,
+	}
+
+	searchSessionsMu.Lock()
+	if old, ok := searchSessions[key]; ok {
+		old.timer.Stop()
+	}
+	searchSessions[key] = session
+	session.timer = time.AfterFunc(searchSessionTimeout, func() {
+		if endSearchSession(key, session) {
+			vi.sendMessageToChannel(m.ChannelID, "Search is timed out.")
+		}
+	})
+	searchSessionsMu.Unlock()
+
+	for i := range results {
+		if i >= len(numberEmojis) {
+			break
+		}
+		err := vi.session.MessageReactionAdd(m.ChannelID, messageID, numberEmojis[i])
+		if err != nil {
+			log.Printf("Error while adding search reaction: %v", err)
+			return
+		}
+	}
+
+	err := vi.session.MessageReactionAdd(m.ChannelID, messageID, cancelEmoji)
+	if err != nil {
+		log.Printf("Error while adding search reaction: %v", err)
+	}
+}
+
+//getSearchSession returns the search session of the given user in the given channel.
+func getSearchSession(channelID, userID string) *searchSession {
+	searchSessionsMu.Lock()
+	defer searchSessionsMu.Unlock()
+
+	return searchSessions[searchSessionKey(channelID, userID)]
+}
+
+//endSearchSession removes the given session. Returns false if the session
+//is already ended, so that a selection is not handled twice.
+func endSearchSession(key string, session *searchSession) bool {
+	searchSessionsMu.Lock()
+	defer searchSessionsMu.Unlock()
+
+	if searchSessions[key] != session {
+		return false
+	}
+
+	delete(searchSessions, key)
+	session.timer.Stop()
+	return true
+}
+
+//searchReplyHandler handles the replies to search results like "2" or "1 3 5".
+func searchReplyHandler(s *discordgo.Session, m *discordgo.MessageCreate) {
+	if m.Author.ID == s.State.User.ID {
+		return
+	}
+
+	session := getSearchSession(m.ChannelID, m.Author.ID)
+	if session == nil {
+		return
+	}
+
+	content := strings.ToLower(strings.TrimSpace(m.Content))
+	if content == "cancel" || content == "!cancel" {
+		vi.cancelSearchSession(session)
+		return
+	}
+
+	//other commands are handled by messageCreate.
+	if strings.HasPrefix(content, "!") {
+		return
+	}
+
+	selection, err := parseSelection(content, len(session.results))
+	if err != nil {
+		vi.sendMessageToChannel(m.ChannelID, fmt.Sprintf("%v Choose with numbers between 1 and %d like \"1 3\", or type cancel.",
+			err, len(session.results)))
+		return
+	}
+
+	vi.selectSearchResults(session, selection)
+}
+
+//searchReactionHandler handles the number and cancel reactions to search results.
+func searchReactionHandler(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
+	if r.UserID == s.State.User.ID {
+		return
+	}
+
+	session := getSearchSession(r.ChannelID, r.UserID)
+	if session == nil || session.messageID != r.MessageID {
+		return
+	}
+
+	if r.Emoji.Name == cancelEmoji {
+		vi.cancelSearchSession(session)
+		return
+	}
+
+	for i, emoji := range numberEmojis {
+		if r.Emoji.Name == emoji && i < len(session.results) {
+			vi.selectSearchResults(session, []int{i + 1})
+			return
+		}
+	}
+}
+
+func (vi *VoiceInstance) cancelSearchSession(session *searchSession) {
+	m := session.request
+	if endSearchSession(searchSessionKey(m.ChannelID, m.Author.ID), session) {
+		vi.sendMessageToChannel(m.ChannelID, "Search is cancelled.")
+	}
+}
+
+//selectSearchResults plays the chosen results of the session in the given order.
+func (vi *VoiceInstance) selectSearchResults(session *searchSession, selection []int) {
+	m := session.request
+	if !endSearchSession(searchSessionKey(m.ChannelID, m.Author.ID), session) {
+		return
+	}
+
+	chosen := []youtube.SearchResult{}
+	for _, number := range selection {
+		chosen = append(chosen, session.results[number-1])
+	}
+
+	vi.sentMessageEditEmbed(m.ChannelID, session.messageID, chosen)
+	vi.prepSearchSelectionPlay(chosen, vi.session, m)
+}
+
+//parseSelection parses the chosen result numbers like "1 3 5" or "1,3".
+//Numbers must be between 1 and max, repeated numbers are ignored.
+func parseSelection(text string, max int) ([]int, error) {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return unicode.IsSpace(r) || r == ','
+	})
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("No result is chosen.")
+	}
+
+	selection := []int{}
+	chosen := map[int]bool{}
+	for _, field := range fields {
+		number, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("\"%s\" is not a number.", field)
+		}
+
+		if number < 1 || number > max {
+			return nil, fmt.Errorf("%d is not an available option.", number)
+		}
+
+		if chosen[number] {
+			continue
+		}
+		chosen[number] = true
+		selection = append(selection, number)
+	}
+	return selection, nil
+}
+
+//parseSearchArgs parses the arguments of !search command like
+//"--playlist --region TR query" to the query and search options.
+func parseSearchArgs(args string) (string, youtube.SearchOptions, error) {
+	opts := youtube.SearchOptions{}
+	queryWords := []string{}
+
+	fields := strings.Fields(args)
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		if !strings.HasPrefix(field, "--") {
+			queryWords = append(queryWords, field)
+			continue
+		}
+
+		switch field {
+		case "--playlist":
+			opts.Kind = util.KindPlaylist
+			continue
+		case "--channel":
+			opts.Kind = util.KindChannel
+			continue
+		case "--min", "--max", "--region":
+		default:
+			return "", opts, fmt.Errorf("Unknown option %s.", field)
+		}
+
+		//remaining options have a value.
+		if i+1 >= len(fields) {
+			return "", opts, fmt.Errorf("Option %s needs a value.", field)
+		}
+		i++
+		value := fields[i]
+
+		switch field {
+		case "--min", "--max":
+			duration, err := parseDurationArg(value)
+			if err != nil {
+				return "", opts, fmt.Errorf("Invalid duration \"%s\" for %s.", value, field)
+			}
+			if field == "--min" {
+				opts.MinDuration = duration
+			} else {
+				opts.MaxDuration = duration
+			}
+		case "--region":
+			if len(value) != 2 {
+				return "", opts, fmt.Errorf("Region must be a 2 letter country code like TR.")
+			}
+			opts.RegionCode = strings.ToUpper(value)
+		}
+	}
+
+	if opts.MinDuration > 0 && opts.MaxDuration > 0 && opts.MinDuration > opts.MaxDuration {
+		return "", opts, fmt.Errorf("--min can't be longer than --max.")
+	}
+
+	return strings.Join(queryWords, " "), opts, nil
+}
+
+//parseDurationArg parses durations like "2m", "1m30s", "3:20" and "90".
+func parseDurationArg(value string) (time.Duration, error) {
+	if duration, err := time.ParseDuration(value); err == nil && duration > 0 {
+		return duration, nil
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	parts := strings.Split(value, ":")
+	if len(parts) == 2 {
+		minutes, minErr := strconv.Atoi(parts[0])
+		seconds, secErr := strconv.Atoi(parts[1])
+		if minErr == nil && secErr == nil && minutes >= 0 && seconds >= 0 && seconds < 60 {
+			duration := time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second
+			if duration > 0 {
+				return duration, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("Invalid duration: %s", value)
+}
+
+//formatSearchResultText is a helper function to create embeded
+//link text of the given search result with its channel and views.
+func formatSearchResultText(result *youtube.SearchResult) string {
+	switch result.Kind {
+	case util.KindPlaylist:
+		return "Playlist: [" + result.VideoTitle + "](https://www.youtube.com/playlist?list=" + result.PlaylistID + ")\n" +
+			result.ChannelTitle
+	case util.KindChannel:
+		return "Channel: [" + result.VideoTitle + "](https://www.youtube.com/channel/" + result.ChannelID + ")"
+	}
+
+	text := formatEmbededLinkText(result.VideoTitle, result.Duration, result.VideoID)
+	details := []string{}
+	if result.ChannelTitle != "" {
+		details = append(details, result.ChannelTitle)
+	}
+	if result.ViewCount > 0 {
+		details = append(details, formatViewCount(result.ViewCount)+" views")
+	}
+
+	if len(details) > 0 {
+		text += "\n" + strings.Join(details, " • ")
+	}
+	return text
+}
+
+//formatViewCount formats view counts like 1.2K, 3.4M and 1.1B.
+func formatViewCount(views uint64) string {
+	switch {
+	case views >= 1000000000:
+		return strconv.FormatFloat(float64(views)/1000000000, 'f', 1, 64) + "B"
+	case views >= 1000000:
+		return strconv.FormatFloat(float64(views)/1000000, 'f', 1, 64) + "M"
+	case views >= 1000:
+		return strconv.FormatFloat(float64(views)/1000, 'f', 1, 64) + "K"
+	}
+	return strconv.FormatUint(views, 10)
+}
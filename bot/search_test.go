@@ -0,0 +1,95 @@
+package bot
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hemreari/feanor-dcbot/util"
+	"github.com/hemreari/feanor-dcbot/youtube"
+)
+
+func TestParseSelection(t *testing.T) {
+	tables := []struct {
+		text      string
+		max       int
+		selection []int
+		isErr     bool
+	}{
+		{"2", 5, []int{2}, false},
+		{"1 3 5", 5, []int{1, 3, 5}, false},
+		{"3,1", 5, []int{3, 1}, false},
+		{" 4 ,  2 4 ", 5, []int{4, 2}, false},
+		{"0", 5, nil, true},
+		{"6", 5, nil, true},
+		{"1 lol", 5, nil, true},
+		{"lol", 5, nil, true},
+		{"", 5, nil, true},
+	}
+
+	for _, table := range tables {
+		selection, err := parseSelection(table.text, table.max)
+		if (err != nil) != table.isErr {
+			t.Errorf("\"%s\": got error %v, want error: %t.", table.text, err, table.isErr)
+		}
+		if !table.isErr && !reflect.DeepEqual(selection, table.selection) {
+			t.Errorf("\"%s\": got %v, want %v.", table.text, selection, table.selection)
+		}
+	}
+}
+
+func TestParseSearchArgs(t *testing.T) {
+	tables := []struct {
+		args  string
+		query string
+		opts  youtube.SearchOptions
+		isErr bool
+	}{
+		{"billie jean", "billie jean", youtube.SearchOptions{}, false},
+		{"--playlist 80s hits", "80s hits", youtube.SearchOptions{Kind: util.KindPlaylist}, false},
+		{"tarkan --channel", "tarkan", youtube.SearchOptions{Kind: util.KindChannel}, false},
+		{"--min 2m --max 3:30 beat it", "beat it",
+			youtube.SearchOptions{MinDuration: 2 * time.Minute, MaxDuration: 210 * time.Second}, false},
+		{"--region tr sezen aksu", "sezen aksu", youtube.SearchOptions{RegionCode: "TR"}, false},
+		{"--max 90 intro", "intro", youtube.SearchOptions{MaxDuration: 90 * time.Second}, false},
+		{"--min 10m --max 2m song", "", youtube.SearchOptions{}, true},
+		{"--min soon song", "", youtube.SearchOptions{}, true},
+		{"--region turkey song", "", youtube.SearchOptions{}, true},
+		{"song --max", "", youtube.SearchOptions{}, true},
+		{"--live song", "", youtube.SearchOptions{}, true},
+	}
+
+	for _, table := range tables {
+		query, opts, err := parseSearchArgs(table.args)
+		if (err != nil) != table.isErr {
+			t.Errorf("\"%s\": got error %v, want error: %t.", table.args, err, table.isErr)
+			continue
+		}
+		if table.isErr {
+			continue
+		}
+
+		if query != table.query || opts != table.opts {
+			t.Errorf("\"%s\": got \"%s\" %+v, want \"%s\" %+v.", table.args, query, opts, table.query, table.opts)
+		}
+	}
+}
+
+func TestFormatViewCount(t *testing.T) {
+	tables := []struct {
+		views uint64
+		text  string
+	}{
+		{0, "0"},
+		{999, "999"},
+		{1250, "1.2K"},
+		{3400000, "3.4M"},
+		{1100000000, "1.1B"},
+	}
+
+	for _, table := range tables {
+		if text := formatViewCount(table.views); text != table.text {
+			t.Errorf("%d: got %s, want %s.", table.views, text, table.text)
+		}
+	}
+}
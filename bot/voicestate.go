@@ -0,0 +1,191 @@
+package bot
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	//defaultEmptyTimeout is how long bot waits for someone to come
+	//back after everyone left its voice channel.
+	defaultEmptyTimeout = 2 * time.Minute
+	//defaultIdleTimeout is how long bot stays in the voice channel
+	//after the play queue ends.
+	defaultIdleTimeout = 5 * time.Minute
+	//pausePollInterval is how often a paused song checks it is resumed.
+	pausePollInterval = 100 * time.Millisecond
+)
+
+//voiceStateUpdate pauses the song when there is no one left in the
+//bot's voice channel, and resumes it if someone comes back in time.
+func voiceStateUpdate(s *discordgo.Session, v *discordgo.VoiceStateUpdate) {
+	if vi == nil || v.GuildID != vi.guildID() {
+		return
+	}
+
+	vi.checkListeners()
+}
+
+//checkListeners starts the empty channel timer if there are no listeners
+//in the bot's voice channel, or cancels it if someone came back.
+func (vi *VoiceInstance) checkListeners() {
+	if vi.dgv == nil {
+		return
+	}
+	empty := len(vi.listeners()) == 0
+
+	vi.timerMu.Lock()
+	defer vi.timerMu.Unlock()
+
+	if empty && vi.emptyTimer == nil && vi.isPlaying {
+		timeout := configDuration(cfg.Player.EmptyTimeout, defaultEmptyTimeout)
+		log.Printf("Voice channel is empty, pausing and leaving in %s.\n", timeout)
+
+		vi.autoPaused = !vi.isPaused()
+		vi.setPaused(true)
+		vi.emptyTimer = time.AfterFunc(timeout, vi.leaveEmptyChannel)
+		vi.sendMessageToChannel(vi.textChannelID, "Everyone left, song is paused. I will leave in "+timeout.String()+" if no one comes back.")
+		return
+	}
+
+	if !empty && vi.emptyTimer != nil {
+		log.Println("Someone came back to the voice channel.")
+		vi.emptyTimer.Stop()
+		vi.emptyTimer = nil
+
+		if vi.autoPaused {
+			vi.autoPaused = false
+			vi.setPaused(false)
+			vi.sendMessageToChannel(vi.textChannelID, "Welcome back, song is resumed.")
+		}
+	}
+}
+
+//leaveEmptyChannel stops playing and leaves the voice channel
+//if there are still no listeners.
+func (vi *VoiceInstance) leaveEmptyChannel() {
+	vi.timerMu.Lock()
+	vi.emptyTimer = nil
+	vi.autoPaused = false
+	vi.timerMu.Unlock()
+
+	if len(vi.listeners()) > 0 {
+		vi.setPaused(false)
+		return
+	}
+
+	log.Println("No one came back to the voice channel, leaving.")
+	vi.leave()
+}
+
+//leave leaves the voice channel. If a song is playing, it is stopped
+//and bot leaves when the play process ends, since the play process
+//can't end after the voice connection is closed.
+func (vi *VoiceInstance) leave() {
+	vi.setPaused(false)
+	if !vi.isPlaying {
+		vi.leaveVoiceChannel()
+		return
+	}
+
+	vi.timerMu.Lock()
+	vi.leaving = true
+	vi.timerMu.Unlock()
+	vi.stop = true
+}
+
+//startIdleTimer starts the timer that leaves the voice channel if
+//nothing is played for the idle timeout. It is called when the
+//play process ends, bot leaves at once if it is leaving.
+func (vi *VoiceInstance) startIdleTimer() {
+	vi.timerMu.Lock()
+	leaving := vi.leaving
+	vi.leaving = false
+	vi.timerMu.Unlock()
+
+	//bot may have left the channel already.
+	if vi.dgv == nil {
+		return
+	}
+
+	if leaving {
+		vi.leaveVoiceChannel()
+		return
+	}
+
+	err := vi.dgv.Speaking(false)
+	if err != nil {
+		log.Println("Couldn't stop speaking", err)
+	}
+
+	vi.timerMu.Lock()
+	defer vi.timerMu.Unlock()
+
+	if vi.idleTimer != nil {
+		vi.idleTimer.Stop()
+	}
+	vi.idleTimer = time.AfterFunc(configDuration(cfg.Player.IdleTimeout, defaultIdleTimeout), func() {
+		if vi.isPlaying {
+			return
+		}
+		log.Println("Bot is idle, leaving the voice channel.")
+		vi.leaveVoiceChannel()
+	})
+}
+
+//stopIdleTimer cancels the idle timer when a new play process starts.
+func (vi *VoiceInstance) stopIdleTimer() {
+	vi.timerMu.Lock()
+	defer vi.timerMu.Unlock()
+
+	if vi.idleTimer != nil {
+		vi.idleTimer.Stop()
+		vi.idleTimer = nil
+	}
+}
+
+//leaveVoiceChannel disconnects the bot from the voice channel.
+func (vi *VoiceInstance) leaveVoiceChannel() {
+	vi.stopIdleTimer()
+	if vi.dgv == nil {
+		return
+	}
+
+	vi.disconnectBot()
+	vi.sendMessageToChannel(vi.textChannelID, "See you later.")
+}
+
+//isPaused checks the songs are paused.
+func (vi *VoiceInstance) isPaused() bool {
+	return atomic.LoadInt32(&vi.paused) == 1
+}
+
+//setPaused pauses or resumes the song.
+func (vi *VoiceInstance) setPaused(paused bool) {
+	var value int32
+	if paused {
+		value = 1
+	}
+
+	if atomic.SwapInt32(&vi.paused, value) != value {
+		vi.publishNowPlaying()
+	}
+}
+
+//configDuration parses the duration in the config,
+//default is used if it is empty or invalid.
+func configDuration(value string, defaultDuration time.Duration) time.Duration {
+	if value == "" {
+		return defaultDuration
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Printf("Invalid duration in config: %s, using %s.\n", value, defaultDuration)
+		return defaultDuration
+	}
+	return duration
+}
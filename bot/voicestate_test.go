@@ -0,0 +1,25 @@
+package bot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfigDuration(t *testing.T) {
+	tables := []struct {
+		value    string
+		duration time.Duration
+	}{
+		{"", time.Minute},
+		{"30s", 30 * time.Second},
+		{"10m", 10 * time.Minute},
+		{"soon", time.Minute},
+		{"-5m", time.Minute},
+	}
+
+	for _, table := range tables {
+		if duration := configDuration(table.value, time.Minute); duration != table.duration {
+			t.Errorf("%q: got %s, want %s.", table.value, duration, table.duration)
+		}
+	}
+}
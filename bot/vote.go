@@ -0,0 +1,144 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+//defaultSkipVoteRatio is used if skip vote ratio is not configured.
+const defaultSkipVoteRatio = 0.5
+
+//skipSong skips the now playing song if the author is its requester or a DJ.
+//Otherwise the author's vote is counted and the song is skipped when enough
+//listeners in the bot's voice channel voted.
+func (vi *VoiceInstance) skipSong(m *discordgo.MessageCreate) {
+	//if currently no song is playing, no need to skip it.
+	if vi.isPlaying == false || vi.dgv == nil {
+		log.Println("No song is playing. skip returning.")
+		return
+	}
+
+	listeners := vi.listeners()
+	if !listeners[m.Author.ID] {
+		vi.sendMessageToChannel(m.ChannelID, "You have to be in the voice channel to skip.")
+		return
+	}
+
+	nowPlaying := vi.nowPlaying
+	if (nowPlaying != nil && nowPlaying.requesterID == m.Author.ID) || isDJ(vi.session, m) {
+		vi.skip = true
+		return
+	}
+
+	votes, needed := vi.addSkipVote(m.Author.ID, listeners)
+	if votes >= needed {
+		vi.sendMessageToChannel(m.ChannelID, fmt.Sprintf("Skip vote passed (%d/%d).", votes, needed))
+		vi.skip = true
+		return
+	}
+
+	vi.sendMessageToChannel(m.ChannelID, fmt.Sprintf("%s voted to skip (%d/%d).", requesterName(m), votes, needed))
+
+	//show the progress on the now playing message, it is in the
+	//channel that the play process is started from.
+	if nowPlaying != nil {
+		err := vi.sendEmbedNowPlayingMessage(vi.textChannelID, nowPlaying)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+//addSkipVote adds the vote of the given user. Returns the votes of the users
+//that are still listening and the number of votes that is needed to skip.
+func (vi *VoiceInstance) addSkipVote(userID string, listeners map[string]bool) (int, int) {
+	vi.skipVotesMu.Lock()
+	defer vi.skipVotesMu.Unlock()
+
+	if vi.skipVotes == nil {
+		vi.skipVotes = map[string]bool{}
+	}
+	vi.skipVotes[userID] = true
+
+	return countVotes(vi.skipVotes, listeners), votesNeeded(len(listeners), cfg.Player.SkipVoteRatio)
+}
+
+//resetSkipVotes removes the votes, it is called when a new song starts.
+func (vi *VoiceInstance) resetSkipVotes() {
+	vi.skipVotesMu.Lock()
+	vi.skipVotes = map[string]bool{}
+	vi.skipVotesMu.Unlock()
+}
+
+//skipVoteStatus returns the skip vote progress like "1/3",
+//or an empty string if no one voted.
+func (vi *VoiceInstance) skipVoteStatus() string {
+	vi.skipVotesMu.Lock()
+	voted := len(vi.skipVotes) > 0
+	vi.skipVotesMu.Unlock()
+	if !voted {
+		return ""
+	}
+
+	listeners := vi.listeners()
+
+	vi.skipVotesMu.Lock()
+	defer vi.skipVotesMu.Unlock()
+	return fmt.Sprintf("%d/%d", countVotes(vi.skipVotes, listeners), votesNeeded(len(listeners), cfg.Player.SkipVoteRatio))
+}
+
+//listeners returns the users that are not bots in the voice channel of the bot.
+func (vi *VoiceInstance) listeners() map[string]bool {
+	listeners := map[string]bool{}
+	dgv := vi.dgv
+	if dgv == nil {
+		return listeners
+	}
+
+	guild, err := vi.session.State.Guild(dgv.GuildID)
+	if err != nil {
+		log.Printf("Couldn't find guild: %v\n", err)
+		return listeners
+	}
+
+	for _, vs := range guild.VoiceStates {
+		if vs.ChannelID != dgv.ChannelID || vs.UserID == vi.session.State.User.ID {
+			continue
+		}
+
+		member, err := vi.session.State.Member(guild.ID, vs.UserID)
+		if err == nil && member.User != nil && member.User.Bot {
+			continue
+		}
+		listeners[vs.UserID] = true
+	}
+	return listeners
+}
+
+//countVotes returns the number of votes of the users that are listening.
+func countVotes(votes, listeners map[string]bool) int {
+	count := 0
+	for userID := range votes {
+		if listeners[userID] {
+			count++
+		}
+	}
+	return count
+}
+
+//votesNeeded returns the number of votes that is needed to skip
+//a song for the given number of listeners.
+func votesNeeded(listeners int, ratio float64) int {
+	if ratio <= 0 || ratio > 1 {
+		ratio = defaultSkipVoteRatio
+	}
+
+	needed := int(math.Ceil(float64(listeners) * ratio))
+	if needed < 1 {
+		needed = 1
+	}
+	return needed
+}
@@ -0,0 +1,35 @@
+package bot
+
+import "testing"
+
+func TestVotesNeeded(t *testing.T) {
+	tables := []struct {
+		listeners int
+		ratio     float64
+		needed    int
+	}{
+		{1, 0.5, 1},
+		{3, 0.5, 2},
+		{4, 0.5, 2},
+		{4, 0.75, 3},
+		{5, 1, 5},
+		{0, 0.5, 1},
+		{4, 0, 2},   //default ratio
+		{4, 1.5, 2}, //default ratio
+	}
+
+	for _, table := range tables {
+		if needed := votesNeeded(table.listeners, table.ratio); needed != table.needed {
+			t.Errorf("%d listeners with %v ratio: got %d, want %d.", table.listeners, table.ratio, needed, table.needed)
+		}
+	}
+}
+
+func TestCountVotes(t *testing.T) {
+	votes := map[string]bool{"a": true, "b": true, "c": true}
+	listeners := map[string]bool{"a": true, "c": true, "d": true}
+
+	if count := countVotes(votes, listeners); count != 2 {
+		t.Errorf("got %d votes, want 2.", count)
+	}
+}
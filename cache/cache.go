@@ -0,0 +1,225 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+//Kind is the type of the cached data. Each kind has its own TTL.
+type Kind string
+
+const (
+	YoutubeQuery Kind = "youtube-query" //search query to video IDs
+	YoutubeVideo Kind = "youtube-video" //video ID to title, duration and thumbnail
+	SpotifyTrack Kind = "spotify-track" //Spotify track ID to track metadata
+	SpotifyAlbum Kind = "spotify-album" //Spotify album ID to album tracks
+
+	//changes are written to the file after this delay,
+	//so a playlist doesn't cause a write for each track.
+	flushDelay = 5 * time.Second
+)
+
+//DefaultTTLs are used for the kinds that don't have a configured TTL.
+var DefaultTTLs = map[Kind]time.Duration{
+	YoutubeQuery: 24 * time.Hour,
+	YoutubeVideo: 7 * 24 * time.Hour,
+	SpotifyTrack: 30 * 24 * time.Hour,
+	SpotifyAlbum: 7 * 24 * time.Hour,
+}
+
+//Cache is a key value store that is persisted to a JSON file. Entries
+//expire after the TTL of their kind. A nil *Cache is valid and caches
+//nothing, so that caching can be disabled.
+type Cache struct {
+	mu         sync.Mutex
+	path       string
+	ttls       map[Kind]time.Duration
+	entries    map[string]entry
+	flushTimer *time.Timer
+	now        func() time.Time
+}
+
+type entry struct {
+	Value     json.RawMessage `json:"value"`
+	ExpiresAt time.Time       `json:"expiresAt"`
+}
+
+//Open loads the cache in the given file. If file doesn't exist, an empty
+//cache is created. If path is empty, cache is kept only in memory. TTLs
+//that are not in the given map are taken from DefaultTTLs.
+func Open(path string, ttls map[Kind]time.Duration) (*Cache, error) {
+	c := &Cache{
+		path:    path,
+		ttls:    map[Kind]time.Duration{},
+		entries: map[string]entry{},
+		now:     time.Now,
+	}
+
+	for kind, ttl := range DefaultTTLs {
+		c.ttls[kind] = ttl
+	}
+	for kind, ttl := range ttls {
+		c.ttls[kind] = ttl
+	}
+
+	if path == "" {
+		return c, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error while reading cache file: %v", err)
+	}
+
+	err = json.Unmarshal(data, &c.entries)
+	if err != nil {
+		//a broken cache shouldn't stop the bot, it is filled again.
+		log.Printf("Error while parsing cache file %s, starting with an empty cache: %v\n", path, err)
+		c.entries = map[string]entry{}
+	}
+
+	c.removeExpired()
+	return c, nil
+}
+
+//ParseTTLs parses TTL settings like {"youtube-query": "12h"}.
+func ParseTTLs(values map[string]string) (map[Kind]time.Duration, error) {
+	ttls := map[Kind]time.Duration{}
+	for name, value := range values {
+		kind := Kind(name)
+		if _, ok := DefaultTTLs[kind]; !ok {
+			return nil, fmt.Errorf("Unknown cache kind \"%s\"", name)
+		}
+
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("Error while parsing TTL of %s: %v", name, err)
+		}
+		ttls[kind] = ttl
+	}
+	return ttls, nil
+}
+
+//Get decodes the value of the given key to v. Returns false if
+//there is no such key or it is expired.
+func (c *Cache) Get(kind Kind, key string, v interface{}) bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	e, ok := c.entries[entryKey(kind, key)]
+	c.mu.Unlock()
+
+	if !ok || !c.now().Before(e.ExpiresAt) {
+		return false
+	}
+
+	err := json.Unmarshal(e.Value, v)
+	if err != nil {
+		log.Printf("Error while decoding cached %s %s: %v\n", kind, key, err)
+		return false
+	}
+	return true
+}
+
+//Set stores v with the given key until the TTL of its kind passes.
+func (c *Cache) Set(kind Kind, key string, v interface{}) {
+	if c == nil {
+		return
+	}
+
+	ttl := c.ttls[kind]
+	if ttl <= 0 {
+		return
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Error while encoding %s %s to cache: %v\n", kind, key, err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[entryKey(kind, key)] = entry{
+		Value:     data,
+		ExpiresAt: c.now().Add(ttl),
+	}
+
+	if c.path != "" && c.flushTimer == nil {
+		c.flushTimer = time.AfterFunc(flushDelay, func() {
+			err := c.Flush()
+			if err != nil {
+				log.Println(err)
+			}
+		})
+	}
+}
+
+//Flush writes the cache to its file. The file is replaced
+//atomically, so a crash doesn't leave a half written cache.
+func (c *Cache) Flush() error {
+	if c == nil || c.path == "" {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.flushTimer != nil {
+		c.flushTimer.Stop()
+		c.flushTimer = nil
+	}
+
+	c.removeExpired()
+
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return fmt.Errorf("Error while encoding cache: %v", err)
+	}
+
+	tempFile, err := ioutil.TempFile(filepath.Dir(c.path), filepath.Base(c.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("Error while creating cache file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	_, err = tempFile.Write(data)
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("Error while writing cache file: %v", err)
+	}
+
+	err = os.Rename(tempFile.Name(), c.path)
+	if err != nil {
+		return fmt.Errorf("Error while writing cache file: %v", err)
+	}
+	return nil
+}
+
+//removeExpired deletes expired entries. Caller must hold the lock.
+func (c *Cache) removeExpired() {
+	now := c.now()
+	for key, e := range c.entries {
+		if !now.Before(e.ExpiresAt) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+func entryKey(kind Kind, key string) string {
+	return string(kind) + ":" + key
+}
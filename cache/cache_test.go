@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testTrack struct {
+	Name     string
+	Duration time.Duration
+}
+
+func TestCacheGetSet(t *testing.T) {
+	now := time.Date(2020, 5, 10, 12, 0, 0, 0, time.UTC)
+	c, err := Open("", map[Kind]time.Duration{YoutubeQuery: time.Hour})
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	c.now = func() time.Time { return now }
+
+	c.Set(YoutubeQuery, "billie jean", "Zi_XLOBDo_Y")
+	c.Set(SpotifyTrack, "7J1uxwnxfQLu4APicE5Rnj", testTrack{"Billie Jean", 294 * time.Second})
+
+	tables := []struct {
+		after time.Duration
+		kind  Kind
+		key   string
+		found bool
+	}{
+		{0, YoutubeQuery, "billie jean", true},
+		{0, YoutubeQuery, "beat it", false},
+		{0, YoutubeVideo, "billie jean", false},
+		{59 * time.Minute, YoutubeQuery, "billie jean", true},
+		{time.Hour, YoutubeQuery, "billie jean", false},
+		{time.Hour, SpotifyTrack, "7J1uxwnxfQLu4APicE5Rnj", true},
+		{DefaultTTLs[SpotifyTrack], SpotifyTrack, "7J1uxwnxfQLu4APicE5Rnj", false},
+	}
+
+	start := now
+	for _, table := range tables {
+		now = start.Add(table.after)
+
+		var value interface{}
+		found := c.Get(table.kind, table.key, &value)
+		if found != table.found {
+			t.Errorf("%s %s after %s: got found %t, want %t.", table.kind, table.key, table.after, found, table.found)
+		}
+	}
+}
+
+func TestCachePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	c, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+
+	want := testTrack{"Billie Jean", 294 * time.Second}
+	c.Set(SpotifyTrack, "7J1uxwnxfQLu4APicE5Rnj", want)
+	err = c.Flush()
+	if err != nil {
+		t.Fatalf("Got error while flushing: %v", err)
+	}
+
+	reopened, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Got error while reopening: %v", err)
+	}
+
+	var got testTrack
+	if !reopened.Get(SpotifyTrack, "7J1uxwnxfQLu4APicE5Rnj", &got) || got != want {
+		t.Errorf("Got %+v from reopened cache, want %+v.", got, want)
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	var c *Cache
+	c.Set(YoutubeQuery, "key", "value")
+
+	var value string
+	if c.Get(YoutubeQuery, "key", &value) {
+		t.Errorf("Nil cache returned a value.")
+	}
+
+	if err := c.Flush(); err != nil {
+		t.Errorf("Got error from nil cache: %v", err)
+	}
+}
+
+func TestParseTTLs(t *testing.T) {
+	tables := []struct {
+		values map[string]string
+		isErr  bool
+	}{
+		{map[string]string{"youtube-query": "12h", "spotify-album": "30m"}, false},
+		{map[string]string{"youtube-query": "tomorrow"}, true},
+		{map[string]string{"reddit-post": "1h"}, true},
+		{nil, false},
+	}
+
+	for _, table := range tables {
+		_, err := ParseTTLs(table.values)
+		if (err != nil) != table.isErr {
+			t.Errorf("%v: got error %v, want error: %t.", table.values, err, table.isErr)
+		}
+	}
+}
@@ -1,11 +1,50 @@
 package config
 
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	//DefaultPlaylistsPath is used if the playlists file is not configured.
+	DefaultPlaylistsPath = "playlists.json"
+	//DefaultHistoryPath is used if the history database is not configured.
+	DefaultHistoryPath = "history.db"
+	//DefaultPolicy is the key of the policy that is used for the
+	//guilds that don't have their own policy.
+	DefaultPolicy = "default"
+	//DefaultAPIAddress is used if the API address is not configured.
+	DefaultAPIAddress = "127.0.0.1:8484"
+)
+
 type Config struct {
 	Spotify    SpotifyConfig    `json:"spotify"`
 	Youtube    YoutubeConfig    `json:"youtube"`
 	Discord    DiscordConfig    `json:"discord"`
 	PlaylistID PlaylistIDConfig `json:"playlistIDs"`
 	MusicDir   MusicDirectory   `json:"musicDirectory"`
+	Cache      CacheConfig      `json:"cache"`
+	Playlists  PlaylistsConfig  `json:"playlists"`
+	History    HistoryConfig    `json:"history"`
+	Player     PlayerConfig     `json:"player"`
+	//Permissions are the command permission rules by guild IDs.
+	Permissions map[string]GuildPermissions `json:"permissions"`
+	//Policies are the content policies by guild IDs, DefaultPolicy
+	//key is used for the guilds that don't have a policy.
+	Policies map[string]PolicyConfig `json:"policies"`
+	//API is the HTTP API that controls the player.
+	API APIConfig `json:"api"`
+
+	//path is the file that config is loaded from and saved to.
+	path string
+	//mu guards the fields that are changed at runtime.
+	mu sync.RWMutex
 }
 
 type SpotifyConfig struct {
@@ -17,16 +56,278 @@ type YoutubeConfig struct {
 	ClientID       string `json:"clientID"`
 	ClientSecretID string `json:"clientSecretID"`
 	ApiKey         string `json:"apiKey"`
+	//SearchStrategy is one of "api", "extractor" or "fallback".
+	SearchStrategy string `json:"searchStrategy"`
+	//QuotaBudget is the number of Data API units that can be used in a day.
+	QuotaBudget int `json:"quotaBudget"`
+	//SearchReserve is the number of units that searches leave unused for
+	//video details and playlist pages.
+	SearchReserve int `json:"searchReserve"`
+	//RelevanceLanguage is the ISO 639-1 code of the language that searches prefer.
+	RelevanceLanguage string `json:"relevanceLanguage"`
 }
 
 type DiscordConfig struct {
 	Token string `json:"token"`
 }
 
-type PlaylistIDConfig struct {
-	Shame string `json:"shame"`
+//PlaylistIDConfig maps playlist aliases to Youtube or Spotify
+//playlist links or IDs.
+type PlaylistIDConfig map[string]string
+
+type CacheConfig struct {
+	//Path is the cache file, empty path keeps the cache only in memory.
+	Path string `json:"path"`
+	//TTLs are durations like "12h" by cache kinds like "youtube-query".
+	TTLs map[string]string `json:"ttls"`
 }
 
 type MusicDirectory struct {
-	DownloadPath string `json"downlaodPath"`
+	DownloadPath string `json:"downloadPath"`
+	//LibraryPath is the directory that local playlist entries are played
+	//from. Local entries are not played if it is empty.
+	LibraryPath string `json:"libraryPath"`
+}
+
+type PlaylistsConfig struct {
+	//Path is the file that saved playlists are kept in.
+	Path string `json:"path"`
+}
+
+type HistoryConfig struct {
+	//Path is the database file that play history is kept in.
+	Path string `json:"path"`
+}
+
+type PlayerConfig struct {
+	//SkipVoteRatio is the share of the listeners that have to vote
+	//to skip a song, like 0.5. Default is used if it is not in (0, 1].
+	SkipVoteRatio float64 `json:"skipVoteRatio"`
+	//DJRole is the name or ID of the role whose members can skip
+	//songs without voting.
+	DJRole string `json:"djRole"`
+	//FairQueue plays the songs of different requesters in turn
+	//instead of the order they are added.
+	FairQueue bool `json:"fairQueue"`
+	//MaxUserEntries is the number of songs that a user can have
+	//in the queue, 0 is unlimited.
+	MaxUserEntries int `json:"maxUserEntries"`
+	//MaxUserDuration is the total length of the songs that a user
+	//can have in the queue like "1h", empty is unlimited.
+	MaxUserDuration string `json:"maxUserDuration"`
+	//EmptyTimeout is how long the song is paused before bot leaves
+	//when everyone leaves its voice channel like "2m".
+	EmptyTimeout string `json:"emptyTimeout"`
+	//IdleTimeout is how long bot stays in the voice channel after
+	//the play queue ends like "5m".
+	IdleTimeout string `json:"idleTimeout"`
+}
+
+//APIConfig is the HTTP API that controls the player.
+type APIConfig struct {
+	//Enabled starts the API server.
+	Enabled bool `json:"enabled"`
+	//Address is the address that the server listens on like
+	//"127.0.0.1:8484". It is bound to localhost by default.
+	Address string `json:"address"`
+	//Token is sent by the clients as "Authorization: Bearer <token>".
+	Token string `json:"token"`
+	//PublicURL is the address of the dashboard in the login links like
+	//"https://music.example.com", default is "http://" + Address.
+	PublicURL string `json:"publicUrl"`
+}
+
+//PolicyConfig is the content policy of a guild. Tracks that
+//don't obey it are not added to the queue.
+type PolicyConfig struct {
+	//MaxDuration is the max length of a track like "15m", empty is unlimited.
+	MaxDuration string `json:"maxDuration"`
+	//BlockedKeywords are not allowed in the titles, artists and channels of tracks.
+	BlockedKeywords []string `json:"blockedKeywords"`
+	//BlockedChannels are Youtube channel IDs or names.
+	BlockedChannels []string `json:"blockedChannels"`
+	//BlockedVideos are Youtube video IDs.
+	BlockedVideos []string `json:"blockedVideos"`
+	//BlockExplicit rejects the tracks that are explicit on Spotify
+	//or age restricted on Youtube.
+	BlockExplicit bool `json:"blockExplicit"`
+	//AuditChannel is the ID of the text channel that rejections are logged to.
+	AuditChannel string `json:"auditChannel"`
+}
+
+//Load reads the config in the given JSON file.
+func Load(path string) (*Config, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error while getting config path: %v", err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error while reading config: %v", err)
+	}
+
+	cfg := &Config{path: path}
+	err = json.Unmarshal(data, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("Error while parsing config: %v", err)
+	}
+
+	if cfg.PlaylistID == nil {
+		cfg.PlaylistID = PlaylistIDConfig{}
+	}
+	cfg.PlaylistID = lowerAliases(cfg.PlaylistID)
+	if cfg.Playlists.Path == "" {
+		cfg.Playlists.Path = DefaultPlaylistsPath
+	}
+	if cfg.History.Path == "" {
+		cfg.History.Path = DefaultHistoryPath
+	}
+	if cfg.API.Address == "" {
+		cfg.API.Address = DefaultAPIAddress
+	}
+	return cfg, nil
+}
+
+//lowerAliases returns the aliases with lower case names. Aliases are looked
+//up in lower case, so aliases written to the file by hand are found too.
+func lowerAliases(aliases PlaylistIDConfig) PlaylistIDConfig {
+	lowered := PlaylistIDConfig{}
+	for alias, target := range aliases {
+		name := strings.ToLower(alias)
+		if _, ok := lowered[name]; ok && name != alias {
+			//alias that is already lower case wins.
+			continue
+		}
+		lowered[name] = target
+	}
+	return lowered
+}
+
+//Path returns the file that config is loaded from.
+func (c *Config) Path() string {
+	return c.path
+}
+
+//save writes the config to its file. File is replaced atomically,
+//so a crash doesn't leave a half written config. Caller must hold the lock.
+func (c *Config) save() error {
+	if c.path == "" {
+		return fmt.Errorf("Config is not loaded from a file, it can't be saved.")
+	}
+
+	data, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return fmt.Errorf("Error while encoding config: %v", err)
+	}
+
+	mode := os.FileMode(0600)
+	if info, err := os.Stat(c.path); err == nil {
+		mode = info.Mode()
+	}
+
+	tempFile, err := ioutil.TempFile(filepath.Dir(c.path), filepath.Base(c.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("Error while saving config: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	_, err = tempFile.Write(append(data, '\n'))
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tempFile.Name(), mode)
+	}
+	if err != nil {
+		return fmt.Errorf("Error while saving config: %v", err)
+	}
+
+	err = os.Rename(tempFile.Name(), c.path)
+	if err != nil {
+		return fmt.Errorf("Error while saving config: %v", err)
+	}
+	return nil
+}
+
+//PlaylistAlias returns the playlist link or ID of the given alias.
+func (c *Config) PlaylistAlias(alias string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	target, ok := c.PlaylistID[alias]
+	return target, ok
+}
+
+//PlaylistAliases returns the names of all aliases in alphabetical order.
+func (c *Config) PlaylistAliases() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	aliases := []string{}
+	for alias := range c.PlaylistID {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
+//SetPlaylistAlias adds or replaces the given alias and saves the config.
+func (c *Config) SetPlaylistAlias(alias, target string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.PlaylistID == nil {
+		c.PlaylistID = PlaylistIDConfig{}
+	}
+
+	old, existed := c.PlaylistID[alias]
+	c.PlaylistID[alias] = target
+
+	err := c.save()
+	if err != nil {
+		//keep the memory and the file same.
+		if existed {
+			c.PlaylistID[alias] = old
+		} else {
+			delete(c.PlaylistID, alias)
+		}
+		return err
+	}
+	return nil
+}
+
+//RemovePlaylistAlias removes the given alias and saves the config.
+//Returns false if there is no such alias.
+func (c *Config) RemovePlaylistAlias(alias string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	old, ok := c.PlaylistID[alias]
+	if !ok {
+		return false, nil
+	}
+
+	delete(c.PlaylistID, alias)
+
+	err := c.save()
+	if err != nil {
+		c.PlaylistID[alias] = old
+		return true, err
+	}
+	return true, nil
+}
+
+//GuildPolicy returns the content policy of the given guild,
+//or the default policy if the guild doesn't have one.
+func (c *Config) GuildPolicy(guildID string) (PolicyConfig, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if policy, ok := c.Policies[guildID]; ok {
+		return policy, true
+	}
+
+	policy, ok := c.Policies[DefaultPolicy]
+	return policy, ok
 }
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestConfig(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{"youtube": {"apiKey": "key"}, "playlistIDs": {"chill": "76tzi26o8O920CYAvVbeYO"}}`
+	err := ioutil.WriteFile(path, []byte(data), 0640)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPlaylistAliasesArePersisted(t *testing.T) {
+	path := writeTestConfig(t)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+
+	err = cfg.SetPlaylistAlias("rock", "PLwiyx1dc3P2JR9N8gQaQN_BCvlSlap7re")
+	if err != nil {
+		t.Fatalf("Got error while adding alias: %v", err)
+	}
+
+	ok, err := cfg.RemovePlaylistAlias("chill")
+	if !ok || err != nil {
+		t.Fatalf("Got %t, %v while removing alias, want true, nil.", ok, err)
+	}
+
+	ok, _ = cfg.RemovePlaylistAlias("missing")
+	if ok {
+		t.Errorf("Removing a missing alias returned true.")
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Got error while loading saved config: %v", err)
+	}
+
+	aliases := loaded.PlaylistAliases()
+	if len(aliases) != 1 || aliases[0] != "rock" {
+		t.Errorf("Got aliases %v, want [rock].", aliases)
+	}
+	if target, _ := loaded.PlaylistAlias("rock"); target != "PLwiyx1dc3P2JR9N8gQaQN_BCvlSlap7re" {
+		t.Errorf("Got target %s for rock.", target)
+	}
+	if loaded.Youtube.ApiKey != "key" {
+		t.Errorf("Other settings are lost while saving, got api key %q.", loaded.Youtube.ApiKey)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("Config file mode is not kept: %v, %v", info, err)
+	}
+}
+
+func TestPlaylistAliasesAreLowered(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{"playlistIDs": {"MyMix": "76tzi26o8O920CYAvVbeYO", "rock": "PLrock", "Rock": "PLRock"}}`
+	err := ioutil.WriteFile(path, []byte(data), 0640)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+
+	tables := []struct {
+		alias  string
+		target string
+	}{
+		{"mymix", "76tzi26o8O920CYAvVbeYO"},
+		{"rock", "PLrock"},
+	}
+
+	for _, table := range tables {
+		if target, ok := cfg.PlaylistAlias(table.alias); !ok || target != table.target {
+			t.Errorf("Got target %q, %t for %s, want %q.", target, ok, table.alias, table.target)
+		}
+	}
+}
+
+func TestDownloadPathIsPersisted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{"musicDirectory": {"downloadPath": "/music"}}`
+	err := ioutil.WriteFile(path, []byte(data), 0640)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	if cfg.MusicDir.DownloadPath != "/music" {
+		t.Fatalf("Got download path %q, want /music.", cfg.MusicDir.DownloadPath)
+	}
+
+	err = cfg.SetPlaylistAlias("rock", "PLrock")
+	if err != nil {
+		t.Fatalf("Got error while adding alias: %v", err)
+	}
+
+	saved, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(saved), `"downloadPath": "/music"`) {
+		t.Errorf("Download path is not saved as downloadPath:\n%s", saved)
+	}
+}
+
+func TestCommandRulesArePersisted(t *testing.T) {
+	path := writeTestConfig(t)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+
+	rule := PermissionRule{Roles: []string{DJRoleRule, "123"}, Users: []string{"456"}}
+	err = cfg.SetCommandRule("guild", "stop", rule)
+	if err != nil {
+		t.Fatalf("Got error while setting rule: %v", err)
+	}
+	err = cfg.SetCommandRule("guild", "clear", PermissionRule{})
+	if err != nil {
+		t.Fatalf("Got error while setting rule: %v", err)
+	}
+
+	ok, err := cfg.RemoveCommandRule("guild", "clear")
+	if !ok || err != nil {
+		t.Fatalf("Got %t, %v while removing rule, want true, nil.", ok, err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Got error while loading saved config: %v", err)
+	}
+
+	commands := loaded.RuleCommands("guild")
+	if len(commands) != 1 || commands[0] != "stop" {
+		t.Errorf("Got commands %v, want [stop].", commands)
+	}
+
+	loadedRule, ok := loaded.CommandRule("guild", "stop")
+	if !ok || len(loadedRule.Roles) != 2 || loadedRule.Roles[0] != DJRoleRule || len(loadedRule.Users) != 1 {
+		t.Errorf("Got rule %+v, want %+v.", loadedRule, rule)
+	}
+
+	if _, ok := loaded.CommandRule("other", "stop"); ok {
+		t.Errorf("Rule of another guild is found.")
+	}
+}
@@ -0,0 +1,90 @@
+package config
+
+import "sort"
+
+//DJRoleRule is the role of a permission rule that means
+//the DJ role in the player config.
+const DJRoleRule = "dj"
+
+//PermissionRule is the roles and users that can use a command.
+//Roles are role IDs or DJRoleRule, users are user IDs.
+type PermissionRule struct {
+	Roles []string `json:"roles,omitempty"`
+	Users []string `json:"users,omitempty"`
+}
+
+//GuildPermissions maps command names, without "!", to their rules.
+type GuildPermissions map[string]PermissionRule
+
+//CommandRule returns the permission rule of the given command in the given guild.
+func (c *Config) CommandRule(guildID, command string) (PermissionRule, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	rule, ok := c.Permissions[guildID][command]
+	return rule, ok
+}
+
+//RuleCommands returns the commands that have a rule in the
+//given guild in alphabetical order.
+func (c *Config) RuleCommands(guildID string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	commands := []string{}
+	for command := range c.Permissions[guildID] {
+		commands = append(commands, command)
+	}
+	sort.Strings(commands)
+	return commands
+}
+
+//SetCommandRule adds or replaces the rule of the given command
+//in the given guild and saves the config.
+func (c *Config) SetCommandRule(guildID, command string, rule PermissionRule) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.Permissions == nil {
+		c.Permissions = map[string]GuildPermissions{}
+	}
+	if c.Permissions[guildID] == nil {
+		c.Permissions[guildID] = GuildPermissions{}
+	}
+
+	old, existed := c.Permissions[guildID][command]
+	c.Permissions[guildID][command] = rule
+
+	err := c.save()
+	if err != nil {
+		//keep the memory and the file same.
+		if existed {
+			c.Permissions[guildID][command] = old
+		} else {
+			delete(c.Permissions[guildID], command)
+		}
+		return err
+	}
+	return nil
+}
+
+//RemoveCommandRule removes the rule of the given command in the given
+//guild and saves the config. Returns false if there is no such rule.
+func (c *Config) RemoveCommandRule(guildID, command string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	old, ok := c.Permissions[guildID][command]
+	if !ok {
+		return false, nil
+	}
+
+	delete(c.Permissions[guildID], command)
+
+	err := c.save()
+	if err != nil {
+		c.Permissions[guildID][command] = old
+		return true, err
+	}
+	return true, nil
+}
@@ -1,13 +1,16 @@
 module github.com/hemreari/feanor-dcbot
 
-go 1.13
+go 1.16
 
 require (
 	github.com/bwmarrin/discordgo v0.20.2
+	github.com/gorilla/websocket v1.4.0
 	github.com/hemreari/go-datastructures v1.0.51
 	github.com/stretchr/testify v1.5.0 // indirect
+	go.etcd.io/bbolt v1.3.5
 	golang.org/x/oauth2 v0.0.0-20200107190931-bf48bf16ab8d
 	golang.org/x/sync v0.0.0-20190423024810-112230192c58
+	golang.org/x/sys v0.10.0 // indirect
 	google.golang.org/api v0.17.0
 	layeh.com/gopus v0.0.0-20161224163843-0ebf989153aa
 )
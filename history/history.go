@@ -0,0 +1,223 @@
+package history
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var playsBucket = []byte("plays")
+
+//Record is a track that is played.
+type Record struct {
+	GuildID       string        `json:"guildID"`
+	ChannelID     string        `json:"channelID"` //voice channel
+	RequesterID   string        `json:"requesterID"`
+	RequesterName string        `json:"requesterName"`
+	Source        string        `json:"source"`   //see playlist.Source
+	SourceID      string        `json:"sourceID"` //video ID, track ID, path, URL or query by source
+	Title         string        `json:"title"`
+	Artist        string        `json:"artist,omitempty"`
+	StartedAt     time.Time     `json:"startedAt"`
+	EndedAt       time.Time     `json:"endedAt"`
+	Played        time.Duration `json:"played"` //how long it is played, less than its length if skipped
+}
+
+//TrackCount is a track with how many times and how long it is played.
+type TrackCount struct {
+	Source   string
+	SourceID string
+	Title    string
+	Artist   string
+	Plays    int
+	Played   time.Duration
+}
+
+//UserStats is the listening statistics of a user in a guild.
+type UserStats struct {
+	Plays     int
+	Played    time.Duration
+	FirstPlay time.Time
+	LastPlay  time.Time
+	TopTracks []TrackCount
+}
+
+//DB keeps the play history in a bbolt database file.
+type DB struct {
+	db *bolt.DB
+}
+
+//Open opens the history database in the given file. File is created
+//if it doesn't exist.
+func Open(path string) (*DB, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("Error while opening history database: %v", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(playsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("Error while creating history bucket: %v", err)
+	}
+
+	return &DB{db: db}, nil
+}
+
+//Close closes the database file.
+func (h *DB) Close() error {
+	return h.db.Close()
+}
+
+//Add stores the given record. Records are kept in the order they are added.
+func (h *DB) Add(record Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("Error while encoding history record: %v", err)
+	}
+
+	err = h.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(playsBucket)
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, id)
+		return bucket.Put(key, data)
+	})
+	if err != nil {
+		return fmt.Errorf("Error while adding history record: %v", err)
+	}
+	return nil
+}
+
+//Recent returns the last n records of the given guild, newest first.
+func (h *DB) Recent(guildID string, n int) ([]Record, error) {
+	records := []Record{}
+	err := h.eachNewest(func(record Record) bool {
+		if record.GuildID == guildID {
+			records = append(records, record)
+		}
+		return len(records) < n
+	})
+	return records, err
+}
+
+//Top returns the n most played tracks of the given guild since the
+//given time. Zero time means all of the history.
+func (h *DB) Top(guildID string, since time.Time, n int) ([]TrackCount, error) {
+	counts := map[string]*TrackCount{}
+	err := h.eachNewest(func(record Record) bool {
+		if record.StartedAt.Before(since) {
+			return false
+		}
+		if record.GuildID == guildID {
+			countTrack(counts, record)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return topTracks(counts, n), nil
+}
+
+//Stats returns the listening statistics of the given user in the given guild.
+func (h *DB) Stats(guildID, userID string, topCount int) (*UserStats, error) {
+	stats := &UserStats{}
+	counts := map[string]*TrackCount{}
+	err := h.eachNewest(func(record Record) bool {
+		if record.GuildID != guildID || record.RequesterID != userID {
+			return true
+		}
+
+		if stats.Plays == 0 {
+			stats.LastPlay = record.StartedAt
+		}
+		stats.FirstPlay = record.StartedAt
+		stats.Plays++
+		stats.Played += record.Played
+		countTrack(counts, record)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stats.TopTracks = topTracks(counts, topCount)
+	return stats, nil
+}
+
+//eachNewest calls f with the records from the newest to the oldest
+//until f returns false.
+func (h *DB) eachNewest(f func(record Record) bool) error {
+	err := h.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(playsBucket).Cursor()
+		for key, value := cursor.Last(); key != nil; key, value = cursor.Prev() {
+			var record Record
+			err := json.Unmarshal(value, &record)
+			if err != nil {
+				return err
+			}
+
+			if !f(record) {
+				return nil
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("Error while reading history: %v", err)
+	}
+	return nil
+}
+
+func countTrack(counts map[string]*TrackCount, record Record) {
+	key := record.Source + ":" + record.SourceID
+	count, ok := counts[key]
+	if !ok {
+		count = &TrackCount{
+			Source:   record.Source,
+			SourceID: record.SourceID,
+			Title:    record.Title,
+			Artist:   record.Artist,
+		}
+		counts[key] = count
+	}
+
+	count.Plays++
+	count.Played += record.Played
+}
+
+//topTracks returns the n most played tracks. Tracks that are played the
+//same times are ordered by how long they are played.
+func topTracks(counts map[string]*TrackCount, n int) []TrackCount {
+	tracks := []TrackCount{}
+	for _, count := range counts {
+		tracks = append(tracks, *count)
+	}
+
+	sort.Slice(tracks, func(i, j int) bool {
+		if tracks[i].Plays != tracks[j].Plays {
+			return tracks[i].Plays > tracks[j].Plays
+		}
+		if tracks[i].Played != tracks[j].Played {
+			return tracks[i].Played > tracks[j].Played
+		}
+		return tracks[i].Title < tracks[j].Title
+	})
+
+	if len(tracks) > n {
+		tracks = tracks[:n]
+	}
+	return tracks
+}
@@ -0,0 +1,77 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	h, err := Open(path)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+
+	now := time.Now()
+	records := []Record{
+		{GuildID: "g1", RequesterID: "u1", Source: "youtube", SourceID: "a", Title: "A", StartedAt: now.Add(-40 * 24 * time.Hour), Played: time.Minute},
+		{GuildID: "g1", RequesterID: "u1", Source: "youtube", SourceID: "a", Title: "A", StartedAt: now.Add(-30 * 24 * time.Hour), Played: time.Minute},
+		{GuildID: "g1", RequesterID: "u2", Source: "youtube", SourceID: "b", Title: "B", StartedAt: now.Add(-2 * time.Hour), Played: 3 * time.Minute},
+		{GuildID: "g2", RequesterID: "u1", Source: "youtube", SourceID: "b", Title: "B", StartedAt: now.Add(-time.Hour), Played: time.Minute},
+		{GuildID: "g1", RequesterID: "u1", Source: "spotify", SourceID: "c", Title: "C", StartedAt: now.Add(-time.Minute), Played: 2 * time.Minute},
+	}
+	for _, record := range records {
+		err := h.Add(record)
+		if err != nil {
+			t.Fatalf("Got error while adding: %v", err)
+		}
+	}
+
+	//records must be kept after reopening.
+	h.Close()
+	h, err = Open(path)
+	if err != nil {
+		t.Fatalf("Got error while reopening: %v", err)
+	}
+	defer h.Close()
+
+	recent, err := h.Recent("g1", 2)
+	if err != nil || len(recent) != 2 || recent[0].SourceID != "c" || recent[1].SourceID != "b" {
+		t.Errorf("Got recent %+v, %v, want c and b.", recent, err)
+	}
+
+	tables := []struct {
+		since time.Time
+		ids   []string
+		plays []int
+	}{
+		{now.Add(-7 * 24 * time.Hour), []string{"b", "c"}, []int{1, 1}},
+		{time.Time{}, []string{"a", "b", "c"}, []int{2, 1, 1}},
+	}
+	for _, table := range tables {
+		top, err := h.Top("g1", table.since, 10)
+		if err != nil {
+			t.Fatalf("Got error: %v", err)
+		}
+		if len(top) != len(table.ids) {
+			t.Errorf("Since %s: got %+v, want %v.", table.since, top, table.ids)
+			continue
+		}
+		for i := range top {
+			if top[i].SourceID != table.ids[i] || top[i].Plays != table.plays[i] {
+				t.Errorf("Since %s: got %+v, want %v %v.", table.since, top, table.ids, table.plays)
+				break
+			}
+		}
+	}
+
+	stats, err := h.Stats("g1", "u1", 1)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	if stats.Plays != 3 || stats.Played != 4*time.Minute || !stats.FirstPlay.Equal(records[0].StartedAt) ||
+		!stats.LastPlay.Equal(records[4].StartedAt) || len(stats.TopTracks) != 1 || stats.TopTracks[0].SourceID != "a" {
+		t.Errorf("Got stats %+v.", stats)
+	}
+}
@@ -1,35 +1,17 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
-	"os"
-	"path/filepath"
 
 	"github.com/hemreari/feanor-dcbot/bot"
+	"github.com/hemreari/feanor-dcbot/cache"
 	"github.com/hemreari/feanor-dcbot/config"
+	"github.com/hemreari/feanor-dcbot/spotify"
 	"github.com/hemreari/feanor-dcbot/youtube"
 )
 
-var cfg config.Config
-
-func readConfig(cfg *config.Config, configFileName string) {
-	configFileName, _ = filepath.Abs(configFileName)
-	log.Printf("Loading config: %v", configFileName)
-
-	configFile, err := os.Open(configFileName)
-	if err != nil {
-		log.Fatal("File error: ", err.Error())
-	}
-	defer configFile.Close()
-	jsonParser := json.NewDecoder(configFile)
-	if err := jsonParser.Decode(&cfg); err != nil {
-		log.Fatal("Config error: ", err.Error())
-	}
-}
-
 func banner() {
 	b, err := ioutil.ReadFile("asciiart.txt")
 	if err != nil {
@@ -40,13 +22,45 @@ func banner() {
 
 func main() {
 	banner()
-	readConfig(&cfg, "config.json")
+	cfg, err := config.Load("config.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Loaded config: %v", cfg.Path())
 	log.Println("Starting Feanor.")
 
 	//make api connections
-	youtubeAPI := youtube.NewYoutubeAPI(cfg.Youtube.ApiKey)
+	searchStrategy, err := youtube.ParseSearchStrategy(cfg.Youtube.SearchStrategy)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	youtubeAPI, err := youtube.NewYoutubeAPI(cfg.Youtube.ApiKey, searchStrategy, cfg.Youtube.QuotaBudget, cfg.Youtube.SearchReserve)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	spotifyAPI := spotify.NewSpotifyAPI(cfg.Spotify.ClientID, cfg.Spotify.ClientSecretID)
+
+	cacheTTLs, err := cache.ParseTTLs(cfg.Cache.TTLs)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	lookupCache, err := cache.Open(cfg.Cache.Path, cacheTTLs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	youtubeAPI.Cache = lookupCache
+	youtubeAPI.RelevanceLanguage = cfg.Youtube.RelevanceLanguage
+	spotifyAPI.Cache = lookupCache
+
+	err = bot.InitBot(cfg.Discord.Token, youtubeAPI, spotifyAPI, cfg)
+	if err != nil {
+		log.Println(err)
+	}
 
-	err := bot.InitBot(cfg.Discord.Token, youtubeAPI, &cfg)
+	err = lookupCache.Flush()
 	if err != nil {
 		log.Println(err)
 	}
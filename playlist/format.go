@@ -0,0 +1,266 @@
+package playlist
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hemreari/feanor-dcbot/stream"
+	"github.com/hemreari/feanor-dcbot/util"
+)
+
+const (
+	youtubeVideoUrl  = "https://www.youtube.com/watch?v="
+	spotifyTrackUrl  = "https://open.spotify.com/track/"
+	m3uHeader        = "#EXTM3U"
+	m3uInfoPrefix    = "#EXTINF:"
+	m3uPlaylistTitle = "#PLAYLIST:"
+)
+
+//ErrUnsupportedFormat is returned for files that are not playlists.
+var ErrUnsupportedFormat = errors.New("unsupported playlist format")
+
+//exportedPlaylist is the JSON export format of playlists.
+type exportedPlaylist struct {
+	Name    string  `json:"name"`
+	Entries []Entry `json:"entries"`
+}
+
+//EncodeJSON exports the given playlist as JSON.
+func EncodeJSON(p *Playlist) ([]byte, error) {
+	data, err := json.MarshalIndent(exportedPlaylist{Name: p.Name, Entries: p.Entries}, "", "\t")
+	if err != nil {
+		return nil, fmt.Errorf("Error while encoding playlist: %v", err)
+	}
+	return data, nil
+}
+
+//DecodeJSON imports a playlist that is exported by EncodeJSON.
+//Returns the name of the playlist and its valid entries.
+func DecodeJSON(data []byte) (string, []Entry, error) {
+	var exported exportedPlaylist
+	err := json.Unmarshal(data, &exported)
+	if err != nil {
+		return "", nil, fmt.Errorf("Error while parsing playlist: %v", err)
+	}
+
+	entries := []Entry{}
+	for _, entry := range exported.Entries {
+		switch entry.Source {
+		case SourceYoutube, SourceSpotify, SourceLocal, SourceURL, SourceQuery:
+		default:
+			continue
+		}
+		if strings.TrimSpace(entry.ID) == "" {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return exported.Name, entries, nil
+}
+
+//EncodeM3U exports the given playlist as an extended M3U playlist.
+func EncodeM3U(p *Playlist) []byte {
+	var buffer bytes.Buffer
+	buffer.WriteString(m3uHeader + "\n")
+	buffer.WriteString(m3uPlaylistTitle + p.Name + "\n")
+
+	for _, entry := range p.Entries {
+		seconds := -1
+		if duration, err := time.ParseDuration(entry.Duration); err == nil {
+			seconds = int(duration.Seconds())
+		}
+
+		fmt.Fprintf(&buffer, "%s%d,%s\n", m3uInfoPrefix, seconds, entry.DisplayTitle())
+		buffer.WriteString(entry.Location() + "\n")
+	}
+	return buffer.Bytes()
+}
+
+//DecodeFile imports the entries of an M3U, M3U8, PLS or XSPF playlist by
+//the extension of the given file name. Entries that can't be imported are
+//returned as skipped.
+func DecodeFile(fileName string, data []byte) ([]Entry, []string, error) {
+	switch strings.ToLower(path.Ext(fileName)) {
+	case ".m3u", ".m3u8":
+		entries, skipped := DecodeM3U(data)
+		return entries, skipped, nil
+	case ".pls":
+		entries, skipped := DecodePLS(data)
+		return entries, skipped, nil
+	case ".xspf":
+		return DecodeXSPF(data)
+	}
+	return nil, nil, ErrUnsupportedFormat
+}
+
+//IsPlaylistFile checks the given file is a playlist that DecodeFile imports.
+func IsPlaylistFile(fileName string) bool {
+	switch strings.ToLower(path.Ext(fileName)) {
+	case ".m3u", ".m3u8", ".pls", ".xspf":
+		return true
+	}
+	return false
+}
+
+//DecodeM3U imports the entries of an M3U or M3U8 playlist. Titles are
+//taken from #EXTINF lines. Locations that are not tracks are imported
+//as queries if they have a title, otherwise returned as skipped.
+func DecodeM3U(data []byte) ([]Entry, []string) {
+	entries := []Entry{}
+	skipped := []string{}
+
+	var artist, title, duration string
+	scanner := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, m3uInfoPrefix) {
+			artist, title, duration = parseExtInf(strings.TrimPrefix(line, m3uInfoPrefix))
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		entry, ok := trackEntry(line, artist, title, duration)
+		if ok {
+			entries = append(entries, entry)
+		} else {
+			skipped = append(skipped, line)
+		}
+		artist, title, duration = "", "", ""
+	}
+	return entries, skipped
+}
+
+//parseExtInf parses the "<seconds>,<artist> - <title>" part of #EXTINF lines.
+func parseExtInf(info string) (string, string, string) {
+	parts := strings.SplitN(info, ",", 2)
+	if len(parts) != 2 {
+		return "", "", ""
+	}
+
+	duration := ""
+	//attributes like tvg-id may follow the seconds.
+	if seconds, err := strconv.Atoi(strings.Fields(parts[0] + " ")[0]); err == nil && seconds > 0 {
+		duration = (time.Duration(seconds) * time.Second).String()
+	}
+
+	artist, title := SplitArtistTitle(parts[1])
+	return artist, title, duration
+}
+
+//SplitArtistTitle splits titles like "Artist - Title". Artist
+//is empty if the title doesn't have one.
+func SplitArtistTitle(text string) (string, string) {
+	text = strings.TrimSpace(text)
+	parts := strings.SplitN(text, " - ", 2)
+	if len(parts) != 2 {
+		return "", text
+	}
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+}
+
+//trackEntry creates the entry of a playlist file track. Tracks that don't
+//have a playable location are imported as "artist - title" queries.
+func trackEntry(location, artist, title, duration string) (Entry, bool) {
+	if location != "" {
+		entry, err := EntryFromLocation(location)
+		if err == nil {
+			if title != "" {
+				entry.Artist = artist
+				entry.Title = title
+			}
+			if entry.Duration == "" {
+				entry.Duration = duration
+			}
+			return entry, true
+		}
+	}
+
+	if title == "" {
+		return Entry{}, false
+	}
+
+	entry := Entry{Source: SourceQuery, Artist: artist, Title: title, Duration: duration}
+	entry.ID = entry.DisplayTitle()
+	return entry, true
+}
+
+//EntryFromLocation creates an entry from a Youtube or Spotify track link,
+//an audio file or stream URL, or a local path or file URL. Title of the entry is
+//the location itself, or the file name for local files, until it is known.
+func EntryFromLocation(location string) (Entry, error) {
+	location = strings.TrimSpace(location)
+
+	ref, err := util.ParseMediaRef(location)
+	if err == nil {
+		if ref.Kind != util.KindTrack {
+			return Entry{}, fmt.Errorf("%s %s links can't be playlist entries", ref.Provider, ref.Kind)
+		}
+
+		entry := Entry{Source: SourceSpotify, ID: ref.ID, Title: location}
+		if ref.Provider == util.ProviderYoutube {
+			entry.Source = SourceYoutube
+		}
+		return entry, nil
+	}
+	if err != util.ErrNotMediaRef {
+		return Entry{}, err
+	}
+
+	if stream.IsHttpUrl(location) {
+		return Entry{Source: SourceURL, ID: location, Title: location}, nil
+	}
+
+	if strings.HasPrefix(location, "file://") {
+		u, err := url.Parse(location)
+		if err != nil || u.Path == "" {
+			return Entry{}, fmt.Errorf("Invalid file URL: %s", location)
+		}
+		return localEntry(u.Path), nil
+	}
+
+	if strings.Contains(location, "://") {
+		return Entry{}, fmt.Errorf("Unsupported location: %s", location)
+	}
+	return localEntry(location), nil
+}
+
+//localEntry creates the entry of a local file. Title is taken from
+//file names like "Artist - Title.mp3".
+func localEntry(filePath string) Entry {
+	name := path.Base(strings.Replace(filePath, "\\", "/", -1))
+	artist, title := SplitArtistTitle(strings.TrimSuffix(name, path.Ext(name)))
+	return Entry{Source: SourceLocal, ID: filePath, Artist: artist, Title: title}
+}
+
+//Location returns the link or path of the entry.
+func (e Entry) Location() string {
+	switch e.Source {
+	case SourceYoutube:
+		return youtubeVideoUrl + e.ID
+	case SourceSpotify:
+		return spotifyTrackUrl + e.ID
+	}
+	return e.ID
+}
+
+//DisplayTitle returns the title of the entry like "Artist - Title".
+func (e Entry) DisplayTitle() string {
+	if e.Artist == "" {
+		return e.Title
+	}
+	return e.Artist + " - " + e.Title
+}
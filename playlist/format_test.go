@@ -0,0 +1,167 @@
+package playlist
+
+import (
+	"testing"
+)
+
+func TestM3URoundTrip(t *testing.T) {
+	p := &Playlist{
+		Name: "mix",
+		Entries: []Entry{
+			{Source: SourceYoutube, ID: "B9v8jLBrvug", Title: "Never Gonna Give You Up", Artist: "Rick Astley", Duration: "3m32s"},
+			{Source: SourceSpotify, ID: "2az3iTNyJ1M1JJnsU2Gq6H", Title: "Billie Jean", Artist: "Michael Jackson"},
+			{Source: SourceURL, ID: "http://radio.example.com:8000/stream", Title: "Radio"},
+			{Source: SourceLocal, ID: "albums/song.mp3", Title: "Local Song", Duration: "1m0s"},
+		},
+	}
+
+	entries, skipped := DecodeM3U(EncodeM3U(p))
+	if len(skipped) != 0 {
+		t.Errorf("Got skipped locations: %v", skipped)
+	}
+	if len(entries) != len(p.Entries) {
+		t.Fatalf("Got %d entries, want %d.", len(entries), len(p.Entries))
+	}
+
+	for i, entry := range p.Entries {
+		if entries[i] != entry {
+			t.Errorf("Entry %d: got %+v, want %+v.", i, entries[i], entry)
+		}
+	}
+}
+
+func TestDecodeM3U(t *testing.T) {
+	data := []byte("\xef\xbb\xbf#EXTM3U\r\n" +
+		"#EXTINF:200,Artist - Title\r\n" +
+		"https://youtu.be/SlPhMPnQ58k\r\n" +
+		"\r\n" +
+		"https://www.youtube.com/playlist?list=PLwiyx1dc3P2JR9N8gQaQN_BCvlSlap7re\r\n" +
+		"ftp://example.com/song.mp3\r\n" +
+		"music/song.flac\r\n" +
+		"C:\\Music\\Artist - Song.mp3\r\n")
+
+	tables := []Entry{
+		{Source: SourceYoutube, ID: "SlPhMPnQ58k", Title: "Title", Artist: "Artist", Duration: "3m20s"},
+		{Source: SourceLocal, ID: "music/song.flac", Title: "song"},
+		{Source: SourceLocal, ID: `C:\Music\Artist - Song.mp3`, Artist: "Artist", Title: "Song"},
+	}
+
+	entries, skipped := DecodeM3U(data)
+	if len(skipped) != 2 {
+		t.Errorf("Got skipped %v, want the playlist and ftp links.", skipped)
+	}
+	if len(entries) != len(tables) {
+		t.Fatalf("Got %d entries, want %d.", len(entries), len(tables))
+	}
+
+	for i, table := range tables {
+		if entries[i] != table {
+			t.Errorf("Entry %d: got %+v, want %+v.", i, entries[i], table)
+		}
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	data := []byte(`{"name": "mix", "entries": [
+		{"source": "youtube", "id": "B9v8jLBrvug", "title": "Song"},
+		{"source": "unknown", "id": "x"},
+		{"source": "spotify", "id": " "}
+	]}`)
+
+	name, entries, err := DecodeJSON(data)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	if name != "mix" || len(entries) != 1 || entries[0].ID != "B9v8jLBrvug" {
+		t.Errorf("Got %s %+v, want mix with one valid entry.", name, entries)
+	}
+
+	_, _, err = DecodeJSON([]byte("#EXTM3U"))
+	if err == nil {
+		t.Errorf("Expected error for malformed JSON.")
+	}
+}
+
+func TestDecodeFile(t *testing.T) {
+	pls := []byte("[playlist]\n" +
+		"File2=https://open.spotify.com/track/2az3iTNyJ1M1JJnsU2Gq6H\n" +
+		"Title2=Michael Jackson - Billie Jean\n" +
+		"File1=http://radio.example.com:8000/stream\n" +
+		"Title1=Radio\n" +
+		"Length1=-1\n" +
+		"File3=https://open.spotify.com/album/4HklP3MTUYViTMiNdj43R3\n" +
+		"Title3=Artist - Album Track\n" +
+		"Length3=180\n" +
+		"File4=ftp://example.com/song.mp3\n" +
+		"NumberOfEntries=4\n" +
+		"Version=2\n")
+
+	xspf := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<playlist version="1" xmlns="http://xspf.org/ns/0/">
+	<title>mix</title>
+	<trackList>
+		<track>
+			<location>ftp://example.com/song.mp3</location>
+			<location>file:///music/albums/song.flac</location>
+			<creator>Artist</creator>
+			<title>Local Song</title>
+			<duration>200400</duration>
+		</track>
+		<track>
+			<creator>Rick Astley</creator>
+			<title>Never Gonna Give You Up</title>
+		</track>
+		<track>
+			<location>https://youtu.be/SlPhMPnQ58k</location>
+		</track>
+		<track>
+			<location>ftp://example.com/other.mp3</location>
+		</track>
+	</trackList>
+</playlist>`)
+
+	tables := []struct {
+		fileName string
+		data     []byte
+		entries  []Entry
+		skipped  int
+	}{
+		{"list.PLS", pls, []Entry{
+			{Source: SourceURL, ID: "http://radio.example.com:8000/stream", Title: "Radio"},
+			{Source: SourceSpotify, ID: "2az3iTNyJ1M1JJnsU2Gq6H", Artist: "Michael Jackson", Title: "Billie Jean"},
+			{Source: SourceQuery, ID: "Artist - Album Track", Artist: "Artist", Title: "Album Track", Duration: "3m0s"},
+		}, 1},
+		{"list.xspf", xspf, []Entry{
+			{Source: SourceLocal, ID: "/music/albums/song.flac", Artist: "Artist", Title: "Local Song", Duration: "3m20s"},
+			{Source: SourceQuery, ID: "Rick Astley - Never Gonna Give You Up", Artist: "Rick Astley", Title: "Never Gonna Give You Up"},
+			{Source: SourceYoutube, ID: "SlPhMPnQ58k", Title: "https://youtu.be/SlPhMPnQ58k"},
+		}, 1},
+	}
+
+	for _, table := range tables {
+		entries, skipped, err := DecodeFile(table.fileName, table.data)
+		if err != nil {
+			t.Errorf("%s: got error: %v", table.fileName, err)
+			continue
+		}
+		if len(skipped) != table.skipped {
+			t.Errorf("%s: got skipped %v, want %d.", table.fileName, skipped, table.skipped)
+		}
+		if len(entries) != len(table.entries) {
+			t.Errorf("%s: got %d entries, want %d: %+v", table.fileName, len(entries), len(table.entries), entries)
+			continue
+		}
+		for i, entry := range table.entries {
+			if entries[i] != entry {
+				t.Errorf("%s entry %d: got %+v, want %+v.", table.fileName, i, entries[i], entry)
+			}
+		}
+	}
+
+	if _, _, err := DecodeFile("list.txt", pls); err != ErrUnsupportedFormat {
+		t.Errorf("Got %v for a text file, want ErrUnsupportedFormat.", err)
+	}
+	if _, _, err := DecodeFile("list.xspf", []byte("<playlist")); err == nil {
+		t.Errorf("Expected error for malformed XSPF.")
+	}
+}
@@ -0,0 +1,266 @@
+package playlist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Source is where an entry is played from.
+type Source string
+
+const (
+	SourceYoutube Source = "youtube" //Youtube video ID
+	SourceSpotify Source = "spotify" //Spotify track ID, matched to a video when played
+	SourceLocal   Source = "local"   //path of an audio file in the music library
+	SourceURL     Source = "url"     //direct audio file or radio stream URL
+	SourceQuery   Source = "query"   //"artist - title" query, matched to a video when played
+)
+
+var (
+	ErrNotFound = errors.New("playlist not found")
+	ErrExists   = errors.New("playlist already exists")
+)
+
+//Entry is a track of a saved playlist. Title, artist and duration
+//are kept, so a playlist can be shown without looking tracks up.
+type Entry struct {
+	Source   Source `json:"source"`
+	ID       string `json:"id"` //video ID, track ID, path, URL or query by source
+	Title    string `json:"title"`
+	Artist   string `json:"artist,omitempty"`
+	Duration string `json:"duration,omitempty"`
+	CoverUrl string `json:"coverUrl,omitempty"`
+}
+
+//Playlist is a list of entries saved by a user or for a guild.
+type Playlist struct {
+	Name      string    `json:"name"`
+	Owner     string    `json:"owner"`     //see UserOwner and GuildOwner
+	CreatedBy string    `json:"createdBy"` //ID of the user that saved it
+	Entries   []Entry   `json:"entries"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+//UserOwner returns the owner of the playlists of the given user.
+func UserOwner(userID string) string {
+	return "user:" + userID
+}
+
+//GuildOwner returns the owner of the playlists shared in the given guild.
+func GuildOwner(guildID string) string {
+	return "guild:" + guildID
+}
+
+//Store keeps playlists in a JSON file. Playlist names are case insensitive
+//and unique for each owner.
+type Store struct {
+	mu        sync.Mutex
+	path      string
+	playlists map[string]*Playlist
+}
+
+//Open loads the playlists in the given file. If file doesn't exist,
+//an empty store is created. If path is empty, playlists are kept
+//only in memory.
+func Open(path string) (*Store, error) {
+	s := &Store{
+		path:      path,
+		playlists: map[string]*Playlist{},
+	}
+
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error while reading playlists file: %v", err)
+	}
+
+	playlists := []*Playlist{}
+	err = json.Unmarshal(data, &playlists)
+	if err != nil {
+		return nil, fmt.Errorf("Error while parsing playlists file: %v", err)
+	}
+
+	for _, p := range playlists {
+		s.playlists[playlistKey(p.Owner, p.Name)] = p
+	}
+	return s, nil
+}
+
+//Get returns a copy of the playlist of the given owner.
+func (s *Store) Get(owner, name string) (*Playlist, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.playlists[playlistKey(owner, name)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return p.copy(), nil
+}
+
+//List returns the playlists of the given owner in alphabetical order.
+func (s *Store) List(owner string) []*Playlist {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	playlists := []*Playlist{}
+	for _, p := range s.playlists {
+		if p.Owner == owner {
+			playlists = append(playlists, p.copy())
+		}
+	}
+
+	sort.Slice(playlists, func(i, j int) bool {
+		return strings.ToLower(playlists[i].Name) < strings.ToLower(playlists[j].Name)
+	})
+	return playlists
+}
+
+//Save adds the given playlist or replaces the playlist with the same
+//owner and name, then writes the store to its file.
+func (s *Store) Save(p *Playlist) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved := p.copy()
+	saved.UpdatedAt = time.Now()
+
+	key := playlistKey(p.Owner, p.Name)
+	old, existed := s.playlists[key]
+	if existed {
+		saved.CreatedAt = old.CreatedAt
+	} else if saved.CreatedAt.IsZero() {
+		saved.CreatedAt = saved.UpdatedAt
+	}
+	s.playlists[key] = saved
+
+	err := s.write()
+	if err != nil {
+		if existed {
+			s.playlists[key] = old
+		} else {
+			delete(s.playlists, key)
+		}
+		return err
+	}
+	return nil
+}
+
+//Delete removes the playlist of the given owner.
+func (s *Store) Delete(owner, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := playlistKey(owner, name)
+	old, ok := s.playlists[key]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(s.playlists, key)
+
+	err := s.write()
+	if err != nil {
+		s.playlists[key] = old
+		return err
+	}
+	return nil
+}
+
+//Rename changes the name of the playlist of the given owner.
+//Returns ErrExists if the owner has a playlist with the new name.
+func (s *Store) Rename(owner, oldName, newName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	oldKey := playlistKey(owner, oldName)
+	newKey := playlistKey(owner, newName)
+
+	p, ok := s.playlists[oldKey]
+	if !ok {
+		return ErrNotFound
+	}
+	if _, exists := s.playlists[newKey]; exists && newKey != oldKey {
+		return ErrExists
+	}
+
+	renamed := p.copy()
+	renamed.Name = newName
+	renamed.UpdatedAt = time.Now()
+
+	delete(s.playlists, oldKey)
+	s.playlists[newKey] = renamed
+
+	err := s.write()
+	if err != nil {
+		delete(s.playlists, newKey)
+		s.playlists[oldKey] = p
+		return err
+	}
+	return nil
+}
+
+//write writes the store to its file. The file is replaced atomically,
+//so a crash doesn't leave half written playlists. Caller must hold the lock.
+func (s *Store) write() error {
+	if s.path == "" {
+		return nil
+	}
+
+	playlists := []*Playlist{}
+	for _, p := range s.playlists {
+		playlists = append(playlists, p)
+	}
+	sort.Slice(playlists, func(i, j int) bool {
+		return playlistKey(playlists[i].Owner, playlists[i].Name) < playlistKey(playlists[j].Owner, playlists[j].Name)
+	})
+
+	data, err := json.MarshalIndent(playlists, "", "\t")
+	if err != nil {
+		return fmt.Errorf("Error while encoding playlists: %v", err)
+	}
+
+	tempFile, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("Error while creating playlists file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	_, err = tempFile.Write(data)
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("Error while writing playlists file: %v", err)
+	}
+
+	err = os.Rename(tempFile.Name(), s.path)
+	if err != nil {
+		return fmt.Errorf("Error while writing playlists file: %v", err)
+	}
+	return nil
+}
+
+func (p *Playlist) copy() *Playlist {
+	copied := *p
+	copied.Entries = append([]Entry{}, p.Entries...)
+	return &copied
+}
+
+func playlistKey(owner, name string) string {
+	return owner + "/" + strings.ToLower(name)
+}
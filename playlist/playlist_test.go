@@ -0,0 +1,59 @@
+package playlist
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestStorePersistsChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "playlists.json")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+
+	owner := UserOwner("1")
+	entries := []Entry{{Source: SourceYoutube, ID: "B9v8jLBrvug", Title: "Song"}}
+
+	err = store.Save(&Playlist{Name: "Chill", Owner: owner, CreatedBy: "1", Entries: entries})
+	if err != nil {
+		t.Fatalf("Got error while saving: %v", err)
+	}
+	err = store.Save(&Playlist{Name: "rock", Owner: owner, CreatedBy: "1", Entries: entries})
+	if err != nil {
+		t.Fatalf("Got error while saving: %v", err)
+	}
+
+	if err := store.Rename(owner, "chill", "rock"); err != ErrExists {
+		t.Errorf("Renaming to an existing name: got %v, want ErrExists.", err)
+	}
+	if err := store.Rename(owner, "CHILL", "Evening"); err != nil {
+		t.Fatalf("Got error while renaming: %v", err)
+	}
+	if err := store.Delete(owner, "rock"); err != nil {
+		t.Fatalf("Got error while deleting: %v", err)
+	}
+	if err := store.Delete(owner, "rock"); err != ErrNotFound {
+		t.Errorf("Deleting a missing playlist: got %v, want ErrNotFound.", err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Got error while reopening: %v", err)
+	}
+
+	playlists := reopened.List(owner)
+	if len(playlists) != 1 || playlists[0].Name != "Evening" {
+		t.Fatalf("Got playlists %+v, want only Evening.", playlists)
+	}
+	if len(playlists[0].Entries) != 1 || playlists[0].Entries[0] != entries[0] {
+		t.Errorf("Got entries %+v, want %+v.", playlists[0].Entries, entries)
+	}
+	if playlists[0].CreatedAt.IsZero() {
+		t.Errorf("Creation time is not saved.")
+	}
+
+	if len(reopened.List(GuildOwner("1"))) != 0 {
+		t.Errorf("Guild playlists are mixed with user playlists.")
+	}
+}
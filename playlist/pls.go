@@ -0,0 +1,94 @@
+package playlist
+
+import (
+	"bufio"
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//plsTrack is a track of a PLS playlist. Fields of a track are
+//numbered like File1, Title1 and Length1.
+type plsTrack struct {
+	file   string
+	title  string
+	length string
+}
+
+//DecodePLS imports the entries of a PLS playlist. Tracks that don't
+//have a playable file are imported as queries if they have a title,
+//otherwise returned as skipped.
+func DecodePLS(data []byte) ([]Entry, []string) {
+	tracks := map[int]*plsTrack{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		key := strings.ToLower(strings.TrimSpace(parts[0]))
+		value := strings.TrimSpace(parts[1])
+
+		var field string
+		for _, name := range []string{"file", "title", "length"} {
+			if strings.HasPrefix(key, name) {
+				field = name
+				break
+			}
+		}
+		if field == "" {
+			continue
+		}
+
+		number, err := strconv.Atoi(strings.TrimPrefix(key, field))
+		if err != nil {
+			continue
+		}
+
+		track, ok := tracks[number]
+		if !ok {
+			track = &plsTrack{}
+			tracks[number] = track
+		}
+
+		switch field {
+		case "file":
+			track.file = value
+		case "title":
+			track.title = value
+		case "length":
+			track.length = value
+		}
+	}
+
+	numbers := []int{}
+	for number := range tracks {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+
+	entries := []Entry{}
+	skipped := []string{}
+	for _, number := range numbers {
+		track := tracks[number]
+
+		duration := ""
+		if seconds, err := strconv.Atoi(track.length); err == nil && seconds > 0 {
+			duration = (time.Duration(seconds) * time.Second).String()
+		}
+
+		artist, title := SplitArtistTitle(track.title)
+		entry, ok := trackEntry(track.file, artist, title, duration)
+		if !ok {
+			skipped = append(skipped, track.file)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, skipped
+}
@@ -0,0 +1,66 @@
+package playlist
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//xspfPlaylist is an XML Shareable Playlist Format document.
+type xspfPlaylist struct {
+	Title  string      `xml:"title"`
+	Tracks []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Locations []string `xml:"location"`
+	Creator   string   `xml:"creator"`
+	Title     string   `xml:"title"`
+	Duration  string   `xml:"duration"` //milliseconds
+}
+
+//DecodeXSPF imports the entries of an XSPF playlist. First playable location
+//of a track is used. Tracks that don't have a playable location are imported
+//as queries if they have a title, otherwise returned as skipped.
+func DecodeXSPF(data []byte) ([]Entry, []string, error) {
+	var doc xspfPlaylist
+	err := xml.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error while parsing XSPF playlist: %v", err)
+	}
+
+	entries := []Entry{}
+	skipped := []string{}
+	for _, track := range doc.Tracks {
+		duration := ""
+		if ms, err := strconv.Atoi(strings.TrimSpace(track.Duration)); err == nil && ms > 0 {
+			duration = (time.Duration(ms) * time.Millisecond).Round(time.Second).String()
+		}
+
+		artist := strings.TrimSpace(track.Creator)
+		title := strings.TrimSpace(track.Title)
+
+		imported := false
+		for _, location := range track.Locations {
+			entry, ok := trackEntry(strings.TrimSpace(location), artist, title, duration)
+			if ok && entry.Source != SourceQuery {
+				entries = append(entries, entry)
+				imported = true
+				break
+			}
+		}
+		if imported {
+			continue
+		}
+
+		entry, ok := trackEntry("", artist, title, duration)
+		if !ok {
+			skipped = append(skipped, strings.Join(track.Locations, " "))
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, skipped, nil
+}
@@ -0,0 +1,82 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hemreari/feanor-dcbot/config"
+)
+
+//Names of the rules, same with their config keys.
+const (
+	RuleMaxDuration     = "maxDuration"
+	RuleBlockedKeywords = "blockedKeywords"
+	RuleBlockedChannels = "blockedChannels"
+	RuleBlockedVideos   = "blockedVideos"
+	RuleBlockExplicit   = "blockExplicit"
+)
+
+//Track is the information of a track that policies are checked with.
+//Unknown fields are left empty and the rules that need them are skipped.
+type Track struct {
+	Title         string
+	Artist        string
+	VideoID       string
+	ChannelID     string
+	ChannelTitle  string
+	Length        time.Duration
+	Explicit      bool //explicit on Spotify
+	AgeRestricted bool //age restricted on Youtube
+}
+
+//Violation is the rule of a policy that a track doesn't obey.
+type Violation struct {
+	Rule   string
+	Reason string
+}
+
+func (v *Violation) Error() string {
+	return v.Reason + " (" + v.Rule + ")"
+}
+
+//Check checks the track with the rules of the given policy. Returns the
+//first rule that the track doesn't obey, or nil if it obeys all of them.
+func Check(policy config.PolicyConfig, track Track) *Violation {
+	if track.VideoID != "" {
+		for _, id := range policy.BlockedVideos {
+			if id == track.VideoID {
+				return &Violation{RuleBlockedVideos, fmt.Sprintf("video %s is blocked", track.VideoID)}
+			}
+		}
+	}
+
+	for _, channel := range policy.BlockedChannels {
+		if (track.ChannelID != "" && channel == track.ChannelID) ||
+			(track.ChannelTitle != "" && strings.EqualFold(channel, track.ChannelTitle)) {
+			return &Violation{RuleBlockedChannels, fmt.Sprintf("channel %s is blocked", channel)}
+		}
+	}
+
+	text := strings.ToLower(track.Title + "\n" + track.Artist + "\n" + track.ChannelTitle)
+	for _, keyword := range policy.BlockedKeywords {
+		keyword = strings.TrimSpace(keyword)
+		if keyword != "" && strings.Contains(text, strings.ToLower(keyword)) {
+			return &Violation{RuleBlockedKeywords, fmt.Sprintf("it contains the blocked keyword \"%s\"", keyword)}
+		}
+	}
+
+	if policy.BlockExplicit && track.Explicit {
+		return &Violation{RuleBlockExplicit, "it is explicit on Spotify"}
+	}
+	if policy.BlockExplicit && track.AgeRestricted {
+		return &Violation{RuleBlockExplicit, "it is age restricted on Youtube"}
+	}
+
+	maxDuration, err := time.ParseDuration(policy.MaxDuration)
+	if err == nil && maxDuration > 0 && track.Length > maxDuration {
+		return &Violation{RuleMaxDuration, fmt.Sprintf("it is %s long, max duration is %s",
+			track.Length.Round(time.Second), maxDuration)}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hemreari/feanor-dcbot/config"
+)
+
+func TestCheck(t *testing.T) {
+	policy := config.PolicyConfig{
+		MaxDuration:     "15m",
+		BlockedKeywords: []string{"10 hours", "Earrape"},
+		BlockedChannels: []string{"UCblocked", "Spam Music"},
+		BlockedVideos:   []string{"dQw4w9WgXcQ"},
+		BlockExplicit:   true,
+	}
+
+	tables := []struct {
+		track Track
+		rule  string
+	}{
+		{Track{Title: "Billie Jean", Length: 5 * time.Minute}, ""},
+		{Track{Title: "Nyan Cat 10 Hours", Length: 10 * time.Hour}, RuleBlockedKeywords},
+		{Track{Title: "Lofi Mix", Length: 2 * time.Hour}, RuleMaxDuration},
+		{Track{Title: "Song", ChannelID: "UCblocked"}, RuleBlockedChannels},
+		{Track{Title: "Song", ChannelTitle: "spam music"}, RuleBlockedChannels},
+		{Track{Title: "Song", VideoID: "dQw4w9WgXcQ"}, RuleBlockedVideos},
+		{Track{Title: "Song", Artist: "EARRAPE"}, RuleBlockedKeywords},
+		{Track{Title: "Song", Explicit: true}, RuleBlockExplicit},
+		{Track{Title: "Song", AgeRestricted: true}, RuleBlockExplicit},
+		{Track{Title: "Unknown Length"}, ""},
+	}
+
+	for _, table := range tables {
+		violation := Check(policy, table.track)
+		rule := ""
+		if violation != nil {
+			rule = violation.Rule
+		}
+		if rule != table.rule {
+			t.Errorf("%+v: got rule %q, want %q.", table.track, rule, table.rule)
+		}
+	}
+
+	if violation := Check(config.PolicyConfig{}, Track{Title: "10 hours", Explicit: true, Length: 10 * time.Hour}); violation != nil {
+		t.Errorf("Empty policy rejected a track: %v", violation)
+	}
+}
@@ -0,0 +1,177 @@
+package spotify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultBaseURL    string = "https://api.spotify.com/v1"
+	DefaultMaxRetries int    = 3
+
+	//first retry waits this long, every next retry waits twice as long.
+	retryBackoff = 500 * time.Millisecond
+	//requests are not retried if Spotify asks to wait longer than this.
+	maxRetryAfter = 30 * time.Second
+)
+
+var (
+	ErrNotFound     = errors.New("spotify: resource not found")
+	ErrUnauthorized = errors.New("spotify: unauthorized")
+	ErrRateLimited  = errors.New("spotify: rate limited")
+)
+
+//APIError is returned when Spotify API responds with an unsuccessful
+//status code. It matches ErrNotFound, ErrUnauthorized and ErrRateLimited
+//with errors.Is according to its status code.
+type APIError struct {
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration
+}
+
+//errorResponse is the body of unsuccessful Spotify API responses.
+type errorResponse struct {
+	Error struct {
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("spotify: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("spotify: %d %s", e.StatusCode, e.Message)
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+//get sends a GET request to the given API path and decodes successful
+//response to v. Rate limited requests are retried after the duration in
+//Retry-After header, server errors and transport errors are retried with
+//exponential backoff. If the token is rejected, a new one is requested once.
+func (s *SpotifyAPI) get(path string, v interface{}) error {
+	var lastErr error
+	tokenRenewed := false
+
+	for attempt := 0; attempt <= s.MaxRetries; attempt++ {
+		var wait time.Duration
+
+		resp, err := s.do("GET", s.BaseURL+path)
+		if err != nil {
+			lastErr = err
+			wait = backoff(attempt)
+		} else if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			err = json.NewDecoder(resp.Body).Decode(v)
+			resp.Body.Close()
+			if err != nil {
+				return fmt.Errorf("Error while decoding Spotify response: %v", err)
+			}
+			return nil
+		} else {
+			apiErr := newAPIError(resp)
+			lastErr = apiErr
+
+			switch {
+			case resp.StatusCode == http.StatusTooManyRequests:
+				if apiErr.RetryAfter > maxRetryAfter {
+					return apiErr
+				}
+				log.Printf("Spotify rate limit is exceeded, retrying in %s.\n", apiErr.RetryAfter)
+				wait = apiErr.RetryAfter
+			case resp.StatusCode == http.StatusUnauthorized && !tokenRenewed:
+				tokenRenewed = true
+				s.invalidateToken()
+			case resp.StatusCode >= 500:
+				wait = backoff(attempt)
+			default:
+				return apiErr
+			}
+		}
+
+		if attempt < s.MaxRetries && wait > 0 {
+			s.sleep(wait)
+		}
+	}
+	return lastErr
+}
+
+//do sends an authorized request to the given url. Response body must
+//be closed by the caller.
+func (s *SpotifyAPI) do(method, url string) (*http.Response, error) {
+	token, err := s.tokenSource.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Error while creating Spotify request: %v", err)
+	}
+	token.SetAuthHeader(req)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error while sending Spotify request: %v", err)
+	}
+	return resp, nil
+}
+
+//invalidateToken drops the cached token, so that next request is made
+//with a newly requested token. Underlying token source doesn't reuse
+//tokens, so the rejected token isn't returned again.
+func (s *SpotifyAPI) invalidateToken() {
+	ts, ok := s.tokenSource.(*cachingTokenSource)
+	if !ok {
+		return
+	}
+
+	ts.mu.Lock()
+	ts.token = nil
+	ts.mu.Unlock()
+}
+
+//newAPIError creates an APIError from the given unsuccessful response
+//and closes the response body.
+func newAPIError(resp *http.Response) *APIError {
+	defer resp.Body.Close()
+
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+	}
+
+	var body errorResponse
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err == nil && json.Unmarshal(data, &body) == nil {
+		apiErr.Message = body.Error.Message
+	}
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	} else if resp.StatusCode == http.StatusTooManyRequests {
+		apiErr.RetryAfter = time.Second
+	}
+	return apiErr
+}
+
+func backoff(attempt int) time.Duration {
+	return retryBackoff * time.Duration(1<<uint(attempt))
+}
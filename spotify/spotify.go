@@ -2,11 +2,13 @@ package spotify
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"sync"
+	"time"
 
+	"github.com/hemreari/feanor-dcbot/cache"
 	"github.com/hemreari/feanor-dcbot/util"
 
 	"golang.org/x/oauth2"
@@ -15,12 +17,38 @@ import (
 
 const (
 	DEFAULTCOVERURL string = "https://github.com/golang/go/blob/master/doc/gopher/fiveyears.jpg"
+	tokenURL        string = "https://accounts.spotify.com/api/token"
+
+	//cached token is renewed when it has less time than this to expire.
+	tokenRefreshBefore = time.Minute
 )
 
+//SpotifyAPI is a long-lived Spotify Web API client. Client credentials
+//token is cached and renewed by tokenSource before it expires.
 type SpotifyAPI struct {
 	ClientID       string
 	ClientSecretID string
-	BearerToken    string
+	BaseURL        string       //Spotify Web API address, could be changed for tests
+	MaxRetries     int          //retry count of rate limited and failed requests
+	Cache          *cache.Cache //nil disables caching
+	tokenSource    oauth2.TokenSource
+	client         *http.Client
+	sleep          func(time.Duration)
+}
+
+//cachingTokenSource caches the token of the underlying token source
+//and asks a new one when the cached token is about to expire.
+type cachingTokenSource struct {
+	mu            sync.Mutex
+	token         *oauth2.Token
+	source        oauth2.TokenSource
+	refreshBefore time.Duration
+}
+
+type SpotifyImage struct {
+	Url    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
 }
 
 type SpotifyPlaylistTracks struct {
@@ -30,15 +58,16 @@ type SpotifyPlaylistTracks struct {
 				Artists []struct {
 					Name string `json:"name"`
 				} `json:"artists"`
-				Name   string `json:"name"`
-				Images []struct {
-					Url string `json:"url"`
-				} `json:"images"`
+				Name   string         `json:"name"`
+				Images []SpotifyImage `json:"images"`
 			} `json:"album"`
 			Artists []struct {
 				Name string `json:"name"`
 			} `json:"artists"`
-			Name string `json:"name"`
+			ID         string `json:"id"`
+			Name       string `json:"name"`
+			DurationMs int    `json:"duration_ms"`
+			Explicit   bool   `json:"explicit"`
 		} `json:"track"`
 	} `json:"items"`
 	Limit    int         `json:"limit"`
@@ -49,14 +78,15 @@ type SpotifyPlaylistTracks struct {
 }
 
 type SpotifyAlbumTracks struct {
-	Name   string `json:"name"` //album name
-	Images []struct {
-		Url string `json:"url"` //album cover url
-	} `json:"images"`
+	Name   string         `json:"name"`   //album name
+	Images []SpotifyImage `json:"images"` //album cover urls
 	Tracks struct {
 		Items []struct {
-			Name    string `json:"name"` //track name
-			Artists []struct {
+			ID         string `json:"id"`          //track ID
+			Name       string `json:"name"`        //track name
+			DurationMs int    `json:"duration_ms"` //track duration
+			Explicit   bool   `json:"explicit"`    //track has explicit lyrics
+			Artists    []struct {
 				Name string `json:"name"` //track artist name
 			} `json:"Artists"`
 		} `json:"items"`
@@ -64,15 +94,33 @@ type SpotifyAlbumTracks struct {
 }
 
 type SpotifySingleTrack struct {
-	Name   string `json:"name"` //track name
-	Images []struct {
-		Url string `json:"url"` //track cover url
-	} `json:"images"`
+	ID         string `json:"id"`          //track ID
+	Name       string `json:"name"`        //track name
+	DurationMs int    `json:"duration_ms"` //track duration
+	Explicit   bool   `json:"explicit"`    //track has explicit lyrics
+	Album      struct {
+		Images []SpotifyImage `json:"images"` //track cover urls
+	} `json:"album"`
 	Artists []struct {
 		Name string `json:"name"` //track artist name
 	}
 }
 
+type SpotifyArtistTopTracks struct {
+	Tracks []struct {
+		ID         string `json:"id"`          //track ID
+		Name       string `json:"name"`        //track name
+		DurationMs int    `json:"duration_ms"` //track duration
+		Explicit   bool   `json:"explicit"`    //track has explicit lyrics
+		Album      struct {
+			Images []SpotifyImage `json:"images"` //album cover urls
+		} `json:"album"`
+		Artists []struct {
+			Name string `json:"name"` //track artist name
+		} `json:"artists"`
+	} `json:"tracks"`
+}
+
 type SpotifyPlaylistInfo struct {
 	Name  string `json:"name"`
 	Owner struct {
@@ -82,59 +130,87 @@ type SpotifyPlaylistInfo struct {
 }
 
 type SpotifyPlaylist struct {
+	TrackID     string
 	TrackName   string
 	CoverUrl    string
 	ArtistNames string
+	Duration    time.Duration
+	Explicit    bool //track has explicit lyrics
 }
 
-//!!!! Spotify api endpoints requires bearer token, clientID and
-//clientSecretID needed only for acquiring the bearer token.
-//endpoint functions could be call by a different struct that
-//contains only acquired bearer token.
+//NewSpotifyAPI creates a Spotify API client. Spotify api endpoints requires
+//bearer token, clientID and clientSecretID are needed only for acquiring the
+//bearer token. Token is not requested until the first API call.
 func NewSpotifyAPI(clientID, clientSecretID string) *SpotifyAPI {
-	spoAPI := &SpotifyAPI{
-		ClientID:       clientID,
-		ClientSecretID: clientSecretID,
-	}
-
-	bearerToken, err := spoAPI.getAPIToken()
-	if err != nil {
-		log.Printf("Error while getting Spotify OAUTH Token: %v", err)
+	conf := &clientcredentials.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecretID,
+		TokenURL:     tokenURL,
 	}
 
 	return &SpotifyAPI{
 		ClientID:       clientID,
 		ClientSecretID: clientSecretID,
-		BearerToken:    bearerToken.AccessToken,
+		BaseURL:        DefaultBaseURL,
+		MaxRetries:     DefaultMaxRetries,
+		tokenSource:    newTokenSource(conf),
+		client:         &http.Client{Timeout: 15 * time.Second},
+		sleep:          time.Sleep,
 	}
 }
 
-//getAPIToken returns Spotify oauth token
-func (s *SpotifyAPI) getAPIToken() (*oauth2.Token, error) {
-	ctx := context.Background()
-	conf := &clientcredentials.Config{
-		ClientID:     s.ClientID,
-		ClientSecret: s.ClientSecretID,
-		TokenURL:     "https://accounts.spotify.com/api/token",
-	}
+//credentialsTokenSource requests a new token on every call. Token source
+//of the config reuses its token until it expires, so it can't be used
+//for renewing the token before it expires.
+type credentialsTokenSource struct {
+	conf *clientcredentials.Config
+}
 
-	tok, err := conf.Token(ctx)
-	if err != nil {
-		return nil, err
+func (s credentialsTokenSource) Token() (*oauth2.Token, error) {
+	return s.conf.Token(context.Background())
+}
+
+//newTokenSource returns a token source that caches the client
+//credentials token of the given config.
+func newTokenSource(conf *clientcredentials.Config) *cachingTokenSource {
+	return &cachingTokenSource{
+		source:        credentialsTokenSource{conf: conf},
+		refreshBefore: tokenRefreshBefore,
 	}
-	return tok, nil
 }
 
-func (s *SpotifyAPI) do(method, url string) (*http.Response, error) {
-	req, err := http.NewRequest(method, url, nil)
-	req.Header.Add("Authorization", "Bearer "+s.BearerToken)
+//Token returns the cached token if it is not going to expire
+//soon, otherwise requests a new token and caches it.
+func (ts *cachingTokenSource) Token() (*oauth2.Token, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.token != nil && ts.token.AccessToken != "" &&
+		(ts.token.Expiry.IsZero() || time.Until(ts.token.Expiry) > ts.refreshBefore) {
+		return ts.token, nil
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	token, err := ts.source.Token()
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("Error while getting Spotify OAUTH Token: %v", err)
 	}
-	return resp, nil
+
+	log.Printf("Spotify OAUTH Token is renewed, expires at %s.\n", token.Expiry.Format(time.RFC3339))
+	ts.token = token
+	return token, nil
+}
+
+//coverUrl returns the medium sized image url of the given images.
+//Spotify returns images widest first, so second one is preferred.
+//If there is no image, default cover url is returned.
+func coverUrl(images []SpotifyImage) string {
+	if len(images) > 1 && images[1].Url != "" {
+		return images[1].Url
+	}
+	if len(images) > 0 && images[0].Url != "" {
+		return images[0].Url
+	}
+	return DEFAULTCOVERURL
 }
 
 //GetPlaylistInfo wrapper function of getPlaylistInfo function.
@@ -149,45 +225,44 @@ func (s *SpotifyAPI) GetPlaylistInfo(id string) (*SpotifyPlaylistInfo, error) {
 //GetPlaylistInfo sends request to get information about playlist to
 //Spotify API and decodes API response to SpotifyPlaylistInfo struct.
 func (s *SpotifyAPI) getPlaylistInfo(id string) (*SpotifyPlaylistInfo, error) {
-	url := "https://api.spotify.com/v1/playlists/" + id
-
-	resp, err := s.do("GET", url)
-	if err != nil {
-		return nil, fmt.Errorf("Error while getting playlist info: %v", err)
-	}
-
-	decoder := json.NewDecoder(resp.Body)
 	var spotifyPl SpotifyPlaylistInfo
-	err = decoder.Decode(&spotifyPl)
+	err := s.get("/playlists/"+id, &spotifyPl)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("Error while getting playlist info: %w", err)
 	}
 
 	return &spotifyPl, nil
 }
 
-func (s *SpotifyAPI) GetSpotifyPlaylist(id string, urlType int) ([]SpotifyPlaylist, error) {
-	switch urlType {
-	case util.SPOTIFYPLAYLISTURL:
-		playlist, err := s.HandlePlaylist(id)
+//GetSpotifyPlaylist returns the tracks of the given Spotify media reference.
+func (s *SpotifyAPI) GetSpotifyPlaylist(ref *util.MediaRef) ([]SpotifyPlaylist, error) {
+	switch ref.Kind {
+	case util.KindPlaylist:
+		playlist, err := s.HandlePlaylist(ref.ID)
 		if err != nil {
 			return nil, err
 		}
 		return playlist, nil
-	case util.SPOTIFYALBUMURL:
-		playlist, err := s.HandleAlbum(id)
+	case util.KindAlbum:
+		playlist, err := s.HandleAlbum(ref.ID)
 		if err != nil {
 			return nil, err
 		}
 		return playlist, nil
-	case util.SPOTIFYTRACKURL:
-		playlist, err := s.HandleTrack(id)
+	case util.KindTrack:
+		playlist, err := s.HandleTrack(ref.ID)
+		if err != nil {
+			return nil, err
+		}
+		return playlist, nil
+	case util.KindArtist:
+		playlist, err := s.HandleArtist(ref.ID)
 		if err != nil {
 			return nil, err
 		}
 		return playlist, nil
 	}
-	return nil, nil
+	return nil, fmt.Errorf("Spotify %s links are not supported.", ref.Kind)
 }
 
 //HandlePlaylist eliminates required information to create a download queue in
@@ -209,15 +284,13 @@ func (s *SpotifyAPI) HandlePlaylist(id string) ([]SpotifyPlaylist, error) {
 		}
 
 		trackName := value.Track.Name
-
-		coverUrl := ""
-		album := value.Track.Album
-		if album.Images[1].Url != "" {
-			coverUrl = album.Images[1].Url
-		} else {
-			coverUrl = DEFAULTCOVERURL
+		//unavailable and local tracks of playlists have no track info.
+		if trackName == "" {
+			continue
 		}
 
+		coverUrl := coverUrl(value.Track.Album.Images)
+
 		artistNames := ""
 		artists := value.Track.Artists
 		for artistIndex := range artists {
@@ -225,9 +298,12 @@ func (s *SpotifyAPI) HandlePlaylist(id string) ([]SpotifyPlaylist, error) {
 		}
 
 		spotifyPlaylist := SpotifyPlaylist{
+			TrackID:     value.Track.ID,
 			TrackName:   trackName,
 			CoverUrl:    coverUrl,
 			ArtistNames: artistNames,
+			Duration:    time.Duration(value.Track.DurationMs) * time.Millisecond,
+			Explicit:    value.Track.Explicit,
 		}
 
 		playlist = append(playlist, spotifyPlaylist)
@@ -238,19 +314,10 @@ func (s *SpotifyAPI) HandlePlaylist(id string) ([]SpotifyPlaylist, error) {
 //getPlaylistTracks sends request to get information about playlist's tracks
 //to Spotify API and decodes API response to SpotifyPlaylistTracks struct.
 func (s *SpotifyAPI) getPlaylistTracks(id string) (*SpotifyPlaylistTracks, error) {
-	url := "https://api.spotify.com/v1/playlists/" + id + "/tracks"
-	resp, err := s.do("GET", url)
-	if err != nil {
-		return nil, fmt.Errorf("Error while getting response from playlist tracks endpoint: %v", err)
-	}
-
-	// spotify playlist
-	decoder := json.NewDecoder(resp.Body)
-
 	var spotifyPlTracks SpotifyPlaylistTracks
-	err = decoder.Decode(&spotifyPlTracks)
+	err := s.get("/playlists/"+id+"/tracks", &spotifyPlTracks)
 	if err != nil {
-		return nil, fmt.Errorf("Error while getting playlist track info: %v", err)
+		return nil, fmt.Errorf("Error while getting playlist track info: %w", err)
 	}
 
 	return &spotifyPlTracks, nil
@@ -269,7 +336,7 @@ func (s *SpotifyAPI) HandleAlbum(id string) ([]SpotifyPlaylist, error) {
 
 	//this is album playlist so track covers will be
 	//same for all the tracks.
-	coverUrl := albumTracks.Images[1].Url
+	coverUrl := coverUrl(albumTracks.Images)
 
 	items := albumTracks.Tracks.Items
 	for _, value := range items {
@@ -284,9 +351,12 @@ func (s *SpotifyAPI) HandleAlbum(id string) ([]SpotifyPlaylist, error) {
 		}
 
 		spotifyPlaylist := SpotifyPlaylist{
+			TrackID:     value.ID,
 			TrackName:   trackName,
 			CoverUrl:    coverUrl,
 			ArtistNames: artistNames,
+			Duration:    time.Duration(value.DurationMs) * time.Millisecond,
+			Explicit:    value.Explicit,
 		}
 
 		playlist = append(playlist, spotifyPlaylist)
@@ -297,20 +367,17 @@ func (s *SpotifyAPI) HandleAlbum(id string) ([]SpotifyPlaylist, error) {
 //getAlbumTracks sends request to get information about album's tracks to
 //Spotify API and decodes API Response to SpotifyAlbumTracks struct.
 func (s *SpotifyAPI) getAlbumTracks(id string) (*SpotifyAlbumTracks, error) {
-	url := "https://api.spotify.com/v1/albums/" + id
-
-	resp, err := s.do("GET", url)
-	if err != nil {
-		return nil, fmt.Errorf("Error while getting album tracks info: %v", err)
+	var spotifyAlbumTracks SpotifyAlbumTracks
+	if s.Cache.Get(cache.SpotifyAlbum, id, &spotifyAlbumTracks) {
+		return &spotifyAlbumTracks, nil
 	}
 
-	decoder := json.NewDecoder(resp.Body)
-	var spotifyAlbumTracks SpotifyAlbumTracks
-	err = decoder.Decode(&spotifyAlbumTracks)
+	err := s.get("/albums/"+id, &spotifyAlbumTracks)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("Error while getting album tracks info: %w", err)
 	}
 
+	s.Cache.Set(cache.SpotifyAlbum, id, &spotifyAlbumTracks)
 	return &spotifyAlbumTracks, nil
 }
 
@@ -331,9 +398,12 @@ func (s *SpotifyAPI) HandleTrack(id string) ([]SpotifyPlaylist, error) {
 	}
 
 	spotifyPlaylist := SpotifyPlaylist{
+		TrackID:     id,
 		TrackName:   track.Name,
-		CoverUrl:    track.Images[1].Url,
+		CoverUrl:    coverUrl(track.Album.Images),
 		ArtistNames: artistNames,
+		Duration:    time.Duration(track.DurationMs) * time.Millisecond,
+		Explicit:    track.Explicit,
 	}
 	playlist = append(playlist, spotifyPlaylist)
 	return playlist, nil
@@ -342,19 +412,60 @@ func (s *SpotifyAPI) HandleTrack(id string) ([]SpotifyPlaylist, error) {
 //getTrack sends request to get information about track to
 //Spotify API and decodes API Response to SpotifySingleTrack struct.
 func (s *SpotifyAPI) getTrack(id string) (*SpotifySingleTrack, error) {
-	url := "https://api.spotify.com/v1/tracks/" + id
+	var spotifySingleTrack SpotifySingleTrack
+	if s.Cache.Get(cache.SpotifyTrack, id, &spotifySingleTrack) {
+		return &spotifySingleTrack, nil
+	}
 
-	resp, err := s.do("GET", url)
+	err := s.get("/tracks/"+id, &spotifySingleTrack)
 	if err != nil {
-		return nil, fmt.Errorf("Error while getting track info: %v", err)
+		return nil, fmt.Errorf("Error while getting track info: %w", err)
 	}
 
-	decoder := json.NewDecoder(resp.Body)
-	var spotifySingleTrack SpotifySingleTrack
-	err = decoder.Decode(&spotifySingleTrack)
+	s.Cache.Set(cache.SpotifyTrack, id, &spotifySingleTrack)
+	return &spotifySingleTrack, nil
+}
+
+//HandleArtist eliminates required information to create a download queue in
+//the bot package from the getArtistTopTracks function response and creates a
+//slice of SpotifyPlaylist struct.
+func (s *SpotifyAPI) HandleArtist(id string) ([]SpotifyPlaylist, error) {
+	topTracks, err := s.getArtistTopTracks(id)
 	if err != nil {
 		return nil, err
 	}
 
-	return &spotifySingleTrack, nil
+	playlist := []SpotifyPlaylist{}
+
+	for _, value := range topTracks.Tracks {
+		coverUrl := coverUrl(value.Album.Images)
+
+		artistNames := ""
+		for _, artist := range value.Artists {
+			artistNames += artist.Name + " "
+		}
+
+		spotifyPlaylist := SpotifyPlaylist{
+			TrackID:     value.ID,
+			TrackName:   value.Name,
+			CoverUrl:    coverUrl,
+			ArtistNames: artistNames,
+			Duration:    time.Duration(value.DurationMs) * time.Millisecond,
+			Explicit:    value.Explicit,
+		}
+		playlist = append(playlist, spotifyPlaylist)
+	}
+	return playlist, nil
+}
+
+//getArtistTopTracks sends request to get top tracks of the artist to
+//Spotify API and decodes API Response to SpotifyArtistTopTracks struct.
+func (s *SpotifyAPI) getArtistTopTracks(id string) (*SpotifyArtistTopTracks, error) {
+	var spotifyTopTracks SpotifyArtistTopTracks
+	err := s.get("/artists/"+id+"/top-tracks?market=US", &spotifyTopTracks)
+	if err != nil {
+		return nil, fmt.Errorf("Error while getting artist top tracks: %w", err)
+	}
+
+	return &spotifyTopTracks, nil
 }
@@ -0,0 +1,259 @@
+package spotify
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+//fakeTokenSource returns tokens that expire after the given duration.
+type fakeTokenSource struct {
+	calls     int
+	expiresIn time.Duration
+	err       error
+}
+
+func (f *fakeTokenSource) Token() (*oauth2.Token, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &oauth2.Token{
+		AccessToken: "token",
+		TokenType:   "Bearer",
+		Expiry:      time.Now().Add(f.expiresIn),
+	}, nil
+}
+
+func TestCachingTokenSource(t *testing.T) {
+	tables := []struct {
+		expiresIn time.Duration
+		calls     int
+	}{
+		//token is valid for an hour, so it's requested once.
+		{time.Hour, 1},
+		//token expires before the refresh margin, so it's requested every time.
+		{30 * time.Second, 3},
+	}
+
+	for _, table := range tables {
+		source := &fakeTokenSource{expiresIn: table.expiresIn}
+		ts := &cachingTokenSource{source: source, refreshBefore: time.Minute}
+
+		for i := 0; i < 3; i++ {
+			token, err := ts.Token()
+			if err != nil {
+				t.Fatalf("Got error: %v", err)
+			}
+			if token.AccessToken != "token" {
+				t.Errorf("Token is incorrect, got: %s", token.AccessToken)
+			}
+		}
+
+		if source.calls != table.calls {
+			t.Errorf("Token request count is incorrect, got: %d, want: %d", source.calls, table.calls)
+		}
+	}
+}
+
+func TestCachingTokenSourceError(t *testing.T) {
+	ts := &cachingTokenSource{
+		source:        &fakeTokenSource{err: errors.New("invalid_client")},
+		refreshBefore: time.Minute,
+	}
+
+	token, err := ts.Token()
+	if err == nil || token != nil {
+		t.Errorf("Expected an error, got: %v, %v", token, err)
+	}
+}
+
+//newTokenServer returns a test token endpoint that gives tokens
+//expiring after expiresIn seconds and counts the token requests.
+func newTokenServer(expiresIn int, calls *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token": "token%d", "token_type": "Bearer", "expires_in": %d}`, *calls, expiresIn)
+	}))
+}
+
+func TestClientCredentialsTokenSource(t *testing.T) {
+	tables := []struct {
+		expiresIn int
+		calls     int
+	}{
+		//token is valid for an hour, so it's requested once.
+		{3600, 1},
+		//token expires before the refresh margin, so a new one is requested every time.
+		{30, 3},
+	}
+
+	for _, table := range tables {
+		calls := 0
+		server := newTokenServer(table.expiresIn, &calls)
+		ts := newTokenSource(&clientcredentials.Config{
+			ClientID:     "id",
+			ClientSecret: "secret",
+			TokenURL:     server.URL,
+		})
+
+		for i := 0; i < 3; i++ {
+			if _, err := ts.Token(); err != nil {
+				t.Fatalf("Got error: %v", err)
+			}
+		}
+
+		if calls != table.calls {
+			t.Errorf("Token request count is incorrect for expires_in %d, got: %d, want: %d", table.expiresIn, calls, table.calls)
+		}
+		server.Close()
+	}
+}
+
+//newTestSpotifyAPI returns a SpotifyAPI that sends requests to the
+//given test server with a static token and records waits instead of
+//sleeping.
+func newTestSpotifyAPI(server *httptest.Server, waits *[]time.Duration) *SpotifyAPI {
+	return &SpotifyAPI{
+		BaseURL:     server.URL,
+		MaxRetries:  DefaultMaxRetries,
+		tokenSource: &fakeTokenSource{expiresIn: time.Hour},
+		client:      server.Client(),
+		sleep: func(d time.Duration) {
+			*waits = append(*waits, d)
+		},
+	}
+}
+
+func TestGetStatusCodes(t *testing.T) {
+	tables := []struct {
+		status int
+		target error
+		calls  int
+	}{
+		{http.StatusNotFound, ErrNotFound, 1},
+		{http.StatusUnauthorized, ErrUnauthorized, 2},
+		{http.StatusTooManyRequests, ErrRateLimited, DefaultMaxRetries + 1},
+	}
+
+	for _, table := range tables {
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(table.status)
+			w.Write([]byte(`{"error": {"status": 0, "message": "test error"}}`))
+		}))
+
+		var waits []time.Duration
+		spotifyAPI := newTestSpotifyAPI(server, &waits)
+
+		_, err := spotifyAPI.HandleTrack("2az3iTNyJ1M1JJnsU2Gq6H")
+		if !errors.Is(err, table.target) {
+			t.Errorf("Error is incorrect for status %d, got: %v, want: %v", table.status, err, table.target)
+		}
+
+		if calls != table.calls {
+			t.Errorf("Request count is incorrect for status %d, got: %d, want: %d", table.status, calls, table.calls)
+		}
+		server.Close()
+	}
+}
+
+func TestGetRetryAfter(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("Authorization header is incorrect, got: %s", r.Header.Get("Authorization"))
+		}
+
+		switch calls {
+		case 1:
+			w.Header().Set("Retry-After", "3")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.Write([]byte(`{"name": "Thunderstruck", "artists": [{"name": "AC/DC"}], "album": {"images": []}}`))
+		}
+	}))
+	defer server.Close()
+
+	var waits []time.Duration
+	spotifyAPI := newTestSpotifyAPI(server, &waits)
+
+	playlist, err := spotifyAPI.HandleTrack("2az3iTNyJ1M1JJnsU2Gq6H")
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+
+	if len(waits) != 2 || waits[0] != 3*time.Second || waits[1] != 2*retryBackoff {
+		t.Errorf("Waits are incorrect, got: %v", waits)
+	}
+
+	//track without images has to get the default cover.
+	if len(playlist) != 1 || playlist[0].TrackName != "Thunderstruck" || playlist[0].CoverUrl != DEFAULTCOVERURL {
+		t.Errorf("Track is incorrect, got: %+v", playlist)
+	}
+}
+
+func TestGetUnauthorizedRenewsToken(t *testing.T) {
+	tokenCalls := 0
+	tokenServer := newTokenServer(3600, &tokenCalls)
+	defer tokenServer.Close()
+
+	var auths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auths = append(auths, r.Header.Get("Authorization"))
+		if len(auths) == 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"name": "Thunderstruck", "artists": [{"name": "AC/DC"}], "album": {"images": []}}`))
+	}))
+	defer server.Close()
+
+	var waits []time.Duration
+	spotifyAPI := newTestSpotifyAPI(server, &waits)
+	spotifyAPI.tokenSource = newTokenSource(&clientcredentials.Config{
+		ClientID:     "id",
+		ClientSecret: "secret",
+		TokenURL:     tokenServer.URL,
+	})
+
+	if _, err := spotifyAPI.HandleTrack("2az3iTNyJ1M1JJnsU2Gq6H"); err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+
+	//rejected token has to be replaced with a newly requested one.
+	if tokenCalls != 2 || len(auths) != 2 || auths[0] != "Bearer token1" || auths[1] != "Bearer token2" {
+		t.Errorf("Retry is not made with a new token, token requests: %d, got: %v", tokenCalls, auths)
+	}
+}
+
+func TestCoverUrl(t *testing.T) {
+	tables := []struct {
+		images []SpotifyImage
+		url    string
+	}{
+		{nil, DEFAULTCOVERURL},
+		{[]SpotifyImage{{Url: "large"}}, "large"},
+		{[]SpotifyImage{{Url: "large"}, {Url: "medium"}, {Url: "small"}}, "medium"},
+	}
+
+	for _, table := range tables {
+		url := coverUrl(table.images)
+
+		if url != table.url {
+			t.Errorf("Cover url is incorrect, got: %s, want: %s", url, table.url)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package stream
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+//Reader strips ICY metadata blocks from an Icecast/SHOUTcast stream.
+//Server sends metaInt bytes of audio followed by one length byte and
+//length*16 bytes of metadata like "StreamTitle='Artist - Song';".
+type Reader struct {
+	r         io.Reader
+	metaInt   int
+	remaining int //audio bytes left until the next metadata block
+	title     string
+	onTitle   func(string)
+}
+
+//NewReader returns a Reader that reads audio data from r and calls
+//onTitle when the stream title in the metadata changes.
+func NewReader(r io.Reader, metaInt int, onTitle func(string)) *Reader {
+	return &Reader{
+		r:         r,
+		metaInt:   metaInt,
+		remaining: metaInt,
+		onTitle:   onTitle,
+	}
+}
+
+func (ir *Reader) Read(p []byte) (int, error) {
+	if ir.remaining == 0 {
+		err := ir.readMetadata()
+		if err != nil {
+			return 0, err
+		}
+		ir.remaining = ir.metaInt
+	}
+
+	if len(p) > ir.remaining {
+		p = p[:ir.remaining]
+	}
+
+	n, err := ir.r.Read(p)
+	ir.remaining -= n
+	return n, err
+}
+
+//readMetadata reads one metadata block and reports its stream title.
+func (ir *Reader) readMetadata() error {
+	lengthByte := make([]byte, 1)
+	_, err := io.ReadFull(ir.r, lengthByte)
+	if err != nil {
+		return err
+	}
+
+	length := int(lengthByte[0]) * 16
+	if length == 0 {
+		return nil
+	}
+
+	meta := make([]byte, length)
+	_, err = io.ReadFull(ir.r, meta)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("Error while reading ICY metadata: %v", err)
+	}
+
+	title, ok := ParseStreamTitle(string(meta))
+	if !ok || title == ir.title {
+		return nil
+	}
+
+	ir.title = title
+	if ir.onTitle != nil {
+		ir.onTitle(title)
+	}
+	return nil
+}
+
+//ParseStreamTitle returns the StreamTitle value of the given ICY metadata.
+//Second return value is false if metadata doesn't contain a StreamTitle.
+func ParseStreamTitle(meta string) (string, bool) {
+	meta = strings.TrimRight(meta, "\x00")
+
+	const key = "StreamTitle='"
+	start := strings.Index(meta, key)
+	if start < 0 {
+		return "", false
+	}
+	value := meta[start+len(key):]
+
+	//titles may contain quotes themselves, so value ends at the
+	//first "';" or at the last quote of the metadata.
+	end := strings.Index(value, "';")
+	if end < 0 {
+		end = strings.LastIndex(value, "'")
+	}
+	if end < 0 {
+		return "", false
+	}
+
+	return strings.TrimSpace(value[:end]), true
+}
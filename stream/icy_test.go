@@ -0,0 +1,101 @@
+package stream
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+//icyBlock builds an ICY metadata block for the given metadata text.
+func icyBlock(meta string) []byte {
+	length := (len(meta) + 15) / 16
+	block := make([]byte, 1+length*16)
+	block[0] = byte(length)
+	copy(block[1:], meta)
+	return block
+}
+
+func TestParseStreamTitle(t *testing.T) {
+	tables := []struct {
+		meta  string
+		title string
+		ok    bool
+	}{
+		{"StreamTitle='Daft Punk - Around the World';StreamUrl='';", "Daft Punk - Around the World", true},
+		{"StreamTitle='Guns N' Roses - Patience';\x00\x00\x00", "Guns N' Roses - Patience", true},
+		{"StreamTitle='';", "", true},
+		{"StreamUrl='http://example.com';", "", false},
+	}
+
+	for _, table := range tables {
+		title, ok := ParseStreamTitle(table.meta)
+
+		if title != table.title || ok != table.ok {
+			t.Errorf("ParseStreamTitle(%q) got: (%s, %v), want: (%s, %v)", table.meta, title, ok, table.title, table.ok)
+		}
+	}
+}
+
+func TestReader(t *testing.T) {
+	var raw bytes.Buffer
+	raw.WriteString("aaaa")
+	raw.Write(icyBlock("StreamTitle='First';"))
+	raw.WriteString("bbbb")
+	raw.Write([]byte{0})
+	raw.WriteString("cccc")
+	raw.Write(icyBlock("StreamTitle='First';"))
+	raw.WriteString("dddd")
+	raw.Write(icyBlock("StreamTitle='Second';"))
+	raw.WriteString("ee")
+
+	var titles []string
+	reader := NewReader(&raw, 4, func(title string) {
+		titles = append(titles, title)
+	})
+
+	audio, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+
+	if string(audio) != "aaaabbbbccccddddee" {
+		t.Errorf("Audio data is incorrect, got: %s", audio)
+	}
+
+	if strings.Join(titles, ",") != "First,Second" {
+		t.Errorf("Titles are incorrect, got: %v", titles)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/radio":
+			w.Header().Set("Content-Type", "audio/mpeg")
+			w.Header().Set("icy-name", "Test Radio")
+			w.Header().Set("icy-metaint", "16000")
+			w.Write([]byte("audio"))
+		case "/page":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html></html>"))
+		}
+	}))
+	defer server.Close()
+
+	info, err := Probe(server.URL + "/radio")
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+
+	if !info.Live || info.MetaInt != 16000 || info.Title != "Test Radio" || info.Duration != "" {
+		t.Errorf("Radio stream info is incorrect, got: %+v", info)
+	}
+
+	_, err = Probe(server.URL + "/page")
+	if err == nil {
+		t.Errorf("Probe accepted a html page.")
+	}
+}
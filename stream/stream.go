@@ -0,0 +1,198 @@
+package stream
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	probeTimeout = 10 * time.Second
+)
+
+var (
+	//audio file extensions that ffmpeg can play directly from an URL.
+	audioExtensions = map[string]bool{
+		".mp3":  true,
+		".ogg":  true,
+		".oga":  true,
+		".opus": true,
+		".flac": true,
+		".m4a":  true,
+		".aac":  true,
+		".wav":  true,
+	}
+
+	httpClient = &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			ResponseHeaderTimeout: probeTimeout,
+		},
+	}
+)
+
+//Info holds the information that is required to play a direct audio
+//file URL or an internet radio stream.
+type Info struct {
+	URL         string
+	Title       string
+	ContentType string
+	Duration    string //empty for live streams
+	Live        bool   //true for endless Icecast/SHOUTcast streams
+	MetaInt     int    //ICY metadata interval in bytes, 0 if there is none
+}
+
+//IsHttpUrl checks given string is an http(s) URL or not.
+func IsHttpUrl(rawUrl string) bool {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+//IsAudioFileUrl checks given URL's path ends with a known audio file extension.
+func IsAudioFileUrl(rawUrl string) bool {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return false
+	}
+	return audioExtensions[strings.ToLower(path.Ext(u.Path))]
+}
+
+//Probe requests the given URL and decides whether it is a playable
+//audio file or a live radio stream. The response body is not read,
+//so probing an endless stream returns as soon as headers arrive.
+func Probe(rawUrl string) (*Info, error) {
+	resp, err := get(rawUrl)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	contentType := strings.ToLower(strings.TrimSpace(strings.Split(resp.Header.Get("Content-Type"), ";")[0]))
+	if !isAudioContentType(contentType) && !IsAudioFileUrl(rawUrl) {
+		return nil, fmt.Errorf("%s is not an audio resource (Content-Type: %s)", rawUrl, contentType)
+	}
+
+	metaInt, _ := strconv.Atoi(resp.Header.Get("icy-metaint"))
+	live := metaInt > 0 || resp.Header.Get("icy-name") != "" || resp.Header.Get("icy-br") != ""
+	//radio streams don't advertise a length, files served without
+	//one are still played as files if they have an audio extension.
+	if resp.ContentLength < 0 && !IsAudioFileUrl(rawUrl) {
+		live = true
+	}
+
+	info := &Info{
+		URL:         rawUrl,
+		Title:       resp.Header.Get("icy-name"),
+		ContentType: contentType,
+		Live:        live,
+		MetaInt:     metaInt,
+	}
+
+	if info.Title == "" {
+		info.Title = titleFromUrl(rawUrl)
+	}
+
+	if !live {
+		duration, err := probeDuration(rawUrl)
+		if err == nil {
+			info.Duration = duration.String()
+		}
+	}
+
+	return info, nil
+}
+
+//Open starts reading the stream in the given URL. If the server sends
+//ICY metadata, metadata blocks are removed from the returned reader
+//and every new stream title is passed to onTitle.
+func Open(rawUrl string, onTitle func(string)) (io.ReadCloser, error) {
+	resp, err := get(rawUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	metaInt, _ := strconv.Atoi(resp.Header.Get("icy-metaint"))
+	if metaInt <= 0 {
+		return resp.Body, nil
+	}
+
+	return &readCloser{
+		Reader: NewReader(resp.Body, metaInt, onTitle),
+		Closer: resp.Body,
+	}, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func get(rawUrl string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", rawUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Error while creating stream request: %v", err)
+	}
+	req.Header.Set("Icy-MetaData", "1")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error while requesting stream: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("Error while requesting stream: %s", resp.Status)
+	}
+	return resp, nil
+}
+
+//probeDuration asks ffprobe the duration of the audio file in the given URL.
+func probeDuration(rawUrl string) (time.Duration, error) {
+	out, err := exec.Command("ffprobe", "-v", "error", "-show_entries",
+		"format=duration", "-of", "default=noprint_wrappers=1:nokey=1", rawUrl).Output()
+	if err != nil {
+		return 0, fmt.Errorf("Error while probing duration of %s: %v", rawUrl, err)
+	}
+
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	if err != nil {
+		return 0, fmt.Errorf("Error while parsing duration of %s: %v", rawUrl, err)
+	}
+	return time.Duration(seconds * float64(time.Second)).Round(time.Second), nil
+}
+
+func isAudioContentType(contentType string) bool {
+	//radio playlist files are served with audio content types too.
+	if strings.Contains(contentType, "mpegurl") || strings.Contains(contentType, "scpls") {
+		return false
+	}
+	return strings.HasPrefix(contentType, "audio/") || contentType == "application/ogg"
+}
+
+//titleFromUrl returns the file name in the given URL to use it as a title.
+func titleFromUrl(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return rawUrl
+	}
+
+	name := path.Base(u.Path)
+	if name == "/" || name == "." {
+		return u.Host
+	}
+
+	name, err = url.PathUnescape(name)
+	if err != nil {
+		return u.Host
+	}
+	return name
+}
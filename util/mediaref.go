@@ -0,0 +1,339 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Provider is the service that a media reference belongs to.
+type Provider int
+
+const (
+	ProviderUnknown Provider = iota
+	ProviderYoutube
+	ProviderSpotify
+)
+
+//Kind is the type of the media that a media reference points to.
+type Kind int
+
+const (
+	KindUnknown Kind = iota
+	KindTrack
+	KindAlbum
+	KindPlaylist
+	KindArtist
+	KindChannel
+	KindMix
+)
+
+//ErrNotMediaRef is returned by ParseMediaRef when the given string
+//is not a Youtube or Spotify link at all, so it can be handled as
+//something else like a search query.
+var ErrNotMediaRef = errors.New("not a Youtube or Spotify reference")
+
+var (
+	ytVideoIDRegex   = regexp.MustCompile(`^[a-zA-Z0-9_-]{11}$`)
+	ytListIDRegex    = regexp.MustCompile(`^[a-zA-Z0-9_-]{2,}$`)
+	ytChannelIDRegex = regexp.MustCompile(`^UC[a-zA-Z0-9_-]{22}$`)
+	spotifyIDRegex   = regexp.MustCompile(`^[a-zA-Z0-9]{22}$`)
+	//start time formats: "90", "90s", "1m30s", "1h2m3s"
+	startTimeRegex = regexp.MustCompile(`^(?:(\d+)h)?(?:(\d+)m)?(?:(\d+)s?)?$`)
+
+	ytHosts = map[string]bool{
+		"youtube.com":              true,
+		"www.youtube.com":          true,
+		"m.youtube.com":            true,
+		"music.youtube.com":        true,
+		"youtube-nocookie.com":     true,
+		"www.youtube-nocookie.com": true,
+	}
+
+	spotifyKinds = map[string]Kind{
+		"track":    KindTrack,
+		"album":    KindAlbum,
+		"playlist": KindPlaylist,
+		"artist":   KindArtist,
+	}
+)
+
+//MediaRef is a parsed Youtube or Spotify link.
+type MediaRef struct {
+	Provider      Provider
+	Kind          Kind
+	ID            string
+	ListID        string        //playlist of a track link like "watch?v=...&list=..."
+	StartTime     time.Duration //"t" parameter of a Youtube link
+	PlaylistIndex int           //1-based "index" parameter, 0 if not given
+}
+
+func (p Provider) String() string {
+	switch p {
+	case ProviderYoutube:
+		return "youtube"
+	case ProviderSpotify:
+		return "spotify"
+	}
+	return "unknown"
+}
+
+func (k Kind) String() string {
+	switch k {
+	case KindTrack:
+		return "track"
+	case KindAlbum:
+		return "album"
+	case KindPlaylist:
+		return "playlist"
+	case KindArtist:
+		return "artist"
+	case KindChannel:
+		return "channel"
+	case KindMix:
+		return "mix"
+	}
+	return "unknown"
+}
+
+//ParseMediaRef parses Youtube links (youtube.com, youtu.be, music.youtube.com,
+//shorts, playlists, channels) and Spotify URIs and links. If given string is
+//not a Youtube or Spotify link, ErrNotMediaRef is returned.
+func ParseMediaRef(raw string) (*MediaRef, error) {
+	raw = strings.TrimSpace(raw)
+
+	if strings.HasPrefix(raw, "spotify:") {
+		return parseSpotifyURI(raw)
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		//links are often pasted without the scheme.
+		u, err = url.Parse("https://" + raw)
+		if err != nil {
+			return nil, ErrNotMediaRef
+		}
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, ErrNotMediaRef
+	}
+
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case host == "youtu.be":
+		return parseYoutubeShortUrl(u)
+	case ytHosts[host]:
+		return parseYoutubeUrl(u)
+	case host == "open.spotify.com" || host == "play.spotify.com":
+		return parseSpotifyUrl(u)
+	}
+	return nil, ErrNotMediaRef
+}
+
+//ParsePlaylistRef parses Youtube and Spotify links like ParseMediaRef and
+//also accepts bare playlist IDs. 22 character IDs are Spotify playlist IDs,
+//other IDs are Youtube playlist IDs.
+func ParsePlaylistRef(raw string) (*MediaRef, error) {
+	raw = strings.TrimSpace(raw)
+
+	ref, err := ParseMediaRef(raw)
+	if err != ErrNotMediaRef {
+		return ref, err
+	}
+
+	switch {
+	case spotifyIDRegex.MatchString(raw):
+		return &MediaRef{Provider: ProviderSpotify, Kind: KindPlaylist, ID: raw}, nil
+	case ytListIDRegex.MatchString(raw) && !strings.Contains(raw, "."):
+		return newYoutubeListRef(raw, &url.URL{Path: raw})
+	}
+	return nil, fmt.Errorf("Not a playlist link or ID: %s", raw)
+}
+
+//parseSpotifyURI parses URIs like spotify:track:ID and the legacy
+//spotify:user:NAME:playlist:ID format.
+func parseSpotifyURI(uri string) (*MediaRef, error) {
+	parts := strings.Split(uri, ":")
+	if len(parts) == 5 && parts[1] == "user" {
+		parts = []string{parts[0], parts[3], parts[4]}
+	}
+
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("Unknown Spotify URI format: %s", uri)
+	}
+	return newSpotifyRef(parts[1], parts[2], uri)
+}
+
+//parseSpotifyUrl parses links like https://open.spotify.com/track/ID?si=...,
+//including localized (/intl-tr/...), embed and user playlist paths.
+func parseSpotifyUrl(u *url.URL) (*MediaRef, error) {
+	segments := pathSegments(u.Path)
+
+	if len(segments) > 0 && (strings.HasPrefix(segments[0], "intl-") || segments[0] == "embed") {
+		segments = segments[1:]
+	}
+
+	//format: /user/NAME/playlist/ID
+	if len(segments) == 4 && segments[0] == "user" {
+		segments = segments[2:]
+	}
+
+	if len(segments) != 2 {
+		return nil, fmt.Errorf("Unknown Spotify URL format: %s", u.String())
+	}
+	return newSpotifyRef(segments[0], segments[1], u.String())
+}
+
+func newSpotifyRef(kindName, id, raw string) (*MediaRef, error) {
+	kind, ok := spotifyKinds[kindName]
+	if !ok {
+		return nil, fmt.Errorf("Unsupported Spotify link type \"%s\": %s", kindName, raw)
+	}
+
+	if !spotifyIDRegex.MatchString(id) {
+		return nil, fmt.Errorf("Invalid Spotify ID \"%s\": %s", id, raw)
+	}
+
+	return &MediaRef{
+		Provider: ProviderSpotify,
+		Kind:     kind,
+		ID:       id,
+	}, nil
+}
+
+//parseYoutubeShortUrl parses links like https://youtu.be/ID?t=30.
+func parseYoutubeShortUrl(u *url.URL) (*MediaRef, error) {
+	segments := pathSegments(u.Path)
+	if len(segments) != 1 {
+		return nil, fmt.Errorf("Unknown Youtube URL format: %s", u.String())
+	}
+	return newYoutubeTrackRef(segments[0], u)
+}
+
+//parseYoutubeUrl parses youtube.com and music.youtube.com links.
+func parseYoutubeUrl(u *url.URL) (*MediaRef, error) {
+	segments := pathSegments(u.Path)
+	query := u.Query()
+
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("Unknown Youtube URL format: %s", u.String())
+	}
+
+	switch segments[0] {
+	case "watch":
+		return newYoutubeTrackRef(query.Get("v"), u)
+	case "shorts", "embed", "v", "live":
+		if len(segments) != 2 {
+			break
+		}
+		return newYoutubeTrackRef(segments[1], u)
+	case "playlist":
+		return newYoutubeListRef(query.Get("list"), u)
+	case "channel":
+		if len(segments) < 2 || !ytChannelIDRegex.MatchString(segments[1]) {
+			break
+		}
+		return &MediaRef{Provider: ProviderYoutube, Kind: KindChannel, ID: segments[1]}, nil
+	case "c", "user":
+		return nil, errYoutubeChannelName(u)
+	default:
+		//format: https://www.youtube.com/@handle
+		if strings.HasPrefix(segments[0], "@") {
+			return nil, errYoutubeChannelName(u)
+		}
+	}
+	return nil, fmt.Errorf("Unknown Youtube URL format: %s", u.String())
+}
+
+//errYoutubeChannelName is returned for the channel links with names or
+//handles, only the channel IDs can be listed without searching the channel.
+func errYoutubeChannelName(u *url.URL) error {
+	return fmt.Errorf("Only Youtube channel links with channel IDs like youtube.com/channel/UC... are supported: %s", u.String())
+}
+
+func newYoutubeTrackRef(videoID string, u *url.URL) (*MediaRef, error) {
+	if !ytVideoIDRegex.MatchString(videoID) {
+		return nil, fmt.Errorf("Invalid Youtube video ID \"%s\": %s", videoID, u.String())
+	}
+
+	query := u.Query()
+	ref := &MediaRef{
+		Provider: ProviderYoutube,
+		Kind:     KindTrack,
+		ID:       videoID,
+	}
+
+	if listID := query.Get("list"); ytListIDRegex.MatchString(listID) {
+		ref.ListID = listID
+	}
+
+	if index, err := strconv.Atoi(query.Get("index")); err == nil && index > 0 {
+		ref.PlaylistIndex = index
+	}
+
+	startTime := query.Get("t")
+	if startTime == "" {
+		startTime = query.Get("start")
+	}
+	if startTime == "" && strings.HasPrefix(u.Fragment, "t=") {
+		startTime = strings.TrimPrefix(u.Fragment, "t=")
+	}
+	ref.StartTime = parseStartTime(startTime)
+
+	return ref, nil
+}
+
+func newYoutubeListRef(listID string, u *url.URL) (*MediaRef, error) {
+	if !ytListIDRegex.MatchString(listID) {
+		return nil, fmt.Errorf("Invalid Youtube playlist ID \"%s\": %s", listID, u.String())
+	}
+
+	kind := KindPlaylist
+	//auto generated mixes can't be listed as playlists.
+	if strings.HasPrefix(listID, "RD") {
+		kind = KindMix
+	}
+
+	return &MediaRef{
+		Provider: ProviderYoutube,
+		Kind:     kind,
+		ID:       listID,
+	}, nil
+}
+
+//parseStartTime parses Youtube "t" parameter values. Returns 0 for
+//empty or malformed values.
+func parseStartTime(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	matches := startTimeRegex.FindStringSubmatch(value)
+	if matches == nil {
+		return 0
+	}
+
+	hours, _ := strconv.Atoi(matches[1])
+	minutes, _ := strconv.Atoi(matches[2])
+	seconds, _ := strconv.Atoi(matches[3])
+
+	return time.Duration(hours)*time.Hour +
+		time.Duration(minutes)*time.Minute +
+		time.Duration(seconds)*time.Second
+}
+
+//pathSegments returns non empty segments of the given URL path.
+func pathSegments(urlPath string) []string {
+	segments := []string{}
+	for _, segment := range strings.Split(urlPath, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
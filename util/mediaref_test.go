@@ -0,0 +1,167 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseMediaRef(t *testing.T) {
+	tables := []struct {
+		url           string
+		provider      Provider
+		kind          Kind
+		id            string
+		listID        string
+		startTime     time.Duration
+		playlistIndex int
+	}{
+		//spotify links
+		{spotifyPlUrl1, ProviderSpotify, KindPlaylist, "37i9dQZF1DZ06evO1fLAgU", "", 0, 0},
+		{spotifyAlbumUrl1, ProviderSpotify, KindAlbum, "4HklP3MTUYViTMiNdj43R3", "", 0, 0},
+		{spotifyTrackUrl1, ProviderSpotify, KindTrack, "2az3iTNyJ1M1JJnsU2Gq6H", "", 0, 0},
+		{"https://open.spotify.com/artist/0oSGxfWSnnOXhD2fKuz2Gy", ProviderSpotify, KindArtist, "0oSGxfWSnnOXhD2fKuz2Gy", "", 0, 0},
+		{"https://open.spotify.com/intl-tr/track/2az3iTNyJ1M1JJnsU2Gq6H?si=x", ProviderSpotify, KindTrack, "2az3iTNyJ1M1JJnsU2Gq6H", "", 0, 0},
+		{"https://open.spotify.com/embed/playlist/76tzi26o8O920CYAvVbeYO", ProviderSpotify, KindPlaylist, "76tzi26o8O920CYAvVbeYO", "", 0, 0},
+		{"https://open.spotify.com/user/spotify/playlist/76tzi26o8O920CYAvVbeYO", ProviderSpotify, KindPlaylist, "76tzi26o8O920CYAvVbeYO", "", 0, 0},
+		{"open.spotify.com/album/4HklP3MTUYViTMiNdj43R3", ProviderSpotify, KindAlbum, "4HklP3MTUYViTMiNdj43R3", "", 0, 0},
+
+		//spotify URIs
+		{"spotify:playlist:76tzi26o8O920CYAvVbeYO", ProviderSpotify, KindPlaylist, "76tzi26o8O920CYAvVbeYO", "", 0, 0},
+		{"spotify:track:2az3iTNyJ1M1JJnsU2Gq6H", ProviderSpotify, KindTrack, "2az3iTNyJ1M1JJnsU2Gq6H", "", 0, 0},
+		{"spotify:album:4HklP3MTUYViTMiNdj43R3", ProviderSpotify, KindAlbum, "4HklP3MTUYViTMiNdj43R3", "", 0, 0},
+		{"spotify:artist:0oSGxfWSnnOXhD2fKuz2Gy", ProviderSpotify, KindArtist, "0oSGxfWSnnOXhD2fKuz2Gy", "", 0, 0},
+		{"spotify:user:spotify:playlist:76tzi26o8O920CYAvVbeYO", ProviderSpotify, KindPlaylist, "76tzi26o8O920CYAvVbeYO", "", 0, 0},
+
+		//youtube videos
+		{ytTrackUrl1, ProviderYoutube, KindTrack, "B9v8jLBrvug", "", 0, 0},
+		{ytTrackUrl2, ProviderYoutube, KindTrack, "SlPhMPnQ58k", "", 0, 0},
+		{"youtube.com/watch?v=B9v8jLBrvug", ProviderYoutube, KindTrack, "B9v8jLBrvug", "", 0, 0},
+		{"https://m.youtube.com/watch?v=B9v8jLBrvug&feature=share", ProviderYoutube, KindTrack, "B9v8jLBrvug", "", 0, 0},
+		{"https://music.youtube.com/watch?v=B9v8jLBrvug", ProviderYoutube, KindTrack, "B9v8jLBrvug", "", 0, 0},
+		{"https://www.youtube.com/shorts/SlPhMPnQ58k", ProviderYoutube, KindTrack, "SlPhMPnQ58k", "", 0, 0},
+		{"https://www.youtube.com/embed/SlPhMPnQ58k", ProviderYoutube, KindTrack, "SlPhMPnQ58k", "", 0, 0},
+		{"https://www.youtube.com/live/SlPhMPnQ58k?si=abc", ProviderYoutube, KindTrack, "SlPhMPnQ58k", "", 0, 0},
+
+		//youtube start times
+		{"https://youtu.be/SlPhMPnQ58k?t=90", ProviderYoutube, KindTrack, "SlPhMPnQ58k", "", 90 * time.Second, 0},
+		{"https://www.youtube.com/watch?v=B9v8jLBrvug&t=1m30s", ProviderYoutube, KindTrack, "B9v8jLBrvug", "", 90 * time.Second, 0},
+		{"https://www.youtube.com/watch?v=B9v8jLBrvug&t=1h2m3s", ProviderYoutube, KindTrack, "B9v8jLBrvug", "", time.Hour + 2*time.Minute + 3*time.Second, 0},
+		{"https://www.youtube.com/watch?v=B9v8jLBrvug#t=45s", ProviderYoutube, KindTrack, "B9v8jLBrvug", "", 45 * time.Second, 0},
+		{"https://www.youtube.com/embed/SlPhMPnQ58k?start=30", ProviderYoutube, KindTrack, "SlPhMPnQ58k", "", 30 * time.Second, 0},
+		{"https://www.youtube.com/watch?v=B9v8jLBrvug&t=abc", ProviderYoutube, KindTrack, "B9v8jLBrvug", "", 0, 0},
+
+		//youtube videos in playlists
+		{"https://www.youtube.com/watch?v=B9v8jLBrvug&list=PL4o29bINVT4EG_y-k5jGoOu3-Am8Nvi10&index=3",
+			ProviderYoutube, KindTrack, "B9v8jLBrvug", "PL4o29bINVT4EG_y-k5jGoOu3-Am8Nvi10", 0, 3},
+		{"https://youtu.be/SlPhMPnQ58k?list=PL4o29bINVT4EG_y-k5jGoOu3-Am8Nvi10&t=10",
+			ProviderYoutube, KindTrack, "SlPhMPnQ58k", "PL4o29bINVT4EG_y-k5jGoOu3-Am8Nvi10", 10 * time.Second, 0},
+		{"https://www.youtube.com/watch?v=B9v8jLBrvug&list=RDB9v8jLBrvug&start_radio=1",
+			ProviderYoutube, KindTrack, "B9v8jLBrvug", "RDB9v8jLBrvug", 0, 0},
+
+		//youtube playlists, mixes and channels
+		{ytPlUrl1, ProviderYoutube, KindPlaylist, "PL4o29bINVT4EG_y-k5jGoOu3-Am8Nvi10", "", 0, 0},
+		{"https://music.youtube.com/playlist?list=OLAK5uy_kJkz0Ax7kBGpXTgkeNNgtUMr1O2XiYVmA", ProviderYoutube, KindPlaylist, "OLAK5uy_kJkz0Ax7kBGpXTgkeNNgtUMr1O2XiYVmA", "", 0, 0},
+		{"https://www.youtube.com/playlist?list=RDCLAK5uy_kmPRjHDECIcuVwnKsx2Ng7fyNgFKWNJFs", ProviderYoutube, KindMix, "RDCLAK5uy_kmPRjHDECIcuVwnKsx2Ng7fyNgFKWNJFs", "", 0, 0},
+		{"https://www.youtube.com/channel/UC_x5XG1OV2P6uZZ5FSM9Ttw", ProviderYoutube, KindChannel, "UC_x5XG1OV2P6uZZ5FSM9Ttw", "", 0, 0},
+		{"https://music.youtube.com/channel/UC_x5XG1OV2P6uZZ5FSM9Ttw", ProviderYoutube, KindChannel, "UC_x5XG1OV2P6uZZ5FSM9Ttw", "", 0, 0},
+	}
+
+	for _, table := range tables {
+		ref, err := ParseMediaRef(table.url)
+		if err != nil {
+			t.Errorf("Got error for %s: %v", table.url, err)
+			continue
+		}
+
+		if ref.Provider != table.provider || ref.Kind != table.kind || ref.ID != table.id {
+			t.Errorf("Media ref is incorrect for %s, got: %s %s %s, want: %s %s %s", table.url,
+				ref.Provider, ref.Kind, ref.ID, table.provider, table.kind, table.id)
+		}
+
+		if ref.ListID != table.listID || ref.StartTime != table.startTime || ref.PlaylistIndex != table.playlistIndex {
+			t.Errorf("Media ref details are incorrect for %s, got: %s %v %d, want: %s %v %d", table.url,
+				ref.ListID, ref.StartTime, ref.PlaylistIndex, table.listID, table.startTime, table.playlistIndex)
+		}
+	}
+}
+
+func TestParseMediaRefNotMediaRef(t *testing.T) {
+	tables := []string{
+		spotifyUnknownUrl1,
+		ytUnknownUrl1,
+		"michael jackson billie jean",
+		"http://radio.example.com:8000/stream",
+		"https://example.com/song.mp3",
+		"ftp://www.youtube.com/watch?v=B9v8jLBrvug",
+		"",
+	}
+
+	for _, table := range tables {
+		_, err := ParseMediaRef(table)
+
+		if err != ErrNotMediaRef {
+			t.Errorf("Expected ErrNotMediaRef for %q, got: %v", table, err)
+		}
+	}
+}
+
+func TestParseMediaRefInvalid(t *testing.T) {
+	tables := []string{
+		"https://www.youtube.com/watch?v=short",
+		"https://www.youtube.com/watch",
+		"https://www.youtube.com/feed/trending",
+		"https://www.youtube.com/playlist",
+		"https://youtu.be/",
+		"https://www.youtube.com/channel/notachannelid",
+		"https://www.youtube.com/@GoogleDevelopers",
+		"https://www.youtube.com/c/GoogleDevelopers/videos",
+		"https://www.youtube.com/user/GoogleDevelopers",
+		"https://open.spotify.com/show/2az3iTNyJ1M1JJnsU2Gq6H",
+		"https://open.spotify.com/track/tooshort",
+		"spotify:track:2az3iTNyJ1M1JJnsU2Gq6H:extra",
+		"spotify:episode:2az3iTNyJ1M1JJnsU2Gq6H",
+	}
+
+	for _, table := range tables {
+		ref, err := ParseMediaRef(table)
+
+		if err == nil || err == ErrNotMediaRef {
+			t.Errorf("Expected parse error for %q, got: %+v, %v", table, ref, err)
+		}
+	}
+}
+
+func TestParsePlaylistRef(t *testing.T) {
+	tables := []struct {
+		raw      string
+		provider Provider
+		kind     Kind
+		id       string
+		isErr    bool
+	}{
+		{"76tzi26o8O920CYAvVbeYO", ProviderSpotify, KindPlaylist, "76tzi26o8O920CYAvVbeYO", false},
+		{" PLwiyx1dc3P2JR9N8gQaQN_BCvlSlap7re ", ProviderYoutube, KindPlaylist, "PLwiyx1dc3P2JR9N8gQaQN_BCvlSlap7re", false},
+		{"spotify:album:4HklP3MTUYViTMiNdj43R3", ProviderSpotify, KindAlbum, "4HklP3MTUYViTMiNdj43R3", false},
+		{"https://www.youtube.com/playlist?list=PLwiyx1dc3P2JR9N8gQaQN_BCvlSlap7re", ProviderYoutube, KindPlaylist, "PLwiyx1dc3P2JR9N8gQaQN_BCvlSlap7re", false},
+		{"https://www.youtube.com/playlist", ProviderUnknown, KindUnknown, "", true},
+		{"michael jackson", ProviderUnknown, KindUnknown, "", true},
+		{"example.com", ProviderUnknown, KindUnknown, "", true},
+		{"", ProviderUnknown, KindUnknown, "", true},
+	}
+
+	for _, table := range tables {
+		ref, err := ParsePlaylistRef(table.raw)
+		if (err != nil) != table.isErr {
+			t.Errorf("%q: got error %v, want error: %t.", table.raw, err, table.isErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		if ref.Provider != table.provider || ref.Kind != table.kind || ref.ID != table.id {
+			t.Errorf("%q: got %s %s %s, want %s %s %s.", table.raw, ref.Provider, ref.Kind, ref.ID,
+				table.provider, table.kind, table.id)
+		}
+	}
+}
@@ -15,16 +15,6 @@ import (
 )
 
 const (
-	//Spotify url types
-	SPOTIFYPLAYLISTURL = 1
-	SPOTIFYALBUMURL    = 2
-	SPOTIFYTRACKURL    = 3
-
-	UNKNOWNURL = -1
-
-	YOUTUBEPLAYLISTURL = 11
-	YOUTUBETRACKURL    = 13
-
 	//PATH CONSTS
 	BASECOVERPATH = "cover"
 	BASESONGPATH  = "song"
@@ -33,23 +23,7 @@ const (
 var (
 	letterRunes = []rune("abcdefghijklmnopqrstuvwxyz0123456789")
 
-	//var ytPlaylistUrlRegex = `/^.*(youtu.be\/|list=)([^#\&\?]*).
-	//ytPlaylistUrlRegex = `^.*(youtube.com\/playlist\?list=)([^#\&\?]*)`
-
-	//var ytPlaylistUrlRegex = `^https?:\/\/(www.youtube.com|youtube.com)\/playlist(.*)$`
-	durationRegex = `P(?P<years>\d+Y)?(?P<months>\d+M)?(?P<days>\d+D)?T?(?P<hours>\d+H)?(?P<minutes>\d+M)?(?P<seconds>\d+S)?`
-
-	//http spotify urls regex for track, album, playlist ID's.
-	spotifyHttpUrlRegex      = `^(?:https?:\/\/open.spotify.com\/(?:playlist\/|album\/|track\/)([a-zA-Z0-9]+))(?:.*)`
-	spotifyHttpPlaylistRegex = `^(https:\/\/open.spotify.com\/playlist\/[[a-zA-Z0-9]{22}\?.*)$`
-	spotifyHttpAlbumRegex    = `^(https:\/\/open.spotify.com\/album\/[[a-zA-Z0-9]{22}\?.*)$`
-	spotifyHttpTrackRegex    = `^(https:\/\/open.spotify.com\/track\/[[a-zA-Z0-9]{22}\?.*)$`
-
-	//youtube url regex
-	ytUrlRegex = `^(?:https?\:\/\/)?(?:www\.)?(?:(?:youtube\.com\/watch\?v=)|(?:youtu.be\/))([a-zA-Z0-9\-_]{11})+.*$|^(?:https:\/\/www.youtube.com\/playlist\?list=)([a-zA-Z0-9\-_].*).*$`
-
-	ytPlaylistUrlRegex = `^(?:https:\/\/www.youtube.com\/playlist\?list=)([a-zA-Z0-9\-_]{34}).*$`
-	ytTrackUrlRegex    = `^(?:https?\:\/\/)?(?:www\.)?(?:(?:youtube\.com\/watch\?v=)|(?:youtu.be\/))([a-zA-Z0-9\-_]{11})+.*$`
+	durationRegex = regexp.MustCompile(`P(?P<years>\d+Y)?(?P<months>\d+M)?(?P<days>\d+D)?T?(?P<hours>\d+H)?(?P<minutes>\d+M)?(?P<seconds>\d+S)?`)
 )
 
 func init() {
@@ -119,122 +93,6 @@ func DeleteSoundAndCoverFile(soundFilePath, coverFilePath string) {
 	}
 }
 
-//IsSpotifyUrl checks given URL is a valid Spotify URL or not.
-//If given URL is valid then returns true, otherwise false.
-func IsSpotifyUrl(url string) bool {
-	re := regexp.MustCompile(spotifyHttpUrlRegex)
-	if re.MatchString(url) {
-		return true
-	}
-	return false
-}
-
-//GetSpotifyID returns ID of playlist, album or track from the given URL.
-func GetSpotifyID(url string) string {
-	re := regexp.MustCompile(spotifyHttpUrlRegex)
-	matches := re.FindStringSubmatch(url)
-	if matches == nil {
-		return ""
-	}
-	return matches[1]
-}
-
-//GetSpotifyUrlType returns the type of the given url in integer.
-func GetSpotifyUrlType(url string) int {
-	if strings.Contains(url, "playlist") {
-		return SPOTIFYPLAYLISTURL
-	} else if strings.Contains(url, "album") {
-		return SPOTIFYALBUMURL
-	} else if strings.Contains(url, "track") {
-		return SPOTIFYTRACKURL
-	} else {
-		return UNKNOWNURL
-	}
-}
-
-//IsYoutubeUrl check given is a valid Youtube URL or not.
-//If given URL is valid then returns true, otherwise false.
-func IsYoutubeUrl(url string) bool {
-	re := regexp.MustCompile(ytUrlRegex)
-	if re.MatchString(url) {
-		return true
-	}
-	return false
-}
-
-//GetYoutubeID returns ID of playlist or track from the given URL.
-func GetYoutubeID(url string) string {
-	var re *regexp.Regexp
-
-	if strings.Contains(url, "playlist") {
-		re = regexp.MustCompile(ytPlaylistUrlRegex)
-	} else {
-		re = regexp.MustCompile(ytTrackUrlRegex)
-	}
-	matches := re.FindStringSubmatch(url)
-	if matches == nil {
-		return ""
-	}
-	return matches[1]
-}
-
-//GetYoutubeUrlType returns the type of the given url in integer.
-func GetYoutubeUrlType(url string) int {
-	//Youtube has lots of different URL formats. So this makes
-	//hard to detect URL formats. If a given Youtbe URL is not a
-	//playlist URL then YOUTUBETRACKURL code will be returned.
-
-	//There is also Mixes that we think of as playlist.
-	//I haven't decide whether Mixes treated as playlist
-	//or not(even bot don't accept Mix URL). So until Mix
-	//and playlist concept is decided, Mix URLs will not be accepted.
-	if strings.Contains(url, "playlist") {
-		return YOUTUBEPLAYLISTURL
-	} else {
-		return YOUTUBETRACKURL
-	}
-}
-
-//GetSpotifyPlaylistID returns playlist ID
-//from the given url
-//TODO: Error checking
-func GetSpotifyPlaylistID(url string) string {
-	//format 1: spotify:playlist:76tzi26o8O920CYAvVbeYO
-	if strings.HasPrefix(url, "spotify") {
-		return strings.Trim(strings.Split(url, ":")[2], " ")
-	}
-
-	//format 2: https://open.spotify.com/playlist/76tzi26o8O920CYAvVbeYO?si=WKrHWhGVQTSmF7GbeqI5sw
-	if strings.HasPrefix(url, "https") {
-		trimmed := strings.TrimPrefix(url, "https://open.spotify.com/")
-		return strings.Trim(strings.Split(strings.Split(trimmed, "/")[1], "?")[0], " ")
-	}
-
-	return ""
-
-	/*
-		seperated := strings.Split(url, "?")[1]
-		playlistID := strings.Trim(seperated, "si=")
-		return playlistID
-	*/
-}
-
-//GetYtVideoID trims video id from the given url.
-func GetYtVideoID(url string) string {
-	//format: https://www.youtube.com/watch?v=qT6XCvDUUsU
-	return strings.TrimPrefix(url, "https://www.youtube.com/watch?v=")
-}
-
-//GetYoutubePlaylistID trims youtube playlist id from the url.
-//Checks given url if it is not playlist url return empty string ("")
-//otherwise trims playlist id and returns.
-func GetYoutubePlaylistID(url string) string {
-	if !ValidateYoutubePlaylistUrl(url) {
-		return ""
-	}
-	return strings.TrimPrefix(url, "https://www.youtube.com/playlist?list=")
-}
-
 //GetCoverImage downloads album cover image from the
 //given url and returns its path.
 func GetCoverImage(coverUrl string) (string, error) {
@@ -261,37 +119,16 @@ func GetCoverImage(coverUrl string) (string, error) {
 	return imgFileFullPath, nil
 }
 
-//ValiadateYoutubeUrl validates whether given url is a youtube url or not.
-func ValidateYoutubeUrl(url string) bool {
-	r, err := regexp.Compile(ytUrlRegex)
-	if err != nil {
-		log.Println(err)
-		return false
-	}
-	return r.MatchString(url)
-}
-
-//ValidateYoutubePlaylistUrl validates whether given url is a youtube playlist url or not.
-func ValidateYoutubePlaylistUrl(url string) bool {
-	r, err := regexp.Compile(ytPlaylistUrlRegex)
-	if err != nil {
-		log.Println(err)
-		return false
-	}
-
-	return r.MatchString(url)
-}
-
 //ParseISO8601 takes a duration in format ISO8601 and parses to
 //MM:SS format.
 func ParseISO8601(duration string) string {
-	r, err := regexp.Compile(durationRegex)
-	if err != nil {
-		log.Println(err)
-		return ""
-	}
+	return ParseISO8601Duration(duration).String()
+}
 
-	matches := r.FindStringSubmatch(duration)
+//ParseISO8601Duration takes a duration in format ISO8601 and
+//parses to time.Duration.
+func ParseISO8601Duration(duration string) time.Duration {
+	matches := durationRegex.FindStringSubmatch(duration)
 
 	years := parseInt64(matches[1])
 	months := parseInt64(matches[2])
@@ -306,7 +143,7 @@ func ParseISO8601(duration string) string {
 
 	return time.Duration(years*24*365*hour +
 		months*30*24*hour + days*24*hour +
-		hours*hour + minutes*minute + seconds*second).String()
+		hours*hour + minutes*minute + seconds*second)
 }
 
 func parseInt64(value string) int64 {
@@ -18,106 +18,6 @@ var (
 	ytUnknownUrl1 = "https://google.com"
 )
 
-func TestGetSpotifyID(t *testing.T) {
-	tables := []struct {
-		url string
-		id  string
-	}{
-		{spotifyPlUrl1, "37i9dQZF1DZ06evO1fLAgU"},
-		{spotifyAlbumUrl1, "4HklP3MTUYViTMiNdj43R3"},
-		{spotifyTrackUrl1, "2az3iTNyJ1M1JJnsU2Gq6H"},
-		{spotifyUnknownUrl1, ""},
-	}
-
-	for _, table := range tables {
-		id := GetSpotifyID(table.url)
-
-		if strings.Compare(id, table.id) != 0 {
-			t.Errorf("id is incorrect, got: %s, want: %s", id, table.id)
-		}
-	}
-}
-
-func TestGetSpotifyUrlType(t *testing.T) {
-	tables := []struct {
-		url     string
-		urlType int
-	}{
-		{spotifyPlUrl1, 1},
-		{spotifyAlbumUrl1, 2},
-		{spotifyTrackUrl1, 3},
-		{spotifyUnknownUrl1, -1},
-	}
-
-	for _, table := range tables {
-		urlType := GetSpotifyUrlType(table.url)
-
-		if urlType != table.urlType {
-			t.Errorf("url type is incorrect, got: %d, want: %d", urlType, table.urlType)
-		}
-	}
-}
-
-func TestIsYoutubeURL(t *testing.T) {
-	tables := []struct {
-		url   string
-		valid bool
-	}{
-		{ytPlUrl1, true},
-		{ytTrackUrl1, true},
-		{ytTrackUrl2, true},
-		{ytUnknownUrl1, false},
-	}
-
-	for _, table := range tables {
-		isValid := IsYoutubeUrl(table.url)
-
-		if isValid != table.valid {
-			t.Errorf("IsYoutubeURL is failed, url: %s", table.url)
-		}
-	}
-}
-
-func TestGetYoutubeID(t *testing.T) {
-	tables := []struct {
-		url string
-		id  string
-	}{
-		{ytPlUrl1, "PL4o29bINVT4EG_y-k5jGoOu3-Am8Nvi10"},
-		{ytTrackUrl1, "B9v8jLBrvug"},
-		{ytTrackUrl2, "SlPhMPnQ58k"},
-		{ytUnknownUrl1, ""},
-	}
-
-	for _, table := range tables {
-		id := GetYoutubeID(table.url)
-
-		if strings.Compare(id, table.id) != 0 {
-			t.Errorf("id is incorrect, got: %s, want: %s", id, table.id)
-		}
-	}
-}
-
-func TestYoutubeUrlType(t *testing.T) {
-	tables := []struct {
-		url     string
-		urlType int
-	}{
-		{ytPlUrl1, YOUTUBEPLAYLISTURL},
-		{ytTrackUrl1, YOUTUBETRACKURL},
-		{ytTrackUrl2, YOUTUBETRACKURL},
-		{ytUnknownUrl1, YOUTUBETRACKURL},
-	}
-
-	for _, table := range tables {
-		urlType := GetYoutubeUrlType(table.url)
-
-		if urlType != table.urlType {
-			t.Errorf("Url type is false, got: %d, want %d", table.urlType, urlType)
-		}
-	}
-}
-
 func TestGetCoverImage(t *testing.T) {
 	imgUrl := "https://hemreari.com/assets/img/coming_soon_homepage.jpg"
 
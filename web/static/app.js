@@ -0,0 +1,234 @@
+"use strict";
+
+const state = {
+	guildID: "",
+	nowPlaying: null,
+	receivedAt: 0,
+	dragFrom: -1,
+};
+
+const $ = (id) => document.getElementById(id);
+
+//api calls the player API of the guild, errors are shown on the page.
+async function api(method, path, body) {
+	const response = await fetch("/api/guilds/" + state.guildID + "/" + path, {
+		method: method,
+		credentials: "same-origin",
+		headers: body ? {"Content-Type": "application/json"} : {},
+		body: body ? JSON.stringify(body) : undefined,
+	});
+	const data = await response.json();
+	if (!response.ok) {
+		showMessage(data.error || "Request failed.");
+		return null;
+	}
+	showMessage("");
+	return data;
+}
+
+function showMessage(text) {
+	$("message").textContent = text;
+	$("message").hidden = text === "";
+}
+
+function formatTime(seconds) {
+	seconds = Math.floor(seconds);
+	const minutes = Math.floor(seconds / 60);
+	const rest = String(seconds % 60).padStart(2, "0");
+	if (minutes >= 60) {
+		return Math.floor(minutes / 60) + ":" + String(minutes % 60).padStart(2, "0") + ":" + rest;
+	}
+	return minutes + ":" + rest;
+}
+
+function renderNowPlaying(nowPlaying) {
+	state.nowPlaying = nowPlaying;
+	state.receivedAt = Date.now();
+
+	const song = nowPlaying.song;
+	$("title").textContent = song ? song.title : "Nothing is playing.";
+	$("artist").textContent = song && song.artist ? song.artist : "";
+	$("requester").textContent = song && song.requesterName ? "Requested by " + song.requesterName : "";
+	if (song && song.coverUrl) {
+		$("cover").src = song.coverUrl;
+	} else {
+		$("cover").removeAttribute("src");
+	}
+
+	$("pause").textContent = nowPlaying.paused ? "Resume" : "Pause";
+	$("volume").value = nowPlaying.volume;
+	$("volume-value").textContent = nowPlaying.volume + "%";
+	renderProgress();
+}
+
+//renderProgress moves the progress on from the last position that is received.
+function renderProgress() {
+	const nowPlaying = state.nowPlaying;
+	if (!nowPlaying || !nowPlaying.song) {
+		$("progress-bar").style.width = "0";
+		$("time").textContent = "";
+		return;
+	}
+
+	let position = nowPlaying.position;
+	if (!nowPlaying.paused) {
+		position += (Date.now() - state.receivedAt) / 1000;
+	}
+
+	const length = nowPlaying.song.length;
+	if (nowPlaying.song.live || !length) {
+		$("progress-bar").style.width = "0";
+		$("time").textContent = formatTime(position);
+		return;
+	}
+
+	position = Math.min(position, length);
+	$("progress-bar").style.width = (position / length * 100) + "%";
+	$("time").textContent = formatTime(position) + " / " + formatTime(length);
+}
+
+function songItem(song, detail) {
+	const item = document.createElement("li");
+	const text = document.createElement("div");
+	text.textContent = song.artist ? song.artist + " - " + song.title : song.title;
+
+	const info = document.createElement("div");
+	info.className = "detail";
+	info.textContent = detail;
+	text.appendChild(info);
+	item.appendChild(text);
+	return item;
+}
+
+function renderQueue(queue) {
+	const list = $("queue");
+	list.textContent = "";
+
+	queue.songs.forEach((song, position) => {
+		let detail = song.duration || "";
+		if (song.requesterName) {
+			detail += (detail ? " - " : "") + "requested by " + song.requesterName;
+		}
+		if (song.downloading) {
+			detail += " (downloading)";
+		}
+
+		const item = songItem(song, detail);
+		if (song.downloading) {
+			item.classList.add("downloading");
+		}
+
+		const remove = document.createElement("button");
+		remove.type = "button";
+		remove.textContent = "Remove";
+		remove.addEventListener("click", () => api("DELETE", "queue/" + position));
+		item.appendChild(remove);
+
+		//songs are reordered by dragging them onto each other.
+		item.draggable = true;
+		item.addEventListener("dragstart", () => { state.dragFrom = position; });
+		item.addEventListener("dragover", (event) => {
+			event.preventDefault();
+			item.classList.add("drop");
+		});
+		item.addEventListener("dragleave", () => item.classList.remove("drop"));
+		item.addEventListener("drop", (event) => {
+			event.preventDefault();
+			item.classList.remove("drop");
+			if (state.dragFrom >= 0 && state.dragFrom !== position) {
+				api("POST", "queue/" + state.dragFrom + "/move", {to: position});
+			}
+			state.dragFrom = -1;
+		});
+		list.appendChild(item);
+	});
+
+	if (queue.songs.length === 0) {
+		list.appendChild(songItem({title: "Queue is empty."}, ""));
+	}
+}
+
+function renderHistory(history) {
+	const list = $("history");
+	list.textContent = "";
+	history.entries.forEach((entry) => {
+		const startedAt = new Date(entry.startedAt).toLocaleString();
+		let detail = startedAt + " - played " + formatTime(entry.played);
+		if (entry.requesterName) {
+			detail += " - requested by " + entry.requesterName;
+		}
+		list.appendChild(songItem(entry, detail));
+	});
+}
+
+//connect opens the event socket of the guild, it reconnects when it is closed.
+function connect() {
+	const protocol = location.protocol === "https:" ? "wss://" : "ws://";
+	const socket = new WebSocket(protocol + location.host + "/api/guilds/" + state.guildID + "/events");
+
+	socket.addEventListener("open", () => { $("status").textContent = "Live"; });
+	socket.addEventListener("message", (message) => {
+		const event = JSON.parse(message.data);
+		switch (event.type) {
+		case "nowPlaying":
+			renderNowPlaying(event.data);
+			break;
+		case "queue":
+			renderQueue(event.data);
+			break;
+		case "history":
+			renderHistory(event.data);
+			break;
+		}
+	});
+	socket.addEventListener("close", () => {
+		$("status").textContent = "Reconnecting...";
+		setTimeout(restart, 3000);
+	});
+}
+
+function bindControls() {
+	$("pause").addEventListener("click", () => {
+		api("POST", state.nowPlaying && state.nowPlaying.paused ? "resume" : "pause");
+	});
+	$("skip").addEventListener("click", () => api("POST", "skip"));
+	$("stop").addEventListener("click", () => api("POST", "stop"));
+	$("volume").addEventListener("change", () => {
+		api("POST", "volume", {volume: Number($("volume").value)});
+	});
+	$("enqueue").addEventListener("submit", async (event) => {
+		event.preventDefault();
+		const query = $("query").value.trim();
+		if (query && await api("POST", "queue", {query: query})) {
+			$("query").value = "";
+		}
+	});
+}
+
+//start checks the session and connects to the events of its guild.
+async function start() {
+	const response = await fetch("/api/session", {credentials: "same-origin"});
+	if (!response.ok) {
+		$("dashboard").hidden = true;
+		$("status").textContent = "Logged out";
+		showMessage("Your session is expired or you are not logged in. Use !dashboard in Discord to get a login link.");
+		return;
+	}
+
+	const session = await response.json();
+	state.guildID = session.guildId;
+	$("dashboard").hidden = false;
+	connect();
+}
+
+//restart starts again until the bot can be reached.
+function restart() {
+	start().catch(() => {
+		$("status").textContent = "Reconnecting...";
+		setTimeout(restart, 3000);
+	});
+}
+
+bindControls();
+setInterval(renderProgress, 1000);
+restart();
@@ -0,0 +1,21 @@
+//Package web has the assets of the web dashboard, they are
+//embedded into the binary.
+package web
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed static
+var static embed.FS
+
+//Assets returns the files of the dashboard, index.html is at the root.
+func Assets() fs.FS {
+	assets, err := fs.Sub(static, "static")
+	if err != nil {
+		//static directory is embedded, so this can't happen.
+		panic(err)
+	}
+	return assets
+}
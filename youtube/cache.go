@@ -0,0 +1,111 @@
+package youtube
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hemreari/feanor-dcbot/cache"
+	"github.com/hemreari/feanor-dcbot/util"
+)
+
+//cachedVideo is the information of a video that is kept in the cache.
+type cachedVideo struct {
+	Title         string        `json:"title"`
+	ChannelID     string        `json:"channelID,omitempty"`
+	ChannelTitle  string        `json:"channelTitle"`
+	CoverUrl      string        `json:"coverUrl"`
+	ViewCount     uint64        `json:"viewCount"`
+	Length        time.Duration `json:"length"`
+	AgeRestricted bool          `json:"ageRestricted,omitempty"`
+}
+
+//cachedMatch is a track that is matched to a video before.
+type cachedMatch struct {
+	VideoID    string  `json:"videoID"`
+	Confidence float64 `json:"confidence"`
+}
+
+//cacheVideos stores the given results in the cache. Results
+//without a duration are not stored since they are incomplete.
+func (y *YoutubeAPI) cacheVideos(results []SearchResult) {
+	for _, result := range results {
+		if result.VideoID == "" || result.Length <= 0 {
+			continue
+		}
+
+		y.Cache.Set(cache.YoutubeVideo, result.VideoID, cachedVideo{
+			Title:         result.VideoTitle,
+			ChannelID:     result.ChannelID,
+			ChannelTitle:  result.ChannelTitle,
+			CoverUrl:      result.CoverUrl,
+			ViewCount:     result.ViewCount,
+			Length:        result.Length,
+			AgeRestricted: result.AgeRestricted,
+		})
+	}
+}
+
+//cachedResult returns the cached information of the given video.
+func (y *YoutubeAPI) cachedResult(id string) (SearchResult, bool) {
+	var video cachedVideo
+	if !y.Cache.Get(cache.YoutubeVideo, id, &video) {
+		return SearchResult{}, false
+	}
+
+	return SearchResult{
+		Kind:          util.KindTrack,
+		VideoID:       id,
+		VideoTitle:    video.Title,
+		ChannelID:     video.ChannelID,
+		ChannelTitle:  video.ChannelTitle,
+		CoverUrl:      video.CoverUrl,
+		ViewCount:     video.ViewCount,
+		Length:        video.Length,
+		Duration:      video.Length.String(),
+		AgeRestricted: video.AgeRestricted,
+	}, true
+}
+
+//cachedSearch returns the cached results of the given search.
+//All results must be in the cache to return them.
+func (y *YoutubeAPI) cachedSearch(key string) ([]SearchResult, bool) {
+	var ids []string
+	if !y.Cache.Get(cache.YoutubeQuery, key, &ids) || len(ids) == 0 {
+		return nil, false
+	}
+
+	results := []SearchResult{}
+	for _, id := range ids {
+		result, ok := y.cachedResult(id)
+		if !ok {
+			return nil, false
+		}
+		results = append(results, result)
+	}
+	return results, true
+}
+
+//cacheSearch stores the given search results with the given key.
+func (y *YoutubeAPI) cacheSearch(key string, results []SearchResult) {
+	ids := []string{}
+	for _, result := range results {
+		ids = append(ids, result.VideoID)
+	}
+
+	y.Cache.Set(cache.YoutubeQuery, key, ids)
+	y.cacheVideos(results)
+}
+
+//searchCacheKey returns the cache key of a search, so that
+//queries that differ only in case or spacing share results.
+func searchCacheKey(query string, opts SearchOptions, count int64) string {
+	return fmt.Sprintf("search:%d:%s:%s:%s:%d:%d:%s", count, opts.Kind, opts.RegionCode, opts.RelevanceLanguage,
+		int(opts.MinDuration.Seconds()), int(opts.MaxDuration.Seconds()),
+		strings.Join(strings.Fields(strings.ToLower(query)), " "))
+}
+
+//matchCacheKey returns the cache key of a track match.
+func matchCacheKey(artist, title string, duration time.Duration) string {
+	return fmt.Sprintf("match:%s:%s:%d", normalize(artist), normalize(title), int(duration.Seconds()))
+}
@@ -0,0 +1,107 @@
+package youtube
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/api/googleapi"
+)
+
+var (
+	ErrQuotaExceeded = errors.New("youtube: quota exceeded")
+	ErrNotFound      = errors.New("youtube: not found")
+	ErrTransport     = errors.New("youtube: request failed")
+)
+
+//quotaReasons are the error reasons that Youtube Data API
+//returns when the daily quota or the rate limit is exceeded.
+var quotaReasons = map[string]bool{
+	"quotaExceeded":           true,
+	"dailyLimitExceeded":      true,
+	"rateLimitExceeded":       true,
+	"userRateLimitExceeded":   true,
+	"servingLimitExceeded":    true,
+	"dailyLimitExceededUnreg": true,
+}
+
+//APIError is returned when Youtube Data API responds with an error.
+//It matches ErrQuotaExceeded and ErrNotFound with errors.Is according
+//to its status code and reason.
+type APIError struct {
+	StatusCode int
+	Reason     string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("youtube: %d %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("youtube: %d %s: %s", e.StatusCode, e.Reason, e.Message)
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrQuotaExceeded:
+		return e.StatusCode == http.StatusTooManyRequests ||
+			(e.StatusCode == http.StatusForbidden && quotaReasons[e.Reason])
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
+//dailyQuotaExceeded checks the error is caused by the daily quota
+//rather than a short term rate limit.
+func (e *APIError) dailyQuotaExceeded() bool {
+	return e.Reason == "quotaExceeded" || e.Reason == "dailyLimitExceeded"
+}
+
+//TransportError is returned when a request couldn't reach Youtube
+//or its response couldn't be read. It matches ErrTransport.
+type TransportError struct {
+	Err error
+}
+
+func (e *TransportError) Error() string {
+	return "youtube: " + e.Err.Error()
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+func (e *TransportError) Is(target error) bool {
+	return target == ErrTransport
+}
+
+//wrapError classifies the given error of a Youtube Data API call as an
+//APIError or a TransportError and adds the failed action to it.
+func wrapError(action string, err error) error {
+	var gErr *googleapi.Error
+	if errors.As(err, &gErr) {
+		apiErr := &APIError{
+			StatusCode: gErr.Code,
+			Message:    gErr.Message,
+		}
+		if len(gErr.Errors) > 0 {
+			apiErr.Reason = gErr.Errors[0].Reason
+		}
+		return fmt.Errorf("Error while %s: %w", action, apiErr)
+	}
+	return fmt.Errorf("Error while %s: %w", action, &TransportError{Err: err})
+}
+
+//apiError wraps the given error of a Youtube Data API call with wrapError.
+//If Youtube says the daily quota is exceeded, remaining quota is marked as
+//used, so that next calls are not made until the quota is reset.
+func (y *YoutubeAPI) apiError(action string, err error) error {
+	err = wrapError(action, err)
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.dailyQuotaExceeded() {
+		y.Quota.Exhaust()
+	}
+	return err
+}
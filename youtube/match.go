@@ -0,0 +1,204 @@
+package youtube
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/hemreari/feanor-dcbot/cache"
+)
+
+const (
+	//number of search results that are scored to find a track.
+	matchCandidateCount int64 = 8
+	//matches below this confidence are still played but logged as doubtful.
+	lowMatchConfidence = 0.4
+
+	durationWeight      = 0.45
+	titleWeight         = 0.3
+	artistWeight        = 0.15
+	officialBonus       = 0.1
+	unwantedTermPenalty = 0.3
+
+	//candidates that differ from the track duration by this much
+	//or more don't get any duration score.
+	maxDurationDifference = 30 * time.Second
+)
+
+//unwantedTerms are the versions of a song that are not wanted
+//unless the track title contains them too.
+var unwantedTerms = []string{
+	"live", "cover", "remix", "karaoke", "instrumental", "acoustic",
+	"nightcore", "slowed", "sped", "reverb", "8d", "loop", "hour", "hours",
+	"reaction", "tutorial", "lesson", "mashup", "edit", "bass boosted",
+}
+
+//officialTerms are the signals of an official upload.
+var officialTerms = []string{"official audio", "official video", "official music video", "provided to youtube"}
+
+//MatchTrack searches the given track on Youtube and returns the search
+//result that is the most likely to be the same recording. Candidates are
+//scored by the closeness of their duration to the given duration, title
+//and artist similarity and official upload signals. Second return value is
+//the confidence of the match between 0 and 1.
+func (y *YoutubeAPI) MatchTrack(artist, title string, duration time.Duration) (*SearchResult, float64, error) {
+	query := strings.TrimSpace(artist + " " + title)
+
+	key := matchCacheKey(artist, title, duration)
+	var match cachedMatch
+	if y.Cache.Get(cache.YoutubeQuery, key, &match) {
+		if result, ok := y.cachedResult(match.VideoID); ok {
+			return &result, match.Confidence, nil
+		}
+	}
+
+	candidates, err := y.getMatchCandidates(query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	best, confidence := bestMatch(artist, title, duration, candidates)
+	if best == nil {
+		return nil, 0, fmt.Errorf("Couldn't find a video for \"%s\": %w", query, ErrNotFound)
+	}
+
+	if confidence < lowMatchConfidence {
+		log.Printf("Low confidence match for \"%s\" (%s): \"%s\" (%s, %s) with confidence %.2f\n",
+			query, duration, best.VideoTitle, best.VideoID, best.Duration, confidence)
+	} else {
+		log.Printf("Matched \"%s\" (%s) to \"%s\" (%s, %s) with confidence %.2f\n",
+			query, duration, best.VideoTitle, best.VideoID, best.Duration, confidence)
+	}
+
+	y.Cache.Set(cache.YoutubeQuery, key, cachedMatch{VideoID: best.VideoID, Confidence: confidence})
+	return best, confidence, nil
+}
+
+//getMatchCandidates searches the given query and returns the found
+//videos with their durations.
+func (y *YoutubeAPI) getMatchCandidates(query string) ([]SearchResult, error) {
+	candidates, err := y.search(query, SearchOptions{}, matchCandidateCount)
+	if err != nil {
+		return nil, fmt.Errorf("Error while searching match candidates: %w", err)
+	}
+	return candidates, nil
+}
+
+//bestMatch returns the highest scored candidate and its score.
+func bestMatch(artist, title string, duration time.Duration, candidates []SearchResult) (*SearchResult, float64) {
+	var best *SearchResult
+	bestScore := -1.0
+
+	for i := range candidates {
+		score := scoreCandidate(artist, title, duration, &candidates[i])
+		if score > bestScore {
+			best = &candidates[i]
+			bestScore = score
+		}
+	}
+
+	if best == nil {
+		return nil, 0
+	}
+	return best, bestScore
+}
+
+//scoreCandidate scores how likely the given candidate is the given track.
+//Returned score is between 0 and 1.
+func scoreCandidate(artist, title string, duration time.Duration, candidate *SearchResult) float64 {
+	candidateTitle := normalize(candidate.VideoTitle)
+	channel := normalize(candidate.ChannelTitle)
+	trackTitle := normalize(title)
+
+	score := durationWeight * durationScore(duration, candidate.Length)
+	score += titleWeight * tokenCoverage(trackTitle, candidateTitle)
+	score += artistWeight * tokenCoverage(normalize(artist), candidateTitle+" "+channel)
+
+	if isOfficial(candidate, artist) {
+		score += officialBonus
+	}
+
+	for _, term := range unwantedTerms {
+		if containsWord(candidateTitle, term) && !containsWord(trackTitle, term) {
+			score -= unwantedTermPenalty
+		}
+	}
+
+	return math.Max(0, math.Min(1, score))
+}
+
+//durationScore returns 1 for equal durations and decreases linearly to 0
+//as the difference reaches maxDurationDifference. Unknown durations get a
+//neutral score.
+func durationScore(expected, actual time.Duration) float64 {
+	if expected <= 0 || actual <= 0 {
+		return 0.5
+	}
+
+	difference := expected - actual
+	if difference < 0 {
+		difference = -difference
+	}
+	return math.Max(0, 1-float64(difference)/float64(maxDurationDifference))
+}
+
+//tokenCoverage returns the rate of the words of want that exist in text.
+func tokenCoverage(want, text string) float64 {
+	wantTokens := strings.Fields(want)
+	if len(wantTokens) == 0 {
+		return 0
+	}
+
+	textTokens := map[string]bool{}
+	for _, token := range strings.Fields(text) {
+		textTokens[token] = true
+	}
+
+	found := 0
+	for _, token := range wantTokens {
+		if textTokens[token] {
+			found++
+		}
+	}
+	return float64(found) / float64(len(wantTokens))
+}
+
+//isOfficial checks the candidate is uploaded by the artist's topic or
+//VEVO channel, or its title says it's the official audio or video.
+func isOfficial(candidate *SearchResult, artist string) bool {
+	channel := strings.ToLower(candidate.ChannelTitle)
+	if strings.HasSuffix(channel, " - topic") || strings.HasSuffix(channel, "vevo") {
+		return true
+	}
+
+	title := normalize(candidate.VideoTitle)
+	for _, term := range officialTerms {
+		if strings.Contains(title, term) {
+			return true
+		}
+	}
+
+	return artist != "" && normalize(candidate.ChannelTitle) == normalize(artist)
+}
+
+//containsWord checks text contains the given word or words as whole words.
+func containsWord(text, word string) bool {
+	return strings.Contains(" "+text+" ", " "+word+" ")
+}
+
+//normalize lower cases the given text and replaces everything other
+//than letters and digits with a single space.
+func normalize(text string) string {
+	var builder strings.Builder
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			builder.WriteRune(r)
+		} else {
+			builder.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(builder.String()), " ")
+}
@@ -0,0 +1,127 @@
+package youtube
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBestMatch(t *testing.T) {
+	tables := []struct {
+		name       string
+		artist     string
+		title      string
+		duration   time.Duration
+		candidates []SearchResult
+		videoID    string
+	}{
+		{
+			name:     "official audio is preferred over live and cover versions",
+			artist:   "AC/DC",
+			title:    "Thunderstruck",
+			duration: 4*time.Minute + 52*time.Second,
+			candidates: []SearchResult{
+				{VideoID: "live", VideoTitle: "AC/DC - Thunderstruck (Live At River Plate)", ChannelTitle: "AC/DC", Length: 5*time.Minute + 55*time.Second},
+				{VideoID: "cover", VideoTitle: "Thunderstruck - AC/DC cover", ChannelTitle: "Guitar Guy", Length: 4*time.Minute + 50*time.Second},
+				{VideoID: "topic", VideoTitle: "Thunderstruck", ChannelTitle: "AC/DC - Topic", Length: 4*time.Minute + 53*time.Second},
+			},
+			videoID: "topic",
+		},
+		{
+			name:     "hour long loops are not chosen",
+			artist:   "Metric",
+			title:    "Help I'm Alive",
+			duration: 4*time.Minute + 46*time.Second,
+			candidates: []SearchResult{
+				{VideoID: "loop", VideoTitle: "Metric - Help I'm Alive 1 Hour", ChannelTitle: "Loops", Length: time.Hour},
+				{VideoID: "video", VideoTitle: "Metric - Help I'm Alive (Official Video)", ChannelTitle: "Metric", Length: 4*time.Minute + 49*time.Second},
+			},
+			videoID: "video",
+		},
+		{
+			name:     "remix is chosen if the track is a remix",
+			artist:   "Daft Punk",
+			title:    "Around the World - Remix",
+			duration: 5 * time.Minute,
+			candidates: []SearchResult{
+				{VideoID: "original", VideoTitle: "Daft Punk - Around the World (Official Audio)", ChannelTitle: "Daft Punk", Length: 7*time.Minute + 9*time.Second},
+				{VideoID: "remix", VideoTitle: "Daft Punk - Around the World (Remix)", ChannelTitle: "Remixes", Length: 5*time.Minute + 2*time.Second},
+			},
+			videoID: "remix",
+		},
+		{
+			name:     "closest duration wins between similar titles",
+			artist:   "The Rolling Stones",
+			title:    "Paint It, Black",
+			duration: 3*time.Minute + 22*time.Second,
+			candidates: []SearchResult{
+				{VideoID: "extended", VideoTitle: "The Rolling Stones - Paint It, Black", ChannelTitle: "Music", Length: 4*time.Minute + 30*time.Second},
+				{VideoID: "album", VideoTitle: "The Rolling Stones - Paint It, Black", ChannelTitle: "Music", Length: 3*time.Minute + 23*time.Second},
+			},
+			videoID: "album",
+		},
+	}
+
+	for _, table := range tables {
+		best, confidence := bestMatch(table.artist, table.title, table.duration, table.candidates)
+		if best == nil {
+			t.Errorf("%s: no match found", table.name)
+			continue
+		}
+
+		if best.VideoID != table.videoID {
+			t.Errorf("%s: match is incorrect, got: %s, want: %s", table.name, best.VideoID, table.videoID)
+		}
+
+		if confidence < 0 || confidence > 1 {
+			t.Errorf("%s: confidence is out of range: %f", table.name, confidence)
+		}
+	}
+}
+
+func TestBestMatchEmpty(t *testing.T) {
+	best, confidence := bestMatch("artist", "title", time.Minute, nil)
+	if best != nil || confidence != 0 {
+		t.Errorf("Expected no match, got: %+v, %f", best, confidence)
+	}
+}
+
+func TestDurationScore(t *testing.T) {
+	tables := []struct {
+		expected time.Duration
+		actual   time.Duration
+		score    float64
+	}{
+		{3 * time.Minute, 3 * time.Minute, 1},
+		{3 * time.Minute, 3*time.Minute + 15*time.Second, 0.5},
+		{3 * time.Minute, 2*time.Minute + 45*time.Second, 0.5},
+		{3 * time.Minute, time.Hour, 0},
+		{0, 3 * time.Minute, 0.5},
+	}
+
+	for _, table := range tables {
+		score := durationScore(table.expected, table.actual)
+
+		if score != table.score {
+			t.Errorf("Duration score is incorrect for %s and %s, got: %f, want: %f", table.expected, table.actual, score, table.score)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tables := []struct {
+		text       string
+		normalized string
+	}{
+		{"AC/DC - Thunderstruck (Official Video)", "ac dc thunderstruck official video"},
+		{"Paint It, Black", "paint it black"},
+		{"Sıla - Yan Benimle", "sıla yan benimle"},
+	}
+
+	for _, table := range tables {
+		normalized := normalize(table.text)
+
+		if normalized != table.normalized {
+			t.Errorf("Normalized text is incorrect, got: %s, want: %s", normalized, table.normalized)
+		}
+	}
+}
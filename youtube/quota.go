@@ -0,0 +1,167 @@
+package youtube
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	//DefaultQuotaBudget is the daily quota of a Youtube Data API project.
+	DefaultQuotaBudget int = 10000
+
+	//unit costs of the Youtube Data API calls.
+	searchListCost        int = 100
+	videosListCost        int = 1
+	playlistItemsListCost int = 1
+)
+
+//QuotaTracker counts Youtube Data API units that are used today.
+//Youtube resets the quota on midnight Pacific Time, so does the tracker.
+type QuotaTracker struct {
+	mu       sync.Mutex
+	budget   int
+	used     int
+	calls    map[string]int
+	units    map[string]int
+	resetAt  time.Time
+	location *time.Location
+	now      func() time.Time
+	//units that searches leave for the calls that can't fall back to the extractor.
+	searchReserve int
+}
+
+//QuotaUsage is a snapshot of the tracked quota usage.
+type QuotaUsage struct {
+	Budget  int
+	Used    int
+	ResetAt time.Time
+	Calls   []CallUsage
+}
+
+//CallUsage is the usage of a single API call type like "search.list".
+type CallUsage struct {
+	Name  string
+	Calls int
+	Units int
+}
+
+//NewQuotaTracker creates a tracker that refuses calls after the given
+//number of units are used in a day. Budget is DefaultQuotaBudget if it
+//is not positive. Searches are refused when less than searchReserve units
+//are left, so that video details and playlist pages can still be requested.
+func NewQuotaTracker(budget, searchReserve int) *QuotaTracker {
+	if budget <= 0 {
+		budget = DefaultQuotaBudget
+	}
+	if searchReserve < 0 {
+		searchReserve = 0
+	}
+
+	q := &QuotaTracker{
+		budget:        budget,
+		searchReserve: searchReserve,
+		location:      pacificLocation(),
+		now:           time.Now,
+	}
+	q.reset()
+	return q
+}
+
+//pacificLocation returns the time zone that Youtube quota resets on.
+//If time zone database is not available, Pacific Standard Time is used,
+//so reset time may be an hour off in summer.
+func pacificLocation() *time.Location {
+	location, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		return time.FixedZone("PST", -8*60*60)
+	}
+	return location
+}
+
+//Reserve counts the given units for the given call type. If the call
+//would exceed the budget, it is not counted and ErrQuotaExceeded is returned.
+func (q *QuotaTracker) Reserve(call string, units int) error {
+	return q.reserve(call, units, 0)
+}
+
+//ReserveSearch counts the given units for a search. Search is refused
+//with ErrQuotaExceeded if it would use the units kept by searchReserve.
+func (q *QuotaTracker) ReserveSearch(units int) error {
+	return q.reserve("search.list", units, q.searchReserve)
+}
+
+//reserve counts the units if the call leaves at least kept units of the budget.
+func (q *QuotaTracker) reserve(call string, units, kept int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.resetIfExpired()
+
+	if q.used+units > q.budget-kept {
+		return fmt.Errorf("%s needs %d units but %d of %d daily units are used (%d are kept): %w",
+			call, units, q.used, q.budget, kept, ErrQuotaExceeded)
+	}
+
+	q.used += units
+	q.calls[call]++
+	q.units[call] += units
+	return nil
+}
+
+//Exhaust marks the whole budget as used until the next reset. It is used
+//when Youtube reports the quota is exceeded although the tracker doesn't,
+//e.g. the same API key is used by another application too.
+func (q *QuotaTracker) Exhaust() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.resetIfExpired()
+	q.used = q.budget
+}
+
+//Usage returns the current usage.
+func (q *QuotaTracker) Usage() QuotaUsage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.resetIfExpired()
+
+	usage := QuotaUsage{
+		Budget:  q.budget,
+		Used:    q.used,
+		ResetAt: q.resetAt,
+	}
+
+	for name, calls := range q.calls {
+		usage.Calls = append(usage.Calls, CallUsage{
+			Name:  name,
+			Calls: calls,
+			Units: q.units[name],
+		})
+	}
+
+	sort.Slice(usage.Calls, func(i, j int) bool {
+		return usage.Calls[i].Units > usage.Calls[j].Units
+	})
+	return usage
+}
+
+func (q *QuotaTracker) resetIfExpired() {
+	if !q.now().Before(q.resetAt) {
+		q.reset()
+	}
+}
+
+//reset clears the usage and sets the next reset time to the next
+//midnight in Pacific Time.
+func (q *QuotaTracker) reset() {
+	now := q.now().In(q.location)
+	year, month, day := now.Date()
+
+	q.used = 0
+	q.calls = map[string]int{}
+	q.units = map[string]int{}
+	q.resetAt = time.Date(year, month, day+1, 0, 0, 0, 0, q.location)
+}
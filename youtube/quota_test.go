@@ -0,0 +1,124 @@
+package youtube
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+//newTestQuotaTracker returns a tracker whose clock is the given time.
+func newTestQuotaTracker(budget int, now *time.Time) *QuotaTracker {
+	q := &QuotaTracker{
+		budget:   budget,
+		location: time.FixedZone("PST", -8*60*60),
+		now:      func() time.Time { return *now },
+	}
+	q.reset()
+	return q
+}
+
+func TestQuotaTrackerReserve(t *testing.T) {
+	now := time.Date(2020, 5, 10, 12, 0, 0, 0, time.UTC)
+	q := newTestQuotaTracker(250, &now)
+
+	tables := []struct {
+		call  string
+		units int
+		isErr bool
+		used  int
+	}{
+		{"search.list", searchListCost, false, 100},
+		{"videos.list", videosListCost, false, 101},
+		{"search.list", searchListCost, false, 201},
+		{"search.list", searchListCost, true, 201},
+		{"videos.list", videosListCost, false, 202},
+	}
+
+	for i, table := range tables {
+		err := q.Reserve(table.call, table.units)
+		if (err != nil) != table.isErr {
+			t.Errorf("Call %d: got error %v, want error: %t.", i, err, table.isErr)
+		}
+		if err != nil && !errors.Is(err, ErrQuotaExceeded) {
+			t.Errorf("Call %d: got error %v, want %v.", i, err, ErrQuotaExceeded)
+		}
+		if used := q.Usage().Used; used != table.used {
+			t.Errorf("Call %d: got %d used units, want %d.", i, used, table.used)
+		}
+	}
+
+	usage := q.Usage()
+	if len(usage.Calls) != 2 || usage.Calls[0].Name != "search.list" || usage.Calls[0].Calls != 2 {
+		t.Errorf("Got call usage %+v, want search.list with 2 calls first.", usage.Calls)
+	}
+}
+
+func TestQuotaTrackerSearchReserve(t *testing.T) {
+	now := time.Date(2020, 5, 10, 12, 0, 0, 0, time.UTC)
+	q := newTestQuotaTracker(250, &now)
+	q.searchReserve = 100
+
+	tables := []struct {
+		search bool
+		units  int
+		isErr  bool
+		used   int
+	}{
+		{true, searchListCost, false, 100},
+		//second search would leave less than the reserve.
+		{true, searchListCost, true, 100},
+		{false, 140, false, 240},
+		{false, 10, false, 250},
+		{false, 1, true, 250},
+	}
+
+	for i, table := range tables {
+		var err error
+		if table.search {
+			err = q.ReserveSearch(table.units)
+		} else {
+			err = q.Reserve("videos.list", table.units)
+		}
+
+		if (err != nil) != table.isErr {
+			t.Errorf("Call %d: got error %v, want error: %t.", i, err, table.isErr)
+		}
+		if err != nil && !errors.Is(err, ErrQuotaExceeded) {
+			t.Errorf("Call %d: got error %v, want %v.", i, err, ErrQuotaExceeded)
+		}
+		if used := q.Usage().Used; used != table.used {
+			t.Errorf("Call %d: got %d used units, want %d.", i, used, table.used)
+		}
+	}
+}
+
+func TestQuotaTrackerReset(t *testing.T) {
+	//19:00 UTC is 11:00 in Pacific Standard Time.
+	now := time.Date(2020, 1, 10, 19, 0, 0, 0, time.UTC)
+	q := newTestQuotaTracker(100, &now)
+
+	wantResetAt := time.Date(2020, 1, 11, 8, 0, 0, 0, time.UTC)
+	if !q.Usage().ResetAt.Equal(wantResetAt) {
+		t.Errorf("Got reset time %s, want %s.", q.Usage().ResetAt, wantResetAt)
+	}
+
+	q.Exhaust()
+	if err := q.Reserve("videos.list", 1); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Got error %v after exhaust, want %v.", err, ErrQuotaExceeded)
+	}
+
+	//just before the Pacific midnight.
+	now = time.Date(2020, 1, 11, 7, 59, 0, 0, time.UTC)
+	if err := q.Reserve("videos.list", 1); err == nil {
+		t.Errorf("Quota is reset before the Pacific midnight.")
+	}
+
+	now = time.Date(2020, 1, 11, 8, 0, 0, 0, time.UTC)
+	if err := q.Reserve("videos.list", 1); err != nil {
+		t.Errorf("Got error %v after reset.", err)
+	}
+
+	if used := q.Usage().Used; used != 1 {
+		t.Errorf("Got %d used units after reset, want 1.", used)
+	}
+}
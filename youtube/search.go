@@ -0,0 +1,337 @@
+package youtube
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/hemreari/feanor-dcbot/util"
+)
+
+//SearchStrategy decides which backend is used to search videos.
+type SearchStrategy string
+
+const (
+	//StrategyAPI searches only with the Youtube Data API.
+	StrategyAPI SearchStrategy = "api"
+	//StrategyExtractor searches only with the extractor, it doesn't need an API key.
+	StrategyExtractor SearchStrategy = "extractor"
+	//StrategyFallback searches with the Youtube Data API and falls back to
+	//the extractor if the API key is missing or the quota budget is used up.
+	StrategyFallback SearchStrategy = "fallback"
+
+	DefaultSearchStrategy = StrategyFallback
+
+	//number of results that are shown for !search.
+	searchResultCount int64 = 5
+	//number of results that are requested when results are filtered by
+	//duration, so that enough results are left after filtering.
+	filteredSearchCount int64 = 25
+
+	extractorCommand = "youtube-dl"
+	extractorTimeout = 30 * time.Second
+)
+
+//SearchOptions are the filters of a search.
+type SearchOptions struct {
+	Kind              util.Kind     //KindTrack (default), KindPlaylist or KindChannel
+	MinDuration       time.Duration //0 for no limit, only for videos
+	MaxDuration       time.Duration //0 for no limit, only for videos
+	RegionCode        string        //ISO 3166-1 alpha-2 country code like "TR"
+	RelevanceLanguage string        //ISO 639-1 language code like "tr"
+}
+
+//extractorEntry is the part of the extractor's JSON output of a search result.
+type extractorEntry struct {
+	ID         string  `json:"id"`
+	Title      string  `json:"title"`
+	Duration   float64 `json:"duration"`
+	ViewCount  uint64  `json:"view_count"`
+	Channel    string  `json:"channel"`
+	ChannelID  string  `json:"channel_id"`
+	Uploader   string  `json:"uploader"`
+	AgeLimit   int     `json:"age_limit"`
+	Thumbnail  string  `json:"thumbnail"`
+	Thumbnails []struct {
+		Url string `json:"url"`
+	} `json:"thumbnails"`
+}
+
+//ParseSearchStrategy parses the search strategy setting.
+//Empty string is parsed as DefaultSearchStrategy.
+func ParseSearchStrategy(value string) (SearchStrategy, error) {
+	switch SearchStrategy(strings.ToLower(strings.TrimSpace(value))) {
+	case "":
+		return DefaultSearchStrategy, nil
+	case StrategyAPI:
+		return StrategyAPI, nil
+	case StrategyExtractor:
+		return StrategyExtractor, nil
+	case StrategyFallback:
+		return StrategyFallback, nil
+	}
+	return "", fmt.Errorf("Unknown Youtube search strategy \"%s\", it must be one of: %s, %s, %s",
+		value, StrategyAPI, StrategyExtractor, StrategyFallback)
+}
+
+//search returns at most count results for the given query and options.
+//Video results have their durations. Video searches are returned from
+//the cache until they expire.
+func (y *YoutubeAPI) search(query string, opts SearchOptions, count int64) ([]SearchResult, error) {
+	if opts.Kind == util.KindUnknown {
+		opts.Kind = util.KindTrack
+	}
+
+	if opts.RelevanceLanguage == "" {
+		opts.RelevanceLanguage = y.RelevanceLanguage
+	}
+
+	//playlists and channels are not cached since only videos have
+	//their own cache entries.
+	key := searchCacheKey(query, opts, count)
+	if opts.Kind == util.KindTrack {
+		if results, ok := y.cachedSearch(key); ok {
+			return results, nil
+		}
+	}
+
+	requestCount := count
+	if opts.MinDuration > 0 || opts.MaxDuration > 0 {
+		requestCount = filteredSearchCount
+	}
+
+	results, err := y.searchBackend(query, opts, requestCount)
+	if err != nil {
+		return nil, err
+	}
+
+	results = filterByDuration(results, opts.MinDuration, opts.MaxDuration)
+	if int64(len(results)) > count {
+		results = results[:count]
+	}
+
+	if opts.Kind == util.KindTrack {
+		y.cacheSearch(key, results)
+	}
+	return results, nil
+}
+
+//searchBackend searches the given query with the backend
+//that the search strategy chooses.
+func (y *YoutubeAPI) searchBackend(query string, opts SearchOptions, count int64) ([]SearchResult, error) {
+	switch y.SearchStrategy {
+	case StrategyAPI:
+		return y.apiSearch(query, opts, count)
+	case StrategyExtractor:
+		return extractorSearch(query, opts, count)
+	}
+
+	if y.DeveloperKey == "" {
+		return extractorSearch(query, opts, count)
+	}
+
+	results, err := y.apiSearch(query, opts, count)
+	if errors.Is(err, ErrQuotaExceeded) && opts.Kind == util.KindTrack {
+		log.Printf("Youtube quota is exceeded, searching \"%s\" with %s.\n", query, extractorCommand)
+		return extractorSearch(query, opts, count)
+	}
+	return results, err
+}
+
+//apiSearch searches with the Youtube Data API.
+func (y *YoutubeAPI) apiSearch(query string, opts SearchOptions, count int64) ([]SearchResult, error) {
+	err := y.Quota.ReserveSearch(searchListCost)
+	if err != nil {
+		return nil, err
+	}
+
+	call := y.service.Search.List("id,snippet").
+		Q(query).
+		Type(searchType(opts.Kind)).
+		MaxResults(count)
+	if opts.RegionCode != "" {
+		call = call.RegionCode(opts.RegionCode)
+	}
+	if opts.RelevanceLanguage != "" {
+		call = call.RelevanceLanguage(opts.RelevanceLanguage)
+	}
+
+	response, err := call.Do()
+	if err != nil {
+		return nil, y.apiError("searching", err)
+	}
+
+	results := []SearchResult{}
+	for _, item := range response.Items {
+		if item.Snippet.Title == "" {
+			continue
+		}
+
+		result := SearchResult{
+			Kind:         opts.Kind,
+			VideoTitle:   item.Snippet.Title,
+			ChannelTitle: item.Snippet.ChannelTitle,
+			CoverUrl:     thumbnailUrl(item.Snippet.Thumbnails),
+		}
+
+		switch {
+		case opts.Kind == util.KindTrack && item.Id.VideoId != "":
+			result.VideoID = item.Id.VideoId
+			result.ChannelID = item.Snippet.ChannelId
+		case opts.Kind == util.KindPlaylist && item.Id.PlaylistId != "":
+			result.PlaylistID = item.Id.PlaylistId
+		case opts.Kind == util.KindChannel && item.Id.ChannelId != "":
+			result.ChannelID = item.Id.ChannelId
+		default:
+			continue
+		}
+		results = append(results, result)
+	}
+
+	//durations of all videos are requested with a single call.
+	err = y.fillDurations(results)
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+//searchType returns the search.list type parameter of the given kind.
+func searchType(kind util.Kind) string {
+	switch kind {
+	case util.KindPlaylist:
+		return "playlist"
+	case util.KindChannel:
+		return "channel"
+	}
+	return "video"
+}
+
+//filterByDuration removes the videos that are shorter than min or longer
+//than max. Zero limits are ignored, videos with unknown durations are kept.
+func filterByDuration(results []SearchResult, min, max time.Duration) []SearchResult {
+	if min <= 0 && max <= 0 {
+		return results
+	}
+
+	filtered := []SearchResult{}
+	for _, result := range results {
+		if result.Length > 0 &&
+			((min > 0 && result.Length < min) || (max > 0 && result.Length > max)) {
+			continue
+		}
+		filtered = append(filtered, result)
+	}
+	return filtered
+}
+
+//extractorSearch searches videos with the extractor's "ytsearchN:" mode.
+//Search results are listed without being resolved, so it returns as fast
+//as a single page request. Only videos can be searched with the extractor.
+func extractorSearch(query string, opts SearchOptions, count int64) ([]SearchResult, error) {
+	if opts.Kind != util.KindTrack {
+		return nil, fmt.Errorf("Only videos can be searched with %s, %s search needs the Youtube Data API", extractorCommand, opts.Kind)
+	}
+
+	args := []string{
+		"--flat-playlist",
+		"--dump-json",
+		"--no-warnings",
+		"--socket-timeout", "15",
+		fmt.Sprintf("ytsearch%d:%s", count, query),
+	}
+
+	cmd := exec.Command(extractorCommand, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	timer := time.AfterFunc(extractorTimeout, func() {
+		if cmd.Process != nil {
+			cmd.Process.Kill()
+		}
+	})
+	out, err := cmd.Output()
+	timer.Stop()
+	if err != nil {
+		return nil, fmt.Errorf("Error while searching \"%s\" with %s: %v %s",
+			query, extractorCommand, err, strings.TrimSpace(stderr.String()))
+	}
+
+	results, err := parseExtractorOutput(out)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		return nil, fmt.Errorf("No video is found for \"%s\": %w", query, ErrNotFound)
+	}
+	return results, nil
+}
+
+//parseExtractorOutput parses the extractor's output that has
+//one JSON object for each search result in a line.
+func parseExtractorOutput(out []byte) ([]SearchResult, error) {
+	results := []SearchResult{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var entry extractorEntry
+		err := json.Unmarshal(line, &entry)
+		if err != nil {
+			return nil, fmt.Errorf("Error while parsing %s output: %v", extractorCommand, err)
+		}
+
+		if entry.ID == "" || entry.Title == "" {
+			continue
+		}
+		results = append(results, entry.searchResult())
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Error while reading %s output: %v", extractorCommand, err)
+	}
+	return results, nil
+}
+
+func (e *extractorEntry) searchResult() SearchResult {
+	result := SearchResult{
+		Kind:         util.KindTrack,
+		VideoID:      e.ID,
+		VideoTitle:   e.Title,
+		ChannelID:    e.ChannelID,
+		ChannelTitle: e.Channel,
+		ViewCount:    e.ViewCount,
+		CoverUrl:     e.Thumbnail,
+	}
+	result.AgeRestricted = e.AgeLimit >= 18
+
+	if result.ChannelTitle == "" {
+		result.ChannelTitle = e.Uploader
+	}
+
+	//thumbnails are listed from the smallest to the largest.
+	if result.CoverUrl == "" && len(e.Thumbnails) > 0 {
+		result.CoverUrl = e.Thumbnails[len(e.Thumbnails)-1].Url
+	}
+	if result.CoverUrl == "" {
+		result.CoverUrl = "https://i.ytimg.com/vi/" + e.ID + "/hqdefault.jpg"
+	}
+
+	if e.Duration > 0 {
+		result.Length = time.Duration(e.Duration) * time.Second
+		result.Duration = result.Length.String()
+	}
+	return result
+}
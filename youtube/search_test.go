@@ -0,0 +1,133 @@
+package youtube
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hemreari/feanor-dcbot/util"
+)
+
+func TestParseSearchStrategy(t *testing.T) {
+	tables := []struct {
+		value    string
+		strategy SearchStrategy
+		isErr    bool
+	}{
+		{"", DefaultSearchStrategy, false},
+		{"api", StrategyAPI, false},
+		{" Extractor ", StrategyExtractor, false},
+		{"fallback", StrategyFallback, false},
+		{"scraper", "", true},
+	}
+
+	for _, table := range tables {
+		strategy, err := ParseSearchStrategy(table.value)
+		if (err != nil) != table.isErr {
+			t.Errorf("\"%s\": got error %v, want error: %t.", table.value, err, table.isErr)
+		}
+		if strategy != table.strategy {
+			t.Errorf("\"%s\": got %s, want %s.", table.value, strategy, table.strategy)
+		}
+	}
+}
+
+func TestParseExtractorOutput(t *testing.T) {
+	out := []byte(`{"id": "dQw4w9WgXcQ", "title": "Rick Astley - Never Gonna Give You Up", "duration": 212.0, "view_count": 1500000000, "channel": "Rick Astley", "thumbnails": [{"url": "small.jpg"}, {"url": "large.jpg"}]}
+
+{"id": "yPYZpwSpKmA", "title": "Together Forever", "uploader": "RickAstleyVEVO"}
+{"id": "", "title": "no id"}
+`)
+
+	tables := []SearchResult{
+		{
+			Kind:         util.KindTrack,
+			VideoID:      "dQw4w9WgXcQ",
+			VideoTitle:   "Rick Astley - Never Gonna Give You Up",
+			ChannelTitle: "Rick Astley",
+			ViewCount:    1500000000,
+			Duration:     "3m32s",
+			Length:       212 * time.Second,
+			CoverUrl:     "large.jpg",
+		},
+		{
+			Kind:         util.KindTrack,
+			VideoID:      "yPYZpwSpKmA",
+			VideoTitle:   "Together Forever",
+			ChannelTitle: "RickAstleyVEVO",
+			CoverUrl:     "https://i.ytimg.com/vi/yPYZpwSpKmA/hqdefault.jpg",
+		},
+	}
+
+	results, err := parseExtractorOutput(out)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+
+	if len(results) != len(tables) {
+		t.Fatalf("Got %d results, want %d.", len(results), len(tables))
+	}
+
+	for i, table := range tables {
+		if results[i] != table {
+			t.Errorf("Result %d: got %+v, want %+v.", i, results[i], table)
+		}
+	}
+
+	_, err = parseExtractorOutput([]byte("not json"))
+	if err == nil {
+		t.Errorf("Expected error for malformed output.")
+	}
+}
+
+func TestSearchAPIStrategyDoesNotFallback(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"error":{"code":403,"message":"quota","errors":[{"reason":"quotaExceeded"}]}}`)
+	}))
+	defer server.Close()
+	y := newTestYoutubeAPI(t, server)
+
+	_, err := y.search("query", SearchOptions{}, 1)
+	if err == nil || requests != 1 {
+		t.Errorf("Got error %v after %d requests, want quota error after 1 request.", err, requests)
+	}
+}
+
+func TestFilterByDuration(t *testing.T) {
+	results := []SearchResult{
+		{VideoID: "short", Length: time.Minute},
+		{VideoID: "song", Length: 3 * time.Minute},
+		{VideoID: "unknown"},
+		{VideoID: "mix", Length: time.Hour},
+	}
+
+	tables := []struct {
+		min time.Duration
+		max time.Duration
+		ids []string
+	}{
+		{0, 0, []string{"short", "song", "unknown", "mix"}},
+		{2 * time.Minute, 0, []string{"song", "unknown", "mix"}},
+		{0, 10 * time.Minute, []string{"short", "song", "unknown"}},
+		{2 * time.Minute, 10 * time.Minute, []string{"song", "unknown"}},
+	}
+
+	for _, table := range tables {
+		filtered := filterByDuration(results, table.min, table.max)
+
+		ids := []string{}
+		for _, result := range filtered {
+			ids = append(ids, result.VideoID)
+		}
+
+		if strings.Join(ids, ",") != strings.Join(table.ids, ",") {
+			t.Errorf("min %s max %s: got %v, want %v.", table.min, table.max, ids, table.ids)
+		}
+	}
+}
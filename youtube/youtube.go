@@ -6,8 +6,10 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
-	//"strings"
+	"strings"
+	"time"
 
+	"github.com/hemreari/feanor-dcbot/cache"
 	"github.com/hemreari/feanor-dcbot/util"
 
 	"google.golang.org/api/googleapi/transport"
@@ -16,150 +18,290 @@ import (
 
 const (
 	DefaultPlaylistItemCount int64 = 20
+
+	//videos.list accepts at most this many IDs in a single call.
+	maxIDsPerRequest = 50
 )
 
+//YoutubeAPI keeps a single Youtube Data API service client
+//that is shared by all requests.
 type YoutubeAPI struct {
-	DeveloperKey string
+	DeveloperKey      string
+	SearchStrategy    SearchStrategy
+	RelevanceLanguage string //default relevance language of searches
+	Quota             *QuotaTracker
+	Cache             *cache.Cache //nil disables caching
+	service           *youtube.Service
 }
 
 type SearchResult struct {
-	VideoID    string
-	VideoTitle string
-	Duration   string
-	VideoPath  string
-	CoverUrl   string
-	CoverPath  string
+	Kind         util.Kind //KindTrack for videos, KindPlaylist or KindChannel
+	VideoID      string
+	PlaylistID   string
+	ChannelID    string //channel result, or the channel of the video if it is known
+	VideoTitle   string //title of the video, playlist or channel
+	ChannelTitle string
+	ViewCount    uint64
+	Duration     string
+	Length       time.Duration //parsed Duration, 0 if unknown
+	VideoPath    string
+	CoverUrl     string
+	CoverPath    string
+	//AgeRestricted is true for the videos that require sign in
+	//to confirm the age of the viewer.
+	AgeRestricted bool
 }
 
-func NewYoutubeAPI(developerKey string) *YoutubeAPI {
-	return &YoutubeAPI{
-		DeveloperKey: developerKey,
+//NewYoutubeAPI creates the Youtube Data API service client with the developer key.
+//Searches are made with the backend that the given strategy chooses and
+//API calls are refused after the given daily quota budget is used, searches
+//are refused when less than searchReserve units of the budget are left.
+func NewYoutubeAPI(developerKey string, strategy SearchStrategy, quotaBudget, searchReserve int) (*YoutubeAPI, error) {
+	client := &http.Client{
+		Transport: &transport.APIKey{Key: developerKey},
+		Timeout:   15 * time.Second,
+	}
+
+	service, err := youtube.New(client)
+	if err != nil {
+		return nil, fmt.Errorf("Error while creating new YouTube client: %v", err)
 	}
+
+	return &YoutubeAPI{
+		DeveloperKey:   developerKey,
+		SearchStrategy: strategy,
+		Quota:          NewQuotaTracker(quotaBudget, searchReserve),
+		service:        service,
+	}, nil
 }
 
-func (y *YoutubeAPI) GetYoutubePlaylist(id string, urlType int) ([]SearchResult, error) {
-	switch urlType {
-	case util.YOUTUBEPLAYLISTURL:
-		playlist, err := y.HandleYoutubePlaylist(id)
+//getVideos returns the videos of the given IDs by their IDs. IDs are
+//requested in batches of maxIDsPerRequest, so N videos cost N/50 calls
+//instead of N. Videos that don't exist anymore are not in the result.
+func (y *YoutubeAPI) getVideos(ids []string, part string) (map[string]*youtube.Video, error) {
+	videos := map[string]*youtube.Video{}
+
+	for start := 0; start < len(ids); start += maxIDsPerRequest {
+		end := start + maxIDsPerRequest
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		err := y.Quota.Reserve("videos.list", videosListCost)
 		if err != nil {
 			return nil, err
 		}
-		return playlist, err
-	case util.YOUTUBETRACKURL:
-		playlist, err := y.HandleYoutubeTrack(id)
+
+		response, err := y.service.Videos.List(part).
+			Id(strings.Join(ids[start:end], ",")).
+			Do()
 		if err != nil {
-			return nil, err
+			return nil, y.apiError("getting video details", err)
+		}
+
+		for _, video := range response.Items {
+			videos[video.Id] = video
 		}
-		return playlist, err
 	}
-	return nil, nil
+	return videos, nil
 }
 
-//GetVideoID searches given query on the youtube and returns
-//first video's ID and Title.
-//!!!! SOME SEARCH RESULTS ON YT DOESN'T RETURN ID. HANDLE ERROR.
-//FOR NOW I'M DOING IT ON func DownloadVideo.
-func (y *YoutubeAPI) GetVideoID(query string) *SearchResult {
-	developerKey := y.DeveloperKey
+//fillDurations sets durations of the given search results from the
+//cache or with batched videos.list calls.
+func (y *YoutubeAPI) fillDurations(results []SearchResult) error {
+	ids := []string{}
+	for i, result := range results {
+		if result.VideoID == "" {
+			continue
+		}
 
-	client := &http.Client{
-		Transport: &transport.APIKey{Key: developerKey},
+		if cached, ok := y.cachedResult(result.VideoID); ok {
+			results[i].Length = cached.Length
+			results[i].Duration = cached.Duration
+			results[i].ViewCount = cached.ViewCount
+			results[i].AgeRestricted = cached.AgeRestricted
+			if results[i].ChannelID == "" {
+				results[i].ChannelID = cached.ChannelID
+			}
+			if results[i].ChannelTitle == "" {
+				results[i].ChannelTitle = cached.ChannelTitle
+			}
+			continue
+		}
+		ids = append(ids, result.VideoID)
 	}
 
-	service, err := youtube.New(client)
-	if err != nil {
-		log.Fatalf("Error while creating new YouTube client: %v", err)
+	if len(ids) == 0 {
+		return nil
 	}
 
-	// Make the API call to YouTube.
-	call := service.Search.List("id,snippet").
-		Q(query).
-		MaxResults(1)
-	response, err := call.Do()
+	//snippet has the channel of the video, playlist items only have the channel of the playlist.
+	videos, err := y.getVideos(ids, "id,contentDetails,snippet,statistics")
 	if err != nil {
-		log.Println(err)
-	}
-
-	// Iterate through each item and add it to the correct list.
-	for _, item := range response.Items {
-		switch item.Id.Kind {
-		case "youtube#video":
-			newTitle := util.FormatVideoTitle(item.Snippet.Title)
-			return &SearchResult{
-				VideoID:    item.Id.VideoId,
-				VideoTitle: newTitle,
-				Duration:   y.GetDurationByID(item.Id.VideoId),
-				CoverUrl:   "https://github.com/golang/go/blob/master/doc/gopher/fiveyears.jpg",
+		return err
+	}
+
+	for i := range results {
+		video, ok := videos[results[i].VideoID]
+		if !ok {
+			continue
+		}
+		results[i].Length = util.ParseISO8601Duration(video.ContentDetails.Duration)
+		results[i].Duration = results[i].Length.String()
+		results[i].AgeRestricted = isAgeRestricted(video.ContentDetails)
+		if video.Statistics != nil {
+			results[i].ViewCount = video.Statistics.ViewCount
+		}
+		if video.Snippet != nil {
+			results[i].ChannelID = video.Snippet.ChannelId
+			if results[i].ChannelTitle == "" {
+				results[i].ChannelTitle = video.Snippet.ChannelTitle
 			}
-		default:
-			return &SearchResult{}
 		}
 	}
 
-	return &SearchResult{}
+	y.cacheVideos(results)
+	return nil
 }
 
-func (y *YoutubeAPI) GetVideoResults(query string) *[]SearchResult {
-	developerKey := y.DeveloperKey
+//isAgeRestricted checks the content rating of the video is age restricted.
+func isAgeRestricted(details *youtube.VideoContentDetails) bool {
+	return details != nil && details.ContentRating != nil && details.ContentRating.YtRating == "ytAgeRestricted"
+}
 
-	client := &http.Client{
-		Transport: &transport.APIKey{Key: developerKey},
+//thumbnailUrl returns the best available thumbnail url.
+func thumbnailUrl(thumbnails *youtube.ThumbnailDetails) string {
+	if thumbnails == nil {
+		return ""
 	}
 
-	service, err := youtube.New(client)
-	if err != nil {
-		log.Fatalf("Error while creating new YouTube client: %v", err)
+	for _, thumbnail := range []*youtube.Thumbnail{thumbnails.High, thumbnails.Medium, thumbnails.Default} {
+		if thumbnail != nil && thumbnail.Url != "" {
+			return thumbnail.Url
+		}
 	}
+	return ""
+}
 
-	var results []SearchResult
+//GetYoutubePlaylist returns the tracks of the given Youtube media reference.
+//Channels are played from their uploads playlist.
+func (y *YoutubeAPI) GetYoutubePlaylist(ref *util.MediaRef) ([]SearchResult, error) {
+	switch ref.Kind {
+	case util.KindPlaylist:
+		playlist, err := y.HandleYoutubePlaylist(ref.ID)
+		if err != nil {
+			return nil, err
+		}
+		return playlist, err
+	case util.KindTrack:
+		//"watch?v=...&list=..." links play the list from the video,
+		//mixes can't be listed so only the video is played.
+		if ref.ListID != "" && !strings.HasPrefix(ref.ListID, "RD") {
+			playlist, err := y.HandleYoutubePlaylist(ref.ListID)
+			if err != nil {
+				log.Printf("Error while getting playlist of %s, playing only the video: %v", ref.ID, err)
+			} else if tracks := playlistFromTrack(playlist, ref.ID, ref.PlaylistIndex); tracks != nil {
+				return tracks, nil
+			}
+		}
 
-	call := service.Search.List("id,snippet").Q(query)
-	response, err := call.Do()
-	if err != nil {
-		log.Println(err)
+		playlist, err := y.HandleYoutubeTrack(ref.ID)
+		if err != nil {
+			return nil, err
+		}
+		return playlist, err
+	case util.KindChannel:
+		uploadsID, err := channelUploadsPlaylistID(ref.ID)
+		if err != nil {
+			return nil, err
+		}
+		playlist, err := y.HandleYoutubePlaylist(uploadsID)
+		if err != nil {
+			return nil, err
+		}
+		return playlist, err
 	}
+	return nil, fmt.Errorf("Youtube %s links are not supported.", ref.Kind)
+}
 
-	for _, item := range response.Items {
-		searchResult := SearchResult{}
-		switch item.Id.Kind {
-		case "youtube#video":
-			if item.Snippet.Title == "" {
-				continue
-			}
-			searchResult.VideoID = item.Id.VideoId
-			searchResult.VideoTitle = item.Snippet.Title
-			searchResult.Duration = y.GetDurationByID(item.Id.VideoId)
-			searchResult.CoverUrl = "https://github.com/golang/go/blob/master/doc/gopher/fiveyears.jpg"
-			results = append(results, searchResult)
-		default:
-			results = append(results, searchResult)
+//playlistFromTrack returns the tracks of the playlist starting from the given
+//video. Video is found at the 1-based index if it is given, otherwise by its ID.
+//Returns nil if the video isn't in the playlist.
+func playlistFromTrack(playlist []SearchResult, videoID string, index int) []SearchResult {
+	if index > 0 && index <= len(playlist) && playlist[index-1].VideoID == videoID {
+		return playlist[index-1:]
+	}
+
+	for i, track := range playlist {
+		if track.VideoID == videoID {
+			return playlist[i:]
 		}
 	}
-	return &results
+	return nil
 }
 
-func (y *YoutubeAPI) GetDurationByID(id string) string {
-	devKey := y.DeveloperKey
+//ResultTracks returns the videos of the given search result. A video result
+//is returned as it is, playlists and channels are listed with GetYoutubePlaylist.
+func (y *YoutubeAPI) ResultTracks(result SearchResult) ([]SearchResult, error) {
+	switch result.Kind {
+	case util.KindPlaylist:
+		return y.GetYoutubePlaylist(&util.MediaRef{Provider: util.ProviderYoutube, Kind: util.KindPlaylist, ID: result.PlaylistID})
+	case util.KindChannel:
+		return y.GetYoutubePlaylist(&util.MediaRef{Provider: util.ProviderYoutube, Kind: util.KindChannel, ID: result.ChannelID})
+	}
+	return []SearchResult{result}, nil
+}
 
-	client := &http.Client{
-		Transport: &transport.APIKey{Key: devKey},
+//channelUploadsPlaylistID returns ID of the playlist that contains
+//uploads of the given channel. Uploads playlist IDs are channel IDs
+//starting with "UU" instead of "UC".
+func channelUploadsPlaylistID(channelID string) (string, error) {
+	if !strings.HasPrefix(channelID, "UC") {
+		return "", fmt.Errorf("Only channel links with channel IDs are supported: %s", channelID)
 	}
+	return "UU" + strings.TrimPrefix(channelID, "UC"), nil
+}
 
-	service, err := youtube.New(client)
+//GetVideoID searches given query on the youtube and returns
+//first video's ID and Title. Returns ErrNotFound if search
+//doesn't have any video results.
+func (y *YoutubeAPI) GetVideoID(query string) (*SearchResult, error) {
+	results, err := y.search(query, SearchOptions{}, 1)
 	if err != nil {
-		log.Fatalf("Error while creating new Youtube client: %v", err)
+		return nil, err
 	}
 
-	call := service.Videos.List("id,contentDetails").Id(id)
-	response, err := call.Do()
+	if len(results) == 0 {
+		return nil, fmt.Errorf("No video is found for \"%s\": %w", query, ErrNotFound)
+	}
+
+	result := results[0]
+	result.VideoTitle = util.FormatVideoTitle(result.VideoTitle)
+	return &result, nil
+}
+
+//GetSearchResults searches given query on the youtube with the given
+//options and returns the found videos, playlists or channels.
+func (y *YoutubeAPI) GetSearchResults(query string, opts SearchOptions) (*[]SearchResult, error) {
+	results, err := y.search(query, opts, searchResultCount)
+	if err != nil {
+		return nil, err
+	}
+	return &results, nil
+}
+
+//GetDurationByID returns the duration of the given video.
+func (y *YoutubeAPI) GetDurationByID(id string) (string, error) {
+	videos, err := y.getVideos([]string{id}, "id,contentDetails")
 	if err != nil {
-		log.Fatal(err)
+		return "", err
 	}
 
-	for _, item := range response.Items {
-		return util.ParseISO8601(item.ContentDetails.Duration)
+	video, ok := videos[id]
+	if !ok {
+		return "", fmt.Errorf("Video %s: %w", id, ErrNotFound)
 	}
-	return ""
+	return util.ParseISO8601(video.ContentDetails.Duration), nil
 }
 
 //DownloadVideo downloads video with ytdl, returns downloaded video file's path.
@@ -193,7 +335,7 @@ func ytdlExecute(videoTitle, videoID string) (string, error) {
 		videoID,
 	}
 
-	cmd := exec.Command("youtube-dl", ytdlArgs...)
+	cmd := exec.Command(extractorCommand, ytdlArgs...)
 	cmd.Stderr = os.Stderr
 
 	err := cmd.Run()
@@ -206,7 +348,10 @@ func ytdlExecute(videoTitle, videoID string) (string, error) {
 //SearchDownload combines abilities of GetVideoID and
 //DownloadVideo func's as a standalone function
 func (y *YoutubeAPI) SearchDownload(query string) (*SearchResult, error) {
-	searchRes := y.GetVideoID(query)
+	searchRes, err := y.GetVideoID(query)
+	if err != nil {
+		return nil, err
+	}
 
 	path, err := DownloadVideo(searchRes.VideoTitle, searchRes.VideoID)
 	if err != nil {
@@ -218,39 +363,36 @@ func (y *YoutubeAPI) SearchDownload(query string) (*SearchResult, error) {
 
 //GetInfoByID returns video information about the given video id.
 func (y *YoutubeAPI) GetInfoByID(id string) (*SearchResult, error) {
-	devKey := y.DeveloperKey
-
-	client := &http.Client{
-		Transport: &transport.APIKey{Key: devKey},
+	if cached, ok := y.cachedResult(id); ok {
+		return &cached, nil
 	}
 
-	service, err := youtube.New(client)
+	videos, err := y.getVideos([]string{id}, "id,contentDetails,snippet")
 	if err != nil {
-		return nil, fmt.Errorf("Error while creating new YouTube client: %v", err)
+		return nil, err
 	}
 
-	call := service.Videos.List("id,contentDetails,snippet").Id(id)
-	response, err := call.Do()
-	if err != nil {
-		return nil, fmt.Errorf("Error while making call: %v", err)
-	}
-
-	for _, item := range response.Items {
-		switch item.Kind {
-		case "youtube#video":
-			snippet := item.Snippet
-			//newTitle := util.FormatVideoTitle(snippet.Title)
-			return &SearchResult{
-				VideoID:    item.Id,
-				VideoTitle: snippet.Title,
-				Duration:   util.ParseISO8601(item.ContentDetails.Duration),
-				CoverUrl:   snippet.Thumbnails.High.Url,
-			}, nil
-		default:
-			return &SearchResult{}, nil
-		}
+	item, ok := videos[id]
+	if !ok {
+		return nil, fmt.Errorf("Video %s: %w", id, ErrNotFound)
 	}
-	return nil, nil
+
+	snippet := item.Snippet
+	//newTitle := util.FormatVideoTitle(snippet.Title)
+	result := SearchResult{
+		Kind:         util.KindTrack,
+		VideoID:      item.Id,
+		VideoTitle:   snippet.Title,
+		ChannelID:    snippet.ChannelId,
+		ChannelTitle: snippet.ChannelTitle,
+		Length:       util.ParseISO8601Duration(item.ContentDetails.Duration),
+		CoverUrl:     thumbnailUrl(snippet.Thumbnails),
+	}
+	result.AgeRestricted = isAgeRestricted(item.ContentDetails)
+	result.Duration = result.Length.String()
+
+	y.cacheVideos([]SearchResult{result})
+	return &result, nil
 }
 
 func (y *YoutubeAPI) HandleYoutubePlaylist(id string) ([]SearchResult, error) {
@@ -267,39 +409,36 @@ func (y *YoutubeAPI) HandleYoutubePlaylist(id string) ([]SearchResult, error) {
 		}
 
 		videoID := playlistItem.Snippet.ResourceId.VideoId
-		thumbnailUrl := playlistItem.Snippet.Thumbnails.High.Url
 		videoTitle := playlistItem.Snippet.Title
 
 		track := SearchResult{
+			Kind:       util.KindTrack,
 			VideoID:    videoID,
 			VideoTitle: videoTitle,
-			CoverUrl:   thumbnailUrl,
-			Duration:   y.GetDurationByID(videoID),
+			CoverUrl:   thumbnailUrl(playlistItem.Snippet.Thumbnails),
 		}
 		playlist = append(playlist, track)
 	}
+
+	err = y.fillDurations(playlist)
+	if err != nil {
+		return nil, err
+	}
 	return playlist, nil
 }
 
 //getYoutubePlaylistById makes the api request to Youtube Data API to
 //get information about the given playlist ID.
 func (y *YoutubeAPI) getYoutubePlaylistById(id string) (*youtube.PlaylistItemListResponse, error) {
-	devKey := y.DeveloperKey
-
-	client := &http.Client{
-		Transport: &transport.APIKey{Key: devKey},
-	}
-
-	service, err := youtube.New(client)
+	err := y.Quota.Reserve("playlistItems.list", playlistItemsListCost)
 	if err != nil {
-		return nil, fmt.Errorf("Error while creating new Youtube client: %v", err)
+		return nil, err
 	}
 
-	call := service.PlaylistItems.List("snippet").PlaylistId(id).MaxResults(DefaultPlaylistItemCount)
+	call := y.service.PlaylistItems.List("snippet").PlaylistId(id).MaxResults(DefaultPlaylistItemCount)
 	response, err := call.Do()
 	if err != nil {
-		log.Println(err)
-		return nil, fmt.Errorf("Error while doing get Youtube playlist by ID request: %v", err)
+		return nil, y.apiError("getting Youtube playlist", err)
 	}
 
 	return response, nil
@@ -0,0 +1,255 @@
+package youtube
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hemreari/feanor-dcbot/cache"
+	"github.com/hemreari/feanor-dcbot/util"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+//newTestYoutubeAPI returns a YoutubeAPI that sends its requests to the
+//given test server.
+func newTestYoutubeAPI(t *testing.T, server *httptest.Server) *YoutubeAPI {
+	service, err := youtube.New(server.Client())
+	if err != nil {
+		t.Fatalf("Couldn't create service: %v", err)
+	}
+	service.BasePath = server.URL + "/"
+
+	return &YoutubeAPI{
+		DeveloperKey:   "key",
+		SearchStrategy: StrategyAPI,
+		Quota:          NewQuotaTracker(DefaultQuotaBudget, 0),
+		service:        service,
+	}
+}
+
+//videosHandler responds videos.list requests with a 3 minute video for
+//every requested ID and counts the requests.
+func videosHandler(requests *int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+
+		items := []string{}
+		for _, id := range strings.Split(r.URL.Query().Get("id"), ",") {
+			items = append(items, fmt.Sprintf(`{"id":"%s","contentDetails":{"duration":"PT3M"}}`, id))
+		}
+		fmt.Fprintf(w, `{"items":[%s]}`, strings.Join(items, ","))
+	}
+}
+
+func TestGetVideosBatches(t *testing.T) {
+	tables := []struct {
+		idCount  int
+		requests int
+	}{
+		{1, 1},
+		{20, 1},
+		{50, 1},
+		{51, 2},
+		{120, 3},
+	}
+
+	for _, table := range tables {
+		requests := 0
+		server := httptest.NewServer(videosHandler(&requests))
+		y := newTestYoutubeAPI(t, server)
+
+		ids := []string{}
+		for i := 0; i < table.idCount; i++ {
+			ids = append(ids, fmt.Sprintf("video%d", i))
+		}
+
+		videos, err := y.getVideos(ids, "id,contentDetails")
+		server.Close()
+		if err != nil {
+			t.Errorf("Got error for %d IDs: %v", table.idCount, err)
+			continue
+		}
+
+		if requests != table.requests {
+			t.Errorf("%d IDs: got %d requests, want %d.", table.idCount, requests, table.requests)
+		}
+		if len(videos) != table.idCount {
+			t.Errorf("%d IDs: got %d videos, want %d.", table.idCount, len(videos), table.idCount)
+		}
+	}
+}
+
+func TestFillDurations(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(videosHandler(&requests))
+	defer server.Close()
+	y := newTestYoutubeAPI(t, server)
+
+	results := []SearchResult{{VideoID: "a"}, {VideoID: ""}, {VideoID: "b"}}
+	err := y.fillDurations(results)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+
+	if requests != 1 {
+		t.Errorf("Got %d requests, want 1.", requests)
+	}
+
+	tables := []struct {
+		length time.Duration
+	}{
+		{3 * time.Minute},
+		{0},
+		{3 * time.Minute},
+	}
+
+	for i, table := range tables {
+		if results[i].Length != table.length {
+			t.Errorf("Result %d: got length %s, want %s.", i, results[i].Length, table.length)
+		}
+	}
+}
+
+func TestGetDurationByIDErrors(t *testing.T) {
+	tables := []struct {
+		status int
+		body   string
+		want   error
+	}{
+		{http.StatusForbidden, `{"error":{"code":403,"message":"quota","errors":[{"reason":"quotaExceeded"}]}}`, ErrQuotaExceeded},
+		{http.StatusNotFound, `{"error":{"code":404,"message":"not found","errors":[{"reason":"videoNotFound"}]}}`, ErrNotFound},
+		{http.StatusOK, `{"items":[]}`, ErrNotFound},
+	}
+
+	for _, table := range tables {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(table.status)
+			fmt.Fprint(w, table.body)
+		}))
+		y := newTestYoutubeAPI(t, server)
+
+		_, err := y.GetDurationByID("video")
+		server.Close()
+		if !errors.Is(err, table.want) {
+			t.Errorf("Status %d: got error %v, want %v.", table.status, err, table.want)
+		}
+	}
+
+	//requests to a closed server fail before reaching Youtube.
+	server := httptest.NewServer(http.NotFoundHandler())
+	y := newTestYoutubeAPI(t, server)
+	server.Close()
+
+	_, err := y.GetDurationByID("video")
+	if !errors.Is(err, ErrTransport) {
+		t.Errorf("Closed server: got error %v, want %v.", err, ErrTransport)
+	}
+}
+
+func TestFillDurationsCached(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(videosHandler(&requests))
+	defer server.Close()
+	y := newTestYoutubeAPI(t, server)
+
+	lookupCache, err := cache.Open("", nil)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	y.Cache = lookupCache
+
+	tables := []struct {
+		ids      []string
+		requests int
+	}{
+		{[]string{"a", "b"}, 1},
+		{[]string{"a", "b"}, 1},
+		{[]string{"a", "c"}, 2},
+	}
+
+	for i, table := range tables {
+		results := []SearchResult{}
+		for _, id := range table.ids {
+			results = append(results, SearchResult{VideoID: id})
+		}
+
+		err := y.fillDurations(results)
+		if err != nil {
+			t.Fatalf("Got error: %v", err)
+		}
+
+		if requests != table.requests {
+			t.Errorf("Call %d: got %d requests in total, want %d.", i, requests, table.requests)
+		}
+		for _, result := range results {
+			if result.Length != 3*time.Minute {
+				t.Errorf("Call %d: got length %s for %s, want 3m.", i, result.Length, result.VideoID)
+			}
+		}
+	}
+}
+
+func TestPlaylistFromTrack(t *testing.T) {
+	playlist := []SearchResult{{VideoID: "a"}, {VideoID: "b"}, {VideoID: "c"}, {VideoID: "b"}}
+
+	tables := []struct {
+		videoID string
+		index   int
+		first   int
+	}{
+		{"b", 0, 1},
+		{"b", 4, 3},
+		{"b", 3, 1},
+		{"c", 9, 2},
+		{"d", 0, -1},
+	}
+
+	for _, table := range tables {
+		tracks := playlistFromTrack(playlist, table.videoID, table.index)
+		if table.first < 0 {
+			if tracks != nil {
+				t.Errorf("Got %d tracks for %s, want nil.", len(tracks), table.videoID)
+			}
+			continue
+		}
+
+		if len(tracks) != len(playlist)-table.first || tracks[0].VideoID != table.videoID {
+			t.Errorf("Got %d tracks for %s at %d, want the playlist from %d.", len(tracks), table.videoID, table.index, table.first)
+		}
+	}
+}
+
+func TestGetYoutubePlaylistTrackInList(t *testing.T) {
+	requests := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/playlistItems", func(w http.ResponseWriter, r *http.Request) {
+		items := []string{}
+		for _, id := range []string{"aaaaaaaaaaa", "bbbbbbbbbbb", "ccccccccccc"} {
+			items = append(items, fmt.Sprintf(`{"snippet":{"title":"%s","resourceId":{"videoId":"%s"}}}`, id, id))
+		}
+		fmt.Fprintf(w, `{"items":[%s]}`, strings.Join(items, ","))
+	})
+	mux.HandleFunc("/videos", videosHandler(&requests))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	y := newTestYoutubeAPI(t, server)
+
+	ref, err := util.ParseMediaRef("https://www.youtube.com/watch?v=bbbbbbbbbbb&list=PLlist&index=2")
+	if err != nil {
+		t.Fatalf("Got error while parsing link: %v", err)
+	}
+
+	tracks, err := y.GetYoutubePlaylist(ref)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	if len(tracks) != 2 || tracks[0].VideoID != "bbbbbbbbbbb" || tracks[1].VideoID != "ccccccccccc" {
+		t.Errorf("Got tracks %+v, want the playlist from the second video.", tracks)
+	}
+}